@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -23,6 +25,8 @@ import (
 	"github.com/nexodus-io/nexodus/internal/ipam"
 	"github.com/nexodus-io/nexodus/internal/routers"
 	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -151,6 +155,12 @@ func main() {
 				Usage:   "Database ssl mode",
 				EnvVars: []string{"NEXAPI_DB_SSLMODE"},
 			},
+			&cli.StringFlag{
+				Name:    "ipam-backend",
+				Value:   "remote",
+				Usage:   "IPAM backend, remote to use the go-ipam grpc service at --ipam-address, or embedded to store the leases in the apiserver database",
+				EnvVars: []string{"NEXAPI_IPAM_BACKEND"},
+			},
 			&cli.StringFlag{
 				Name:    "ipam-address",
 				Value:   "ipam:9090",
@@ -212,6 +222,124 @@ func main() {
 				Value:   1,
 				EnvVars: []string{"NEXAPI_REDIS_DB"},
 			},
+			&cli.StringFlag{
+				Name:    "signalbus",
+				Usage:   "How the apiserver replicas signal each other changes, postgres or redis",
+				Value:   "postgres",
+				EnvVars: []string{"NEXAPI_SIGNALBUS"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "rate-limit",
+				Usage:   "Rate limit of an API route group per user or organization, as <group>.<user|organization>=<rate>[:<burst>] in requests per second, the groups are " + strings.Join(routers.RateLimitGroups, ", "),
+				Value:   &cli.StringSlice{},
+				EnvVars: []string{"NEXAPI_RATE_LIMITS"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "allowed-email-domain",
+				Usage:   "Email domain of the users that can log in through Google",
+				Value:   cli.NewStringSlice(handlers.DefaultPolicyData.AllowedEmailDomains...),
+				EnvVars: []string{"NEXAPI_ALLOWED_EMAIL_DOMAINS"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "allowed-issuer",
+				Usage:   "Issuer of the JWTs that are accepted, any issuer is accepted when not set",
+				Value:   &cli.StringSlice{},
+				EnvVars: []string{"NEXAPI_ALLOWED_ISSUERS"},
+			},
+			&cli.StringFlag{
+				Name:    "audience",
+				Usage:   "Audience the JWTs must be issued for",
+				Value:   handlers.DefaultPolicyData.Audience,
+				EnvVars: []string{"NEXAPI_AUDIENCE"},
+			},
+			&cli.StringFlag{
+				Name:    "policy-data-file",
+				Usage:   "JSON file with the allowed_email_domains, allowed_issuers and audience of the authorization policy, it overrides the flags and is reloaded when it changes",
+				EnvVars: []string{"NEXAPI_POLICY_DATA_FILE"},
+			},
+			&cli.DurationFlag{
+				Name:    "policy-data-reload-interval",
+				Usage:   "How often to check the policy data file for changes",
+				Value:   10 * time.Second,
+				EnvVars: []string{"NEXAPI_POLICY_DATA_RELOAD_INTERVAL"},
+			},
+			&cli.StringFlag{
+				Name:    "policy-bundle",
+				Usage:   "Directory or OPA bundle file with authorization policies evaluated with the embedded policy, it is reloaded when it changes",
+				EnvVars: []string{"NEXAPI_POLICY_BUNDLE"},
+			},
+			&cli.DurationFlag{
+				Name:    "policy-bundle-reload-interval",
+				Usage:   "How often to check the policy bundle for changes",
+				Value:   10 * time.Second,
+				EnvVars: []string{"NEXAPI_POLICY_BUNDLE_RELOAD_INTERVAL"},
+			},
+			&cli.IntFlag{
+				Name:    "quota-devices-per-organization",
+				Usage:   "Maximum number of devices in an organization, 0 is unlimited",
+				Value:   0,
+				EnvVars: []string{"NEXAPI_QUOTA_DEVICES_PER_ORGANIZATION"},
+			},
+			&cli.IntFlag{
+				Name:    "quota-organizations-per-user",
+				Usage:   "Maximum number of organizations a user owns, 0 is unlimited",
+				Value:   0,
+				EnvVars: []string{"NEXAPI_QUOTA_ORGANIZATIONS_PER_USER"},
+			},
+			&cli.IntFlag{
+				Name:    "quota-security-groups-per-organization",
+				Usage:   "Maximum number of security groups in an organization, including its default security group, 0 is unlimited",
+				Value:   0,
+				EnvVars: []string{"NEXAPI_QUOTA_SECURITY_GROUPS_PER_ORGANIZATION"},
+			},
+			&cli.IntFlag{
+				Name:    "quota-rules-per-security-group",
+				Usage:   "Maximum number of inbound and outbound rules in a security group, 0 is unlimited",
+				Value:   0,
+				EnvVars: []string{"NEXAPI_QUOTA_RULES_PER_SECURITY_GROUP"},
+			},
+			&cli.DurationFlag{
+				Name:    "device-reaper-interval",
+				Usage:   "How often to check for offline and expired devices",
+				Value:   time.Minute,
+				EnvVars: []string{"NEXAPI_DEVICE_REAPER_INTERVAL"},
+			},
+			&cli.DurationFlag{
+				Name:    "device-offline-timeout",
+				Usage:   "How long a device can go without a heartbeat before it is marked offline",
+				Value:   3 * time.Minute,
+				EnvVars: []string{"NEXAPI_DEVICE_OFFLINE_TIMEOUT"},
+			},
+			&cli.DurationFlag{
+				Name:    "device-compaction-interval",
+				Usage:   "How often to remove the deleted devices older than the tombstone retention, 0 disables it",
+				Value:   time.Hour,
+				EnvVars: []string{"NEXAPI_DEVICE_COMPACTION_INTERVAL"},
+			},
+			&cli.DurationFlag{
+				Name:    "device-tombstone-retention",
+				Usage:   "How long deleted devices are kept so that the device watches can resume across their deletion",
+				Value:   7 * 24 * time.Hour,
+				EnvVars: []string{"NEXAPI_DEVICE_TOMBSTONE_RETENTION"},
+			},
+			&cli.DurationFlag{
+				Name:    "ipam-gc-interval",
+				Usage:   "How often to release the ipam leases no device or organization uses, 0 disables it",
+				Value:   0,
+				EnvVars: []string{"NEXAPI_IPAM_GC_INTERVAL"},
+			},
+			&cli.BoolFlag{
+				Name:    "ipam-gc-dry-run",
+				Usage:   "Only log the ipam leases the ipam garbage collector would release",
+				Value:   false,
+				EnvVars: []string{"NEXAPI_IPAM_GC_DRY_RUN"},
+			},
+			&cli.DurationFlag{
+				Name:    "ipam-gc-grace-period",
+				Usage:   "How long an ipam lease must exist without a device before it is released",
+				Value:   time.Minute,
+				EnvVars: []string{"NEXAPI_IPAM_GC_GRACE_PERIOD"},
+			},
 		},
 
 		Action: func(cCtx *cli.Context) error {
@@ -224,20 +352,69 @@ func main() {
 					log.Fatal(err)
 				}
 
-				signalBus := signalbus.NewPgSignalBus(signalbus.NewSignalBus(), db, dsn, logger.Sugar())
 				wg := &sync.WaitGroup{}
-				signalBus.Start(ctx, wg)
+				var signalBus signalbus.SignalBus
+				switch cCtx.String("signalbus") {
+				case "postgres":
+					pgSignalBus := signalbus.NewPgSignalBus(signalbus.NewSignalBus(), db, dsn, logger.Sugar())
+					pgSignalBus.Start(ctx, wg)
+					signalBus = pgSignalBus
+				case "redis":
+					redisClient := redis.NewClient(&redis.Options{
+						Addr: cCtx.String("redis-server"),
+						DB:   cCtx.Int("redis-db"),
+					})
+					redisSignalBus := signalbus.NewRedisSignalBus(signalbus.NewSignalBus(), redisClient, logger.Sugar())
+					redisSignalBus.Start(ctx, wg)
+					signalBus = redisSignalBus
+				default:
+					log.Fatalf("invalid signalbus: %s, must be postgres or redis", cCtx.String("signalbus"))
+				}
 
-				ipam := ipam.NewIPAM(logger.Sugar(), cCtx.String("ipam-address"))
+				ipamClient := newIPAM(cCtx, logger, db)
 
-				fflags := fflags.NewFFlags(logger.Sugar())
+				fflags := fflags.NewFFlags(logger.Sugar(), db, signalBus)
+				fflags.Start(ctx, wg)
 
 				store := inmem.New()
 
-				api, err := handlers.NewAPI(ctx, logger.Sugar(), db, ipam, fflags, store, signalBus)
+				api, err := handlers.NewAPI(ctx, logger.Sugar(), db, ipamClient, fflags, store, signalBus)
 				if err != nil {
 					log.Fatal(err)
 				}
+				if err := api.CheckIpamImported(ctx); err != nil {
+					log.Fatal(err)
+				}
+				policyData := handlers.PolicyData{
+					AllowedEmailDomains: cCtx.StringSlice("allowed-email-domain"),
+					AllowedIssuers:      cCtx.StringSlice("allowed-issuer"),
+					Audience:            cCtx.String("audience"),
+				}
+				if file := cCtx.String("policy-data-file"); file != "" {
+					if err := api.StartPolicyDataReloader(ctx, wg, file, policyData, cCtx.Duration("policy-data-reload-interval")); err != nil {
+						log.Fatal(err)
+					}
+				} else if err := api.SetPolicyData(ctx, policyData); err != nil {
+					log.Fatal(err)
+				}
+				api.SetQuotas(handlers.Quotas{
+					DevicesPerOrganization:        cCtx.Int("quota-devices-per-organization"),
+					OrganizationsPerUser:          cCtx.Int("quota-organizations-per-user"),
+					SecurityGroupsPerOrganization: cCtx.Int("quota-security-groups-per-organization"),
+					RulesPerSecurityGroup:         cCtx.Int("quota-rules-per-security-group"),
+				})
+				api.StartDeviceReaper(ctx, wg, cCtx.Duration("device-reaper-interval"), cCtx.Duration("device-offline-timeout"))
+				if interval := cCtx.Duration("device-compaction-interval"); interval > 0 {
+					api.StartDeviceCompactor(ctx, wg, interval, cCtx.Duration("device-tombstone-retention"))
+				}
+				if interval := cCtx.Duration("ipam-gc-interval"); interval > 0 {
+					api.StartIpamGC(ctx, wg, interval, handlers.IpamGCOptions{
+						DryRun:      cCtx.Bool("ipam-gc-dry-run"),
+						GracePeriod: cCtx.Duration("ipam-gc-grace-period"),
+					})
+				}
+				prometheus.MustRegister(api.MetricsCollector())
+
 				scopes := []string{"openid", "profile", "email"}
 				scopes = append(scopes, cCtx.StringSlice("scopes")...)
 
@@ -279,6 +456,11 @@ func main() {
 					log.Fatal(err)
 				}
 
+				rateLimits, err := routers.ParseRateLimits(cCtx.StringSlice("rate-limit"))
+				if err != nil {
+					log.Fatal(err)
+				}
+
 				router, err := routers.NewAPIRouter(ctx, routers.APIRouterOptions{
 					Logger:          logger.Sugar(),
 					Api:             api,
@@ -292,6 +474,11 @@ func main() {
 					Store:           store,
 					RedisServer:     cCtx.String("redis-server"),
 					RedisDB:         cCtx.Int("redis-db"),
+					RateLimits:      rateLimits,
+
+					PolicyBundle:               cCtx.String("policy-bundle"),
+					PolicyBundleReloadInterval: cCtx.Duration("policy-bundle-reload-interval"),
+					WaitGroup:                  wg,
 				})
 				if err != nil {
 					log.Fatal(err)
@@ -333,6 +520,64 @@ func main() {
 			return nil
 		},
 	})
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:  "ipam-gc",
+		Usage: "Release the ipam leases that no device or organization uses",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Only report the leases that would be released",
+				Value: false,
+			},
+			&cli.DurationFlag{
+				Name:  "grace-period",
+				Usage: "How long to wait between listing the ipam leases and the devices",
+				Value: time.Minute,
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			ctx := cCtx.Context
+			withLoggerAndDB(ctx, cCtx, func(logger *zap.Logger, db *gorm.DB, dsn string) {
+				ipamClient := newIPAM(cCtx, logger, db)
+				signalBus := signalbus.NewSignalBus()
+				api, err := handlers.NewAPI(ctx, logger.Sugar(), db, ipamClient, fflags.NewFFlags(logger.Sugar(), db, signalBus), inmem.New(), signalBus)
+				if err != nil {
+					log.Fatal(err)
+				}
+				report, err := api.IpamGC(ctx, handlers.IpamGCOptions{
+					DryRun:      cCtx.Bool("dry-run"),
+					GracePeriod: cCtx.Duration("grace-period"),
+				})
+				if err != nil {
+					log.Fatal(err)
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					log.Fatal(err)
+				}
+			})
+			return nil
+		},
+	})
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:  "ipam-import",
+		Usage: "Lease the addresses and prefixes of the organizations and devices from the embedded ipam backend, run it once before switching --ipam-backend from remote to embedded",
+		Action: func(cCtx *cli.Context) error {
+			ctx := cCtx.Context
+			withLoggerAndDB(ctx, cCtx, func(logger *zap.Logger, db *gorm.DB, dsn string) {
+				signalBus := signalbus.NewSignalBus()
+				api, err := handlers.NewAPI(ctx, logger.Sugar(), db, ipam.NewEmbeddedIPAM(logger.Sugar(), db), fflags.NewFFlags(logger.Sugar(), db, signalBus), inmem.New(), signalBus)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if err := api.ImportIpamLeases(ctx); err != nil {
+					log.Fatal(err)
+				}
+			})
+			return nil
+		},
+	})
 
 	if err := app.Run(os.Args); err != nil {
 		log.Fatal(err)
@@ -382,6 +627,18 @@ func withLoggerAndDB(ctx context.Context, cCtx *cli.Context, f func(logger *zap.
 	f(logger, db, dsn)
 }
 
+func newIPAM(cCtx *cli.Context, logger *zap.Logger, db *gorm.DB) ipam.IPAM {
+	switch cCtx.String("ipam-backend") {
+	case "remote":
+		return ipam.NewRemoteIPAM(logger.Sugar(), cCtx.String("ipam-address"))
+	case "embedded":
+		return ipam.NewEmbeddedIPAM(logger.Sugar(), db)
+	default:
+		log.Fatalf("invalid ipam backend: %s", cCtx.String("ipam-backend"))
+		return nil
+	}
+}
+
 func initTracer(logger *zap.SugaredLogger, insecure bool, collector string) func(context.Context) error {
 	if collector == "" {
 		logger.Info("No collector endpoint configured")
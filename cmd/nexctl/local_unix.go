@@ -192,6 +192,20 @@ func init() {
 					},
 				},
 			},
+			{
+				Name:  "organizations",
+				Usage: "Commands for the organizations joined by nexd",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "list the organizations joined by nexd and their tunnel interfaces",
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							return cmdListNexdOrganizations(cCtx, encodeOut)
+						},
+					},
+				},
+			},
 			{
 				Name:  "peers",
 				Usage: "Commands for interacting with nexd peer connectivity",
@@ -8,6 +8,7 @@ import (
 	"os"
 	"sort"
 	"text/tabwriter"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/api/public"
@@ -56,6 +57,11 @@ func main() {
 				Name:  "password",
 				Usage: "Password",
 			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "Personal access token, used instead of the username and password",
+				EnvVars: []string{"NEXCTL_TOKEN"},
+			},
 			&cli.StringFlag{
 				Name:     "output",
 				Value:    encodeColumn,
@@ -114,6 +120,10 @@ func main() {
 								Name:  "hub-organization",
 								Value: false,
 							},
+							&cli.StringFlag{
+								Name:  "dns-suffix",
+								Usage: "Domain the mesh dns resolves the device hostnames in, defaults to <name>.nexodus.local",
+							},
 						},
 						Action: func(cCtx *cli.Context) error {
 							encodeOut := cCtx.String("output")
@@ -122,7 +132,8 @@ func main() {
 							organizationCIDR := cCtx.String("cidr")
 							organizationCIDRv6 := cCtx.String("cidr-v6")
 							organizationHub := cCtx.Bool("hub-organization")
-							return createOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationName, organizationDescrip, organizationCIDR, organizationCIDRv6, organizationHub)
+							organizationDnsSuffix := cCtx.String("dns-suffix")
+							return createOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationName, organizationDescrip, organizationCIDR, organizationCIDRv6, organizationHub, organizationDnsSuffix)
 						},
 					},
 					{
@@ -140,6 +151,193 @@ func main() {
 							return deleteOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationID)
 						},
 					},
+					{
+						Name:  "update",
+						Usage: "Update a organization, the cidrs of an organization with a private cidr can only be grown",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name: "description",
+							},
+							&cli.StringFlag{
+								Name: "cidr",
+							},
+							&cli.StringFlag{
+								Name: "cidr-v6",
+							},
+							&cli.StringFlag{
+								Name:  "dns-suffix",
+								Usage: "Domain the mesh dns resolves the device hostnames in, \".\" resets it to <name>.nexodus.local",
+							},
+							&cli.IntFlag{
+								Name:  "device-expiry-seconds",
+								Usage: "How long a device can go unseen before it is removed, 0 disables expiry",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							var deviceExpirySeconds *int32
+							if cCtx.IsSet("device-expiry-seconds") {
+								deviceExpirySeconds = public.PtrInt32(int32(cCtx.Int("device-expiry-seconds")))
+							}
+							return updateOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationID, cCtx.String("description"), cCtx.String("cidr"), cCtx.String("cidr-v6"), cCtx.String("dns-suffix"), deviceExpirySeconds)
+						},
+					},
+					{
+						Name:  "renumber",
+						Usage: "Move a organization to new cidrs and assign its devices new tunnel addresses",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name: "cidr",
+							},
+							&cli.StringFlag{
+								Name: "cidr-v6",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							return renumberOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationID, cCtx.String("cidr"), cCtx.String("cidr-v6"))
+						},
+					},
+					{
+						Name:  "connectivity",
+						Usage: "Show the connectivity status between the devices of an organization",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							return getOrganizationConnectivity(mustCreateAPIClient(cCtx), encodeOut, organizationID)
+						},
+					},
+					{
+						Name:  "routes",
+						Usage: "Show the prefixes advertised by the devices of an organization",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							return getOrganizationRoutes(mustCreateAPIClient(cCtx), encodeOut, organizationID)
+						},
+					},
+					{
+						Name:  "peering",
+						Usage: "Commands relating to the peerings between organizations",
+						Subcommands: []*cli.Command{
+							{
+								Name:  "list",
+								Usage: "List the peerings of an organization",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "organization-id",
+										Required: true,
+									},
+								},
+								Action: func(cCtx *cli.Context) error {
+									encodeOut := cCtx.String("output")
+									organizationID := cCtx.String("organization-id")
+									return listOrganizationPeerings(mustCreateAPIClient(cCtx), encodeOut, organizationID)
+								},
+							},
+							{
+								Name:  "create",
+								Usage: "Request a peering with another organization",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "organization-id",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:     "peer-organization-id",
+										Required: true,
+									},
+									&cli.StringSliceFlag{
+										Name:  "export-device",
+										Usage: "The id of a device to export to the peer organization",
+									},
+									&cli.StringSliceFlag{
+										Name:  "export-prefix",
+										Usage: "Export the devices whose tunnel address or child prefixes are in this cidr",
+									},
+								},
+								Action: func(cCtx *cli.Context) error {
+									encodeOut := cCtx.String("output")
+									organizationID := cCtx.String("organization-id")
+									return createOrganizationPeering(mustCreateAPIClient(cCtx), encodeOut, organizationID, cCtx.String("peer-organization-id"),
+										cCtx.StringSlice("export-device"), cCtx.StringSlice("export-prefix"))
+								},
+							},
+							{
+								Name:  "update",
+								Usage: "Update the exports of an organization or approve a peering",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "organization-id",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:     "peering-id",
+										Required: true,
+									},
+									&cli.StringSliceFlag{
+										Name:  "export-device",
+										Usage: "The id of a device to export to the peer organization",
+									},
+									&cli.StringSliceFlag{
+										Name:  "export-prefix",
+										Usage: "Export the devices whose tunnel address or child prefixes are in this cidr",
+									},
+									&cli.BoolFlag{
+										Name:  "approve",
+										Usage: "Approve the peering requested by another organization",
+									},
+								},
+								Action: func(cCtx *cli.Context) error {
+									encodeOut := cCtx.String("output")
+									organizationID := cCtx.String("organization-id")
+									return updateOrganizationPeering(mustCreateAPIClient(cCtx), encodeOut, organizationID, cCtx.String("peering-id"),
+										cCtx.StringSlice("export-device"), cCtx.StringSlice("export-prefix"), cCtx.Bool("approve"))
+								},
+							},
+							{
+								Name:  "delete",
+								Usage: "Delete a peering",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "organization-id",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:     "peering-id",
+										Required: true,
+									},
+								},
+								Action: func(cCtx *cli.Context) error {
+									encodeOut := cCtx.String("output")
+									organizationID := cCtx.String("organization-id")
+									return deleteOrganizationPeering(mustCreateAPIClient(cCtx), encodeOut, organizationID, cCtx.String("peering-id"))
+								},
+							},
+						},
+					},
 				},
 			},
 			{
@@ -402,6 +600,62 @@ func main() {
 					},
 				},
 			},
+			{
+				Name:  "personal-access-token",
+				Usage: "Commands relating to personal access tokens",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List the personal access tokens of the current user",
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							return listPersonalAccessTokens(mustCreateAPIClient(cCtx), encodeOut)
+						},
+					},
+					{
+						Name:  "create",
+						Usage: "Create a personal access token",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "description",
+								Usage: "What the token is used for",
+							},
+							&cli.StringSliceFlag{
+								Name:     "scopes",
+								Usage:    "Scopes granted to the token: read:organizations, write:organizations, read:users, write:users, read:devices, write:devices",
+								Required: true,
+							},
+							&cli.DurationFlag{
+								Name:  "expires-in",
+								Usage: "How long the token is valid for, at most 8760h",
+								Value: 90 * 24 * time.Hour,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							description := cCtx.String("description")
+							scopes := cCtx.StringSlice("scopes")
+							expiresIn := cCtx.Duration("expires-in")
+							return createPersonalAccessToken(mustCreateAPIClient(cCtx), encodeOut, description, scopes, expiresIn)
+						},
+					},
+					{
+						Name:  "delete",
+						Usage: "Delete a personal access token",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "token-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							tokenID := cCtx.String("token-id")
+							return deletePersonalAccessToken(mustCreateAPIClient(cCtx), encodeOut, tokenID)
+						},
+					},
+				},
+			},
 			{
 				Name:  "invitation",
 				Usage: "commands relating to invitations",
@@ -481,10 +735,15 @@ func mustCreateAPIClient(cCtx *cli.Context) *client.APIClient {
 }
 
 func createClientOptions(cCtx *cli.Context) []client.Option {
-	options := []client.Option{client.WithPasswordGrant(
-		cCtx.String("username"),
-		cCtx.String("password"),
-	)}
+	var options []client.Option
+	if token := cCtx.String("token"); token != "" {
+		options = append(options, client.WithBearerToken(token))
+	} else {
+		options = append(options, client.WithPasswordGrant(
+			cCtx.String("username"),
+			cCtx.String("password"),
+		))
+	}
 	if cCtx.Bool("insecure-skip-tls-verify") { // #nosec G402
 		options = append(options, client.WithTLSConfig(&tls.Config{
 			InsecureSkipVerify: true,
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/urfave/cli/v2"
+)
+
+type NexdOrganization struct {
+	Id         string
+	Name       string
+	Interface  string
+	TunnelIP   string
+	TunnelIpV6 string
+}
+
+func cmdListNexdOrganizations(cCtx *cli.Context, encodeOut string) error {
+	var orgs []NexdOrganization
+	if err := checkVersion(); err != nil {
+		return err
+	}
+
+	result, err := callNexd("ListOrganizations", "")
+	if err != nil {
+		return fmt.Errorf("Failed to list organizations: %w\n", err)
+	}
+
+	err = json.Unmarshal([]byte(result), &orgs)
+	if err != nil {
+		return fmt.Errorf("Failed to marshall organization results: %w\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fs := "%s\t%s\t%s\t%s\t%s\n"
+		w := newTabWriter()
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "ORGANIZATION ID", "NAME", "INTERFACE", "TUNNEL IPV4", "TUNNEL IPV6")
+		}
+		for _, org := range orgs {
+			fmt.Fprintf(w, fs, org.Id, org.Name, org.Interface, org.TunnelIP, org.TunnelIpV6)
+		}
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, orgs)
+	if err != nil {
+		log.Fatalf("Failed to print output: %v", err)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/api/public"
@@ -40,7 +41,7 @@ func listOrganizations(c *client.APIClient, encodeOut string) error {
 	return nil
 }
 
-func createOrganization(c *client.APIClient, encodeOut, name, description, cidr string, cidrV6 string, hub bool) error {
+func createOrganization(c *client.APIClient, encodeOut, name, description, cidr string, cidrV6 string, hub bool, dnsSuffix string) error {
 	res, _, err := c.OrganizationsApi.CreateOrganization(context.Background()).Organization(public.ModelsAddOrganization{
 		Name:        name,
 		Description: description,
@@ -48,6 +49,7 @@ func createOrganization(c *client.APIClient, encodeOut, name, description, cidr
 		CidrV6:      cidrV6,
 		HubZone:     hub,
 		PrivateCidr: !(cidr == "" && cidrV6 == ""),
+		DnsSuffix:   dnsSuffix,
 	}).Execute()
 	if err != nil {
 		log.Fatal(err)
@@ -115,3 +117,260 @@ func deleteOrganization(c *client.APIClient, encodeOut, OrganizationID string) e
 
 	return nil
 }
+
+func updateOrganization(c *client.APIClient, encodeOut, OrganizationID, description, cidr, cidrV6, dnsSuffix string, deviceExpirySeconds *int32) error {
+	OrganizationUUID, err := uuid.Parse(OrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", OrganizationID, err)
+	}
+
+	res, _, err := c.OrganizationsApi.UpdateOrganization(context.Background(), OrganizationUUID.String()).Update(public.ModelsUpdateOrganization{
+		Description:         description,
+		Cidr:                cidr,
+		CidrV6:              cidrV6,
+		DnsSuffix:           dnsSuffix,
+		DeviceExpirySeconds: deviceExpirySeconds,
+	}).Execute()
+	if err != nil {
+		log.Fatalf("Organization update failed: %v\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully updated Organization %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func renumberOrganization(c *client.APIClient, encodeOut, OrganizationID, cidr, cidrV6 string) error {
+	OrganizationUUID, err := uuid.Parse(OrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", OrganizationID, err)
+	}
+
+	res, _, err := c.OrganizationsApi.RenumberOrganization(context.Background(), OrganizationUUID.String()).Renumber(public.ModelsRenumberOrganization{
+		Cidr:   cidr,
+		CidrV6: cidrV6,
+	}).Execute()
+	if err != nil {
+		log.Fatalf("Organization renumber failed: %v\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully renumbered Organization %s to %s %s\n", res.Id, res.Cidr, res.CidrV6)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func getOrganizationConnectivity(c *client.APIClient, encodeOut, OrganizationID string) error {
+	OrganizationUUID, err := uuid.Parse(OrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", OrganizationID, err)
+	}
+
+	matrix, _, err := c.OrganizationsApi.GetOrganizationConnectivity(context.Background(), OrganizationUUID.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "SOURCE DEVICE ID", "DESTINATION DEVICE ID", "STATUS", "ENDPOINT", "LATEST HANDSHAKE", "REPORTED AT")
+		}
+
+		for _, conn := range matrix {
+			fmt.Fprintf(w, fs, conn.SourceDeviceId, conn.DestinationDeviceId, conn.Status, conn.Endpoint, conn.LatestHandshake, conn.ReportedAt)
+		}
+
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, matrix)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func getOrganizationRoutes(c *client.APIClient, encodeOut, OrganizationID string) error {
+	OrganizationUUID, err := uuid.Parse(OrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", OrganizationID, err)
+	}
+
+	routes, _, err := c.OrganizationsApi.GetOrganizationRoutes(context.Background(), OrganizationUUID.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\t%s\t%t\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", "PREFIX", "KIND", "DEVICE ID", "HOSTNAME", "TUNNEL IP", "ONLINE")
+		}
+
+		for _, route := range routes {
+			fmt.Fprintf(w, fs, route.Prefix, route.Kind, route.DeviceId, route.Hostname, route.TunnelIp, route.Online)
+		}
+
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, routes)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func listOrganizationPeerings(c *client.APIClient, encodeOut, OrganizationID string) error {
+	OrganizationUUID, err := uuid.Parse(OrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", OrganizationID, err)
+	}
+
+	peerings, _, err := c.OrganizationsApi.ListOrganizationPeerings(context.Background(), OrganizationUUID.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%t\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", "PEERING ID", "ORGANIZATION ID", "PEER ORGANIZATION ID", "APPROVED", "EXPORTS", "PEER EXPORTS")
+		}
+
+		for _, peering := range peerings {
+			exports := append(peering.ExportedDevices, peering.ExportedPrefixes...)
+			peerExports := append(peering.PeerExportedDevices, peering.PeerExportedPrefixes...)
+			fmt.Fprintf(w, fs, peering.Id, peering.OrganizationId, peering.PeerOrganizationId, peering.PeerApproved,
+				strings.Join(exports, ","), strings.Join(peerExports, ","))
+		}
+
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, peerings)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func createOrganizationPeering(c *client.APIClient, encodeOut, OrganizationID, peerOrganizationID string, devices, prefixes []string) error {
+	OrganizationUUID, err := uuid.Parse(OrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", OrganizationID, err)
+	}
+	PeerOrganizationUUID, err := uuid.Parse(peerOrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", peerOrganizationID, err)
+	}
+
+	res, _, err := c.OrganizationsApi.CreateOrganizationPeering(context.Background(), OrganizationUUID.String()).Peering(public.ModelsAddOrganizationPeering{
+		PeerOrganizationId: PeerOrganizationUUID.String(),
+		ExportedDevices:    devices,
+		ExportedPrefixes:   prefixes,
+	}).Execute()
+	if err != nil {
+		log.Fatalf("Organization peering create failed: %v\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully requested the peering %s, waiting for the approval of organization %s\n", res.Id, res.PeerOrganizationId)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func updateOrganizationPeering(c *client.APIClient, encodeOut, OrganizationID, peeringID string, devices, prefixes []string, approve bool) error {
+	OrganizationUUID, err := uuid.Parse(OrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", OrganizationID, err)
+	}
+	PeeringUUID, err := uuid.Parse(peeringID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", peeringID, err)
+	}
+
+	res, _, err := c.OrganizationsApi.UpdateOrganizationPeering(context.Background(), OrganizationUUID.String(), PeeringUUID.String()).Update(public.ModelsUpdateOrganizationPeering{
+		ExportedDevices:  devices,
+		ExportedPrefixes: prefixes,
+		Approved:         approve,
+	}).Execute()
+	if err != nil {
+		log.Fatalf("Organization peering update failed: %v\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully updated the peering %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func deleteOrganizationPeering(c *client.APIClient, encodeOut, OrganizationID, peeringID string) error {
+	OrganizationUUID, err := uuid.Parse(OrganizationID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", OrganizationID, err)
+	}
+	PeeringUUID, err := uuid.Parse(peeringID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", peeringID, err)
+	}
+
+	res, _, err := c.OrganizationsApi.DeleteOrganizationPeering(context.Background(), OrganizationUUID.String(), PeeringUUID.String()).Execute()
+	if err != nil {
+		log.Fatalf("Organization peering delete failed: %v\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully deleted the peering %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
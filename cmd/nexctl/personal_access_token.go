@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/client"
+)
+
+func listPersonalAccessTokens(c *client.APIClient, encodeOut string) error {
+	tokens, _, err := c.PersonalAccessTokensApi.ListPersonalAccessTokens(context.Background()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "TOKEN ID", "DESCRIPTION", "SCOPES", "EXPIRY")
+		}
+
+		for _, token := range tokens {
+			fmt.Fprintf(w, fs, token.Id, token.Description, strings.Join(token.Scopes, ","), token.Expiry)
+		}
+
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, tokens)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func createPersonalAccessToken(c *client.APIClient, encodeOut, description string, scopes []string, expiresIn time.Duration) error {
+	res, _, err := c.PersonalAccessTokensApi.CreatePersonalAccessToken(context.Background()).PersonalAccessToken(public.ModelsAddPersonalAccessToken{
+		Description: description,
+		Scopes:      scopes,
+		Expiry:      time.Now().Add(expiresIn).UTC().Format(time.RFC3339),
+	}).Execute()
+	if err != nil {
+		log.Fatalf("create personal access token failed: %v\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully created personal access token %s, it expires at %s\n", res.Id, res.Expiry)
+		fmt.Printf("the token is only shown once: %s\n", res.Token)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func deletePersonalAccessToken(c *client.APIClient, encodeOut, id string) error {
+	tokenID, err := uuid.Parse(id)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", id, err)
+	}
+	res, _, err := c.PersonalAccessTokensApi.DeletePersonalAccessToken(context.Background(), tokenID.String()).Execute()
+	if err != nil {
+		log.Fatalf("delete personal access token failed: %v\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully deleted personal access token %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
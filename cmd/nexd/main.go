@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"runtime"
@@ -32,6 +33,8 @@ const (
 	nexdModeProxy
 	nexdModeRouter
 	nexdModeRelay
+	nexdModeExitNode
+	nexdModeHub
 )
 
 // This variable is set using ldflags at build time. See Makefile for details.
@@ -80,21 +83,46 @@ func nexdRun(cCtx *cli.Context, logger *zap.Logger, logLevel *zap.AtomicLevel, m
 
 	userspaceMode := false
 	relayNode := false
+	hubNode := false
+	exitNode := false
 	var childPrefix []string
+	var childPrefixPriority int32
 	switch mode {
 	case nexdModeAgent:
 		logger.Info("Starting node agent with wireguard driver")
 	case nexdModeRouter:
 		childPrefix = cCtx.StringSlice("child-prefix")
+		childPrefixPriority = int32(cCtx.Int("child-prefix-priority"))
 		logger.Info("Starting node agent with wireguard driver and router function")
 	case nexdModeRelay:
 		relayNode = true
 		logger.Info("Starting relay agent with wireguard driver")
+	case nexdModeHub:
+		hubNode = true
+		logger.Info("Starting hub agent with wireguard driver")
+	case nexdModeExitNode:
+		exitNode = true
+		logger.Info("Starting node agent with wireguard driver and exit node function")
 	case nexdModeProxy:
 		userspaceMode = true
 		logger.Info("Starting in L4 proxy mode")
 	}
 
+	useExitNode := cCtx.String("use-exit-node")
+	if useExitNode != "" {
+		if mode != nexdModeAgent && mode != nexdModeRouter {
+			return fmt.Errorf("--use-exit-node is only supported by the agent and router modes")
+		}
+		if runtime.GOOS != nexodus.Linux.String() {
+			return fmt.Errorf("--use-exit-node is only supported for Linux Operating System")
+		}
+	}
+
+	orgIds := cCtx.StringSlice("organization-id")
+	if len(orgIds) > 1 && mode != nexdModeAgent && mode != nexdModeRouter {
+		return fmt.Errorf("joining multiple organizations is only supported by the agent and router modes")
+	}
+
 	stunServers := cCtx.StringSlice("stun-server")
 	if stunServers != nil {
 		if len(stunServers) < 2 {
@@ -115,15 +143,20 @@ func nexdRun(cCtx *cli.Context, logger *zap.Logger, logLevel *zap.AtomicLevel, m
 		cCtx.String("request-ip"),
 		cCtx.String("local-endpoint-ip"),
 		childPrefix,
+		childPrefixPriority,
 		cCtx.Bool("stun"),
 		relayNode,
+		hubNode,
 		cCtx.Bool("relay-only"),
+		exitNode,
+		useExitNode,
+		cCtx.Bool("mesh-dns"),
 		cCtx.Bool("insecure-skip-tls-verify"),
 		Version,
 		userspaceMode,
 		cCtx.String("state-dir"),
 		ctx,
-		cCtx.String("organization-id"),
+		orgIds,
 	)
 	if err != nil {
 		logger.Fatal(err.Error())
@@ -131,6 +164,25 @@ func nexdRun(cCtx *cli.Context, logger *zap.Logger, logLevel *zap.AtomicLevel, m
 
 	wg := &sync.WaitGroup{}
 
+	metricsListen := cCtx.String("metrics-listen")
+	healthListen := cCtx.String("health-listen")
+	if metricsListen != "" && metricsListen == healthListen {
+		if err := nex.HttpServerStart(ctx, wg, metricsListen, true, true); err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to start the http server on %s: %v", metricsListen, err))
+		}
+	} else {
+		if metricsListen != "" {
+			if err := nex.HttpServerStart(ctx, wg, metricsListen, true, false); err != nil {
+				logger.Fatal(fmt.Sprintf("Failed to start the metrics server on %s: %v", metricsListen, err))
+			}
+		}
+		if healthListen != "" {
+			if err := nex.HttpServerStart(ctx, wg, healthListen, false, true); err != nil {
+				logger.Fatal(fmt.Sprintf("Failed to start the health server on %s: %v", healthListen, err))
+			}
+		}
+	}
+
 	for _, egressRule := range cCtx.StringSlice("egress") {
 		rule, err := nexodus.ParseProxyRule(egressRule, nexodus.ProxyTypeEgress)
 		if err != nil {
@@ -247,6 +299,17 @@ func main() {
 							return nil
 						},
 					},
+					&cli.IntFlag{
+						Name:    "child-prefix-priority",
+						Usage:   "When several routers advertise the same child prefix, the one with the highest `priority` that is online routes it (optional)",
+						EnvVars: []string{"NEXD_CHILD_PREFIX_PRIORITY"},
+						Action: func(ctx *cli.Context, priority int) error {
+							if priority < 0 || priority > math.MaxInt32 {
+								return fmt.Errorf("--child-prefix-priority %d is out of range", priority)
+							}
+							return nil
+						},
+					},
 				},
 			},
 			{
@@ -260,6 +323,28 @@ func main() {
 					return nexdRun(cCtx, logger, logLevel, nexdModeRelay)
 				},
 			},
+			{
+				Name:  "hub",
+				Usage: "Enable hub function of the node agent, the other devices of a hub organization route their traffic through the hubs.",
+				Action: func(cCtx *cli.Context) error {
+					if runtime.GOOS != nexodus.Linux.String() {
+						return fmt.Errorf("Hub node is only supported for Linux Operating System")
+					}
+
+					return nexdRun(cCtx, logger, logLevel, nexdModeHub)
+				},
+			},
+			{
+				Name:  "exit-node",
+				Usage: "Enable exit node function of the node agent to forward the internet traffic of other devices.",
+				Action: func(cCtx *cli.Context) error {
+					if runtime.GOOS != nexodus.Linux.String() {
+						return fmt.Errorf("Exit node is only supported for Linux Operating System")
+					}
+
+					return nexdRun(cCtx, logger, logLevel, nexdModeExitNode)
+				},
+			},
 		},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
@@ -337,6 +422,38 @@ func main() {
 				Required: false,
 				Category: agentOptions,
 			},
+			&cli.StringFlag{
+				Name:     "use-exit-node",
+				Value:    "",
+				Usage:    "Send the internet traffic of this host through the exit node with the given device `id`, hostname or tunnel address (optional)",
+				EnvVars:  []string{"NEXD_USE_EXIT_NODE"},
+				Required: false,
+				Category: agentOptions,
+			},
+			&cli.BoolFlag{
+				Name:     "mesh-dns",
+				Usage:    "Resolve the hostnames of the devices in the organization as <hostname>.<organization dns suffix> with a dns server on the tunnel address",
+				Value:    true,
+				EnvVars:  []string{"NEXD_MESH_DNS"},
+				Required: false,
+				Category: agentOptions,
+			},
+			&cli.StringFlag{
+				Name:     "metrics-listen",
+				Value:    "",
+				Usage:    "Serve prometheus metrics on the given `address:port`, for example 127.0.0.1:9100 (optional)",
+				EnvVars:  []string{"NEXD_METRICS_LISTEN"},
+				Required: false,
+				Category: agentOptions,
+			},
+			&cli.StringFlag{
+				Name:     "health-listen",
+				Value:    "",
+				Usage:    "Serve the /healthz and /readyz probes on the given `address:port`, may be the same as --metrics-listen (optional)",
+				EnvVars:  []string{"NEXD_HEALTH_LISTEN"},
+				Required: false,
+				Category: agentOptions,
+			},
 			&cli.StringFlag{
 				Name:     "username",
 				Value:    "",
@@ -374,9 +491,9 @@ func main() {
 				EnvVars:  []string{"NEXD_STUN_SERVER"},
 				Category: nexServiceOptions,
 			},
-			&cli.StringFlag{
+			&cli.StringSliceFlag{
 				Name:     "organization-id",
-				Usage:    "Organization ID to use when registering with the nexodus service",
+				Usage:    "Organization ID to use when registering with the nexodus service. Repeat the flag to join several organizations, each with its own tunnel interface",
 				EnvVars:  []string{"NEXD_ORG_ID"},
 				Required: false,
 				Category: nexServiceOptions,
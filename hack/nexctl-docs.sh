@@ -13,7 +13,7 @@ echo '```text' >> docs/user-guide/nexctl.md.tmp
 dist/nexctl -h | docker run -i --rm --name txt2man quay.io/nexodus/mock:latest txt2man -t nexctl | man -l - | cat >> docs/user-guide/nexctl.md.tmp
 echo '```' >> docs/user-guide/nexctl.md.tmp
 
-for subcmd in device invitation nexd organization user security-group; do
+for subcmd in device invitation nexd organization personal-access-token user security-group; do
     printf "\n#### nexctl $subcmd\n\n" >> docs/user-guide/nexctl.md.tmp
     echo '```text' >> docs/user-guide/nexctl.md.tmp
     dist/nexctl ${subcmd} -h | docker run -i --rm --name txt2man quay.io/nexodus/mock:latest txt2man -t nexctl-${subcmd} | man -l - | cat >> docs/user-guide/nexctl.md.tmp
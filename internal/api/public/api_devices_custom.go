@@ -123,6 +123,17 @@ func (a *DevicesApiService) ListDevicesInOrganizationWatch(r ApiListDevicesInOrg
 			newErr.model = v
 			return nil, localVarHTTPResponse, newErr
 		}
+		if localVarHTTPResponse.StatusCode == 410 {
+			var v ModelsExpiredError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return nil, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return nil, localVarHTTPResponse, newErr
+		}
 		if localVarHTTPResponse.StatusCode == 429 {
 			var v ModelsBaseError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
@@ -171,9 +182,12 @@ type ApiListDevicesInOrganizationInformer struct {
 	modifiedSignal chan struct{}
 	mu             sync.RWMutex
 	data           map[string]ModelsDevice
+	items          map[string]ModelsDevice
 	response       *http.Response
 	err            error
 	lastRevision   int32
+	connected      bool
+	reconnects     uint64
 }
 
 var ErrContextCanceled = errors.New("context canceled")
@@ -182,18 +196,36 @@ func (s *ApiListDevicesInOrganizationInformer) Changed() <-chan struct{} {
 	return s.modifiedSignal
 }
 
+// Reconnects returns how many times the watch stream has been re-established after it was lost.
+func (s *ApiListDevicesInOrganizationInformer) Reconnects() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reconnects
+}
+
 func (s *ApiListDevicesInOrganizationInformer) Execute() (map[string]ModelsDevice, *http.Response, error) {
 
 	var err error
 	s.mu.Lock()
 	if s.stream == nil {
 		// after an error we can recover by resuming event's from the last revision.
-		s.request.GtRevision(s.lastRevision)
+		s.request = s.request.GtRevision(s.lastRevision)
 		s.stream, s.response, s.err = s.request.ApiService.ListDevicesInOrganizationWatch(s.request)
+		if s.err != nil && s.response != nil && s.response.StatusCode == http.StatusGone {
+			// the server can't resume from the last revision anymore, start over with a full list.
+			s.lastRevision = 0
+			s.items = nil
+			s.request = s.request.GtRevision(0)
+			s.stream, s.response, s.err = s.request.ApiService.ListDevicesInOrganizationWatch(s.request)
+		}
 		err = s.err
 		if s.err == nil {
+			if s.connected {
+				s.reconnects += 1
+			}
+			s.connected = true
 			s.inSync = make(chan struct{})
-			go s.readStream(s.lastRevision)
+			go s.readStream(s.request.organizationId, s.lastRevision, s.items)
 		}
 	}
 	s.mu.Unlock()
@@ -218,9 +250,20 @@ func (s *ApiListDevicesInOrganizationInformer) Execute() (map[string]ModelsDevic
 	return s.data, s.response, s.err
 }
 
-func (s *ApiListDevicesInOrganizationInformer) readStream(lastRevision int32) {
+func (s *ApiListDevicesInOrganizationInformer) readStream(organizationId string, lastRevision int32, items map[string]ModelsDevice) {
 	isInSync := false
 
+	if lastRevision == 0 || items == nil {
+		items = map[string]ModelsDevice{}
+	}
+	// the stream resumes the changes of the organization's own devices from the last revision, but
+	// it sends all the devices imported from the peered organizations again before the bookmark.
+	for id, item := range items {
+		if item.OrganizationId != organizationId {
+			delete(items, id)
+		}
+	}
+
 	defer func() {
 		s.mu.Lock()
 		err := s.stream.Close()
@@ -228,6 +271,8 @@ func (s *ApiListDevicesInOrganizationInformer) readStream(lastRevision int32) {
 			s.err = err
 		}
 		s.stream = nil
+		// keep the items so that the next stream can resume from the last revision.
+		s.items = items
 		s.mu.Unlock()
 		if !isInSync {
 			isInSync = true
@@ -235,31 +280,43 @@ func (s *ApiListDevicesInOrganizationInformer) readStream(lastRevision int32) {
 		}
 	}()
 
-	items := map[string]ModelsDevice{}
 	for {
 		event, item, err := s.stream.Receive()
 		if err != nil {
 			s.setResult(nil, lastRevision, err)
 			return
 		}
+		// the imported devices have revisions of other organizations, only the revisions of
+		// the organization's own devices can be resumed from.
+		own := item.OrganizationId == organizationId
 		switch event {
 		case "change":
-			lastRevision = item.Revision
+			if own {
+				lastRevision = item.Revision
+			}
 			items[item.Id] = item
 			if isInSync {
 				s.setResult(dataByIdToByKey(items), lastRevision, nil)
 			}
 		case "delete":
-			lastRevision = item.Revision
+			if own {
+				lastRevision = item.Revision
+			}
 			delete(items, item.Id)
 			if isInSync {
 				s.setResult(dataByIdToByKey(items), lastRevision, nil)
 			}
 		case "bookmark":
+			// the bookmark value only carries the revision the stream has sent all the changes up to.
+			if item.Revision > lastRevision {
+				lastRevision = item.Revision
+			}
 			if !isInSync {
 				isInSync = true
 				s.setResult(dataByIdToByKey(items), lastRevision, nil)
 				close(s.inSync)
+			} else {
+				s.setLastRevision(lastRevision)
 			}
 		case "close":
 			return
@@ -281,6 +338,13 @@ func dataByIdToByKey(dataById map[string]ModelsDevice) map[string]ModelsDevice {
 	return data
 }
 
+// setLastRevision records the revision to resume from without signaling a change.
+func (s *ApiListDevicesInOrganizationInformer) setLastRevision(lastRevision int32) {
+	s.mu.Lock()
+	s.lastRevision = lastRevision
+	s.mu.Unlock()
+}
+
 func (s *ApiListDevicesInOrganizationInformer) setResult(data map[string]ModelsDevice, lastRevision int32, err error) {
 	s.mu.Lock()
 	s.data = data
@@ -35,7 +35,7 @@ func (r ApiGetFeatureFlagRequest) Execute() (map[string]bool, *http.Response, er
 /*
 GetFeatureFlag Get Feature Flag
 
-Gets a Feature Flag by name
+Gets a Feature Flag by name, and whether it is enabled for the current user
 
 	@param ctx context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
 	@param name feature flag name
@@ -142,6 +142,17 @@ func (a *FFlagApiService) GetFeatureFlagExecute(r ApiGetFeatureFlagRequest) (map
 			}
 			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
 			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
+		if localVarHTTPResponse.StatusCode == 500 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
 		}
 		return localVarReturnValue, localVarHTTPResponse, newErr
 	}
@@ -170,7 +181,7 @@ func (r ApiListFeatureFlagsRequest) Execute() (map[string]bool, *http.Response,
 /*
 ListFeatureFlags List Feature Flags
 
-Lists all feature flags
+Lists all feature flags and whether they are enabled for the current user
 
 	@param ctx context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
 	@return ApiListFeatureFlagsRequest
@@ -252,6 +263,17 @@ func (a *FFlagApiService) ListFeatureFlagsExecute(r ApiListFeatureFlagsRequest)
 			}
 			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
 			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
+		if localVarHTTPResponse.StatusCode == 500 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
 		}
 		return localVarReturnValue, localVarHTTPResponse, newErr
 	}
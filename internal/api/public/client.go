@@ -50,6 +50,8 @@ type APIClient struct {
 
 	// API Services
 
+	AdminApi *AdminApiService
+
 	AuthApi *AuthApiService
 
 	DevicesApi *DevicesApiService
@@ -60,6 +62,8 @@ type APIClient struct {
 
 	OrganizationsApi *OrganizationsApiService
 
+	PersonalAccessTokensApi *PersonalAccessTokensApiService
+
 	SecurityGroupApi *SecurityGroupApiService
 
 	UsersApi *UsersApiService
@@ -81,11 +85,13 @@ func NewAPIClient(cfg *Configuration) *APIClient {
 	c.common.client = c
 
 	// API Services
+	c.AdminApi = (*AdminApiService)(&c.common)
 	c.AuthApi = (*AuthApiService)(&c.common)
 	c.DevicesApi = (*DevicesApiService)(&c.common)
 	c.FFlagApi = (*FFlagApiService)(&c.common)
 	c.InvitationApi = (*InvitationApiService)(&c.common)
 	c.OrganizationsApi = (*OrganizationsApiService)(&c.common)
+	c.PersonalAccessTokensApi = (*PersonalAccessTokensApiService)(&c.common)
 	c.SecurityGroupApi = (*SecurityGroupApiService)(&c.common)
 	c.UsersApi = (*UsersApiService)(&c.common)
 
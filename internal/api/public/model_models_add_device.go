@@ -13,10 +13,12 @@ package public
 // ModelsAddDevice struct for ModelsAddDevice
 type ModelsAddDevice struct {
 	ChildPrefix             []string         `json:"child_prefix,omitempty"`
+	ChildPrefixPriority     int32            `json:"child_prefix_priority,omitempty"`
 	Discovery               bool             `json:"discovery,omitempty"`
 	EndpointLocalAddressIp4 string           `json:"endpoint_local_address_ip4,omitempty"`
 	Endpoints               []ModelsEndpoint `json:"endpoints,omitempty"`
 	Hostname                string           `json:"hostname,omitempty"`
+	Hub                     bool             `json:"hub,omitempty"`
 	OrganizationId          string           `json:"organization_id,omitempty"`
 	Os                      string           `json:"os,omitempty"`
 	PublicKey               string           `json:"public_key,omitempty"`
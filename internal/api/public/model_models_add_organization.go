@@ -12,11 +12,13 @@ package public
 
 // ModelsAddOrganization struct for ModelsAddOrganization
 type ModelsAddOrganization struct {
-	Cidr            string `json:"cidr,omitempty"`
-	CidrV6          string `json:"cidr_v6,omitempty"`
-	Description     string `json:"description,omitempty"`
-	HubZone         bool   `json:"hub_zone,omitempty"`
-	Name            string `json:"name,omitempty"`
-	PrivateCidr     bool   `json:"private_cidr,omitempty"`
-	SecurityGroupId string `json:"security_group_id,omitempty"`
+	Cidr                string `json:"cidr,omitempty"`
+	CidrV6              string `json:"cidr_v6,omitempty"`
+	Description         string `json:"description,omitempty"`
+	DeviceExpirySeconds int32  `json:"device_expiry_seconds,omitempty"`
+	DnsSuffix           string `json:"dns_suffix,omitempty"`
+	HubZone             bool   `json:"hub_zone,omitempty"`
+	Name                string `json:"name,omitempty"`
+	PrivateCidr         bool   `json:"private_cidr,omitempty"`
+	SecurityGroupId     string `json:"security_group_id,omitempty"`
 }
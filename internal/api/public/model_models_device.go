@@ -12,13 +12,18 @@ package public
 
 // ModelsDevice struct for ModelsDevice
 type ModelsDevice struct {
+	ActiveChildPrefix       []string         `json:"active_child_prefix,omitempty"`
 	AllowedIps              []string         `json:"allowed_ips,omitempty"`
 	ChildPrefix             []string         `json:"child_prefix,omitempty"`
+	ChildPrefixPriority     int32            `json:"child_prefix_priority,omitempty"`
 	Discovery               bool             `json:"discovery,omitempty"`
 	EndpointLocalAddressIp4 string           `json:"endpoint_local_address_ip4,omitempty"`
 	Endpoints               []ModelsEndpoint `json:"endpoints,omitempty"`
 	Hostname                string           `json:"hostname,omitempty"`
+	Hub                     bool             `json:"hub,omitempty"`
 	Id                      string           `json:"id,omitempty"`
+	LastSeen                string           `json:"last_seen,omitempty"`
+	Online                  bool             `json:"online,omitempty"`
 	OrganizationId          string           `json:"organization_id,omitempty"`
 	OrganizationPrefix      string           `json:"organization_prefix,omitempty"`
 	OrganizationPrefixV6    string           `json:"organization_prefix_v6,omitempty"`
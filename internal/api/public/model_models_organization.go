@@ -12,9 +12,13 @@ package public
 
 // ModelsOrganization struct for ModelsOrganization
 type ModelsOrganization struct {
-	Cidr            string             `json:"cidr,omitempty"`
-	CidrV6          string             `json:"cidr_v6,omitempty"`
-	Description     string             `json:"description,omitempty"`
+	Cidr        string `json:"cidr,omitempty"`
+	CidrV6      string `json:"cidr_v6,omitempty"`
+	Description string `json:"description,omitempty"`
+	// DeviceExpirySeconds is how long a device can go unseen before it is removed, 0 disables expiry.
+	DeviceExpirySeconds int32 `json:"device_expiry_seconds,omitempty"`
+	// DnsSuffix is the domain the device hostnames are resolved in by the mesh dns, empty uses <name>.nexodus.local.
+	DnsSuffix       string             `json:"dns_suffix,omitempty"`
 	HubZone         bool               `json:"hub_zone,omitempty"`
 	Id              string             `json:"id,omitempty"`
 	Invitations     []ModelsInvitation `json:"invitations,omitempty"`
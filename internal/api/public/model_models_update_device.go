@@ -12,7 +12,9 @@ package public
 
 // ModelsUpdateDevice struct for ModelsUpdateDevice
 type ModelsUpdateDevice struct {
-	ChildPrefix             []string         `json:"child_prefix,omitempty"`
+	ChildPrefix []string `json:"child_prefix,omitempty"`
+	// ChildPrefixPriority is left unchanged when it is not set, it can be set to 0.
+	ChildPrefixPriority     *int32           `json:"child_prefix_priority,omitempty"`
 	EndpointLocalAddressIp4 string           `json:"endpoint_local_address_ip4,omitempty"`
 	Endpoints               []ModelsEndpoint `json:"endpoints,omitempty"`
 	Hostname                string           `json:"hostname,omitempty"`
@@ -48,6 +48,12 @@ func NewAPIClient(ctx context.Context, addr string, authcb func(string), options
 	clientConfig.Host = baseURL.Host
 	clientConfig.Scheme = baseURL.Scheme
 
+	if opts.bearerToken != "" {
+		source := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opts.bearerToken})
+		clientConfig.HTTPClient = oauth2.NewClient(ctx, source)
+		return public.NewAPIClient(clientConfig), nil
+	}
+
 	apiClient := public.NewAPIClient(clientConfig)
 
 	resp, _, err := apiClient.AuthApi.DeviceStart(ctx).Execute()
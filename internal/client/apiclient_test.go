@@ -74,6 +74,32 @@ func TestWithTokenFileOption(t *testing.T) {
 
 }
 
+func TestWithBearerTokenOption(t *testing.T) {
+	require := require.New(t)
+
+	mockRouter := http.NewServeMux()
+	mockServer := httptest.NewServer(mockRouter)
+	defer mockServer.Close()
+
+	// the oidc routes are not used.
+	mockRouter.HandleFunc("/", func(resp http.ResponseWriter, req *http.Request) {
+		sendJson(resp, http.StatusNotFound, nil)
+	})
+	var authorization atomic.Value
+	mockRouter.HandleFunc("/api/users/me", func(resp http.ResponseWriter, req *http.Request) {
+		authorization.Store(req.Header.Get("Authorization"))
+		sendJson(resp, http.StatusOK, "{}")
+	})
+
+	c, err := client.NewAPIClient(context.Background(), mockServer.URL, nil,
+		client.WithBearerToken("nexpat_secret"),
+	)
+	require.NoError(err)
+	_, _, err = c.UsersApi.GetUser(context.Background(), "me").Execute()
+	require.NoError(err)
+	require.Equal("Bearer nexpat_secret", authorization.Load())
+}
+
 func sendJson(resp http.ResponseWriter, status int, body interface{}) {
 	resp.Header().Add("Content-Type", "application/json")
 	resp.WriteHeader(status)
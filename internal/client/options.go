@@ -10,6 +10,7 @@ type options struct {
 	username     string
 	password     string
 	tokenFile    string
+	bearerToken  string
 	tlsConfig    *tls.Config
 }
 
@@ -61,3 +62,14 @@ func WithTokenFile(
 		return nil
 	}
 }
+
+// WithBearerToken authenticates with a token that does not need to be refreshed, like a
+// personal access token, instead of an OIDC flow.
+func WithBearerToken(
+	token string,
+) Option {
+	return func(o *options) error {
+		o.bearerToken = token
+		return nil
+	}
+}
@@ -19,6 +19,18 @@ import (
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230413_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230428_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230509_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230515_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230516_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230517_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230518_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230519_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230520_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230521_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230522_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230523_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230524_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230525_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230526_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migrations"
 	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
 	"go.opentelemetry.io/otel"
@@ -115,6 +127,18 @@ func Migrations() *migrations.Migrations {
 			migration_20230413_0000.Migrate(),
 			migration_20230428_0000.Migrate(),
 			migration_20230509_0000.Migrate(),
+			migration_20230515_0000.Migrate(),
+			migration_20230516_0000.Migrate(),
+			migration_20230517_0000.Migrate(),
+			migration_20230518_0000.Migrate(),
+			migration_20230519_0000.Migrate(),
+			migration_20230520_0000.Migrate(),
+			migration_20230521_0000.Migrate(),
+			migration_20230522_0000.Migrate(),
+			migration_20230523_0000.Migrate(),
+			migration_20230524_0000.Migrate(),
+			migration_20230525_0000.Migrate(),
+			migration_20230526_0000.Migrate(),
 		},
 	}
 }
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"hash/fnv"
+
+	"gorm.io/gorm"
+)
+
+// RunExclusively runs fn unless another apiserver replica is running a function with the same
+// name, it returns false when fn was skipped. It is used by the periodic background workers so
+// that only one replica does their work at a time. On PostgreSQL the name is held as a
+// transaction level advisory lock while fn runs, the other dialects always run fn.
+func RunExclusively(ctx context.Context, db *gorm.DB, dialect Dialect, name string, fn func() error) (bool, error) {
+	if dialect != DialectPostgreSQL {
+		return true, fn()
+	}
+	ran := false
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if res := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", lockKey(name)).Scan(&locked); res.Error != nil {
+			return res.Error
+		}
+		if !locked {
+			return nil
+		}
+		ran = true
+		return fn()
+	})
+	return ran, err
+}
+
+// LockInTx takes the transaction level advisory lock of the given name on PostgreSQL, waiting for the
+// transaction holding it to end. It serializes the transactions that check and then change rows
+// that no row lock covers. sqlite only has one writer, so it is not locked.
+func LockInTx(tx *gorm.DB, name string) error {
+	if tx.Dialector.Name() == "sqlite" {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey(name)).Error
+}
+
+// lockKey maps a lock name to the key of a PostgreSQL advisory lock.
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte("nexodus/" + name))
+	return int64(hash.Sum64())
+}
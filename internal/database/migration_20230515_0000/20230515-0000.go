@@ -0,0 +1,27 @@
+package migration_20230515_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+// Device adds heartbeat tracking to this table
+type Device struct {
+	LastSeen *time.Time `json:"last_seen"`
+	Online   bool       `json:"online"`
+}
+
+// Organization adds device expiry to this table
+type Organization struct {
+	DeviceExpirySeconds int64 `json:"device_expiry_seconds"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230515-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Device{}),
+		AddTableColumnsAction(&Organization{}),
+	)
+}
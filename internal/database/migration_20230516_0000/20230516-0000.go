@@ -0,0 +1,34 @@
+package migration_20230516_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type PeerStatus struct {
+	PublicKey       string `json:"public_key"`
+	Endpoint        string `json:"endpoint"`
+	Relayed         bool   `json:"relayed"`
+	Healthy         bool   `json:"healthy"`
+	LatestHandshake string `json:"latest_handshake"`
+	TxBytes         int64  `json:"tx_bytes"`
+	RxBytes         int64  `json:"rx_bytes"`
+}
+
+// DevicePeerStatus holds the peer status summary last reported by each device
+type DevicePeerStatus struct {
+	DeviceID       uuid.UUID    `json:"device_id" gorm:"type:uuid;primary_key"`
+	OrganizationID uuid.UUID    `json:"organization_id" gorm:"type:uuid;index"`
+	Peers          []PeerStatus `json:"peers" gorm:"type:JSONB; serializer:json"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230516-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&DevicePeerStatus{}),
+	)
+}
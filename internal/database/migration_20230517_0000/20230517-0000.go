@@ -0,0 +1,38 @@
+package migration_20230517_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type IpamPrefix struct {
+	Namespace uuid.UUID `gorm:"type:uuid;primary_key"`
+	Cidr      string    `gorm:"primary_key"`
+	CreatedAt time.Time
+}
+
+func (IpamPrefix) TableName() string {
+	return "ipam_prefixes"
+}
+
+type IpamAddress struct {
+	Namespace uuid.UUID `gorm:"type:uuid;primary_key"`
+	Address   string    `gorm:"primary_key"`
+	Cidr      string    `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (IpamAddress) TableName() string {
+	return "ipam_addresses"
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230517-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&IpamPrefix{}),
+		CreateTableAction(&IpamAddress{}),
+	)
+}
@@ -0,0 +1,22 @@
+package migration_20230518_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+// Device adds the subnet router election to this table
+type Device struct {
+	ChildPrefixPriority int32          `json:"child_prefix_priority"`
+	ActiveChildPrefix   pq.StringArray `json:"active_child_prefix" gorm:"type:text[]"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230518-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Device{}),
+		// until a prefix is advertised by another device, every device routes its own child prefixes.
+		ExecAction("UPDATE devices SET active_child_prefix = child_prefix", ""),
+	)
+}
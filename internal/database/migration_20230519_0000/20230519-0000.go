@@ -0,0 +1,18 @@
+package migration_20230519_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+// Organization adds the mesh dns suffix to this table
+type Organization struct {
+	DnsSuffix string `json:"dns_suffix"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230519-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Organization{}),
+	)
+}
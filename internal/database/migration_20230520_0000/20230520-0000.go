@@ -0,0 +1,37 @@
+package migration_20230520_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"gorm.io/gorm"
+)
+
+type Base struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// OrganizationPeering connects the meshes of two organizations
+type OrganizationPeering struct {
+	Base
+	OrganizationID       uuid.UUID      `gorm:"type:uuid;index"`
+	PeerOrganizationID   uuid.UUID      `gorm:"type:uuid;index"`
+	ExportedDevices      pq.StringArray `gorm:"type:text[]"`
+	ExportedPrefixes     pq.StringArray `gorm:"type:text[]"`
+	PeerExportedDevices  pq.StringArray `gorm:"type:text[]"`
+	PeerExportedPrefixes pq.StringArray `gorm:"type:text[]"`
+	PeerApproved         bool
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230520-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&OrganizationPeering{}),
+	)
+}
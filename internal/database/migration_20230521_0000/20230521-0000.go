@@ -0,0 +1,18 @@
+package migration_20230521_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+// Device adds the hub flag to this table
+type Device struct {
+	Hub bool `json:"hub"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230521-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Device{}),
+	)
+}
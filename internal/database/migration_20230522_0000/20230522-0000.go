@@ -0,0 +1,23 @@
+package migration_20230522_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+// DeviceCompaction records the highest revision of the device tombstones removed from an organization
+type DeviceCompaction struct {
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;primary_key"`
+	Revision       uint64    `json:"revision"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230522-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&DeviceCompaction{}),
+	)
+}
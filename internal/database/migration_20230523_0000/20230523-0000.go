@@ -0,0 +1,28 @@
+package migration_20230523_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+// AuditEvent records a use of the admin API
+type AuditEvent struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	UserID    string    `json:"user_id" gorm:"index"`
+	UserName  string    `json:"user_name"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Route     string    `json:"route"`
+	Status    int       `json:"status"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230523-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&AuditEvent{}),
+	)
+}
@@ -0,0 +1,31 @@
+package migration_20230524_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken authenticates the API requests of scripts on behalf of a user
+type PersonalAccessToken struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
+	CreatedAt   time.Time      `json:"-"`
+	UpdatedAt   time.Time      `json:"-"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	UserID      string         `json:"user_id" gorm:"index"`
+	Description string         `json:"description"`
+	Scopes      pq.StringArray `json:"scopes" gorm:"type:text[]"`
+	Expiry      time.Time      `json:"expiry"`
+	TokenHash   string         `json:"-" gorm:"uniqueIndex"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230524-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&PersonalAccessToken{}),
+	)
+}
@@ -0,0 +1,26 @@
+package migration_20230525_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+// FeatureFlag configures who a feature flag is enabled for
+type FeatureFlag struct {
+	Name          string         `json:"name" gorm:"primary_key"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	Enabled       bool           `json:"enabled"`
+	Organizations pq.StringArray `json:"organizations" gorm:"type:text[]"`
+	Users         pq.StringArray `json:"users" gorm:"type:text[]"`
+	Percentage    int            `json:"percentage"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230525-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&FeatureFlag{}),
+	)
+}
@@ -0,0 +1,33 @@
+package migration_20230526_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230526-0000"
+	return CreateMigrationFromActions(migrationId,
+		// a heartbeat only updates last_seen, it keeps the revision of the device so that
+		// it is not streamed again to the watchers of the organization.
+		ExecActionIf(`
+			CREATE OR REPLACE FUNCTION devices_revision_trigger() RETURNS TRIGGER LANGUAGE plpgsql AS '
+			BEGIN
+			IF TG_OP = ''UPDATE'' AND
+				to_jsonb(NEW) - ''last_seen'' - ''updated_at'' - ''revision'' =
+				to_jsonb(OLD) - ''last_seen'' - ''updated_at'' - ''revision'' THEN
+				NEW.revision := OLD.revision;
+				RETURN NEW;
+			END IF;
+			NEW.revision := nextval(''devices_revision_seq'');
+			RETURN NEW;
+			END;'
+		`, `
+			CREATE OR REPLACE FUNCTION devices_revision_trigger() RETURNS TRIGGER LANGUAGE plpgsql AS '
+			BEGIN
+			NEW.revision := nextval(''devices_revision_seq'');
+			RETURN NEW;
+			END;'
+		`, NotOnSqlLite),
+	)
+}
@@ -4,11 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"strings"
 )
 
+var transactionRetries = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "apiserver",
+	Name:      "db_transaction_retries_total",
+	Help:      "Number of times a database transaction was retried due to a serialization conflict.",
+})
+
 type TransactionFunc func(
 	ctx context.Context, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions,
 ) error
@@ -45,7 +53,15 @@ func GetTransactionFunc(db *gorm.DB) (TransactionFunc, Dialect, error) {
 			if len(opts) > 0 {
 				o = opts[0]
 			}
-			return crdbgorm.ExecuteTx(ctx, db, o, fn)
+			attempts := 0
+			return crdbgorm.ExecuteTx(ctx, db, o, func(tx *gorm.DB) error {
+				// crdbgorm calls fn again each time it retries the transaction.
+				if attempts > 0 {
+					transactionRetries.Inc()
+				}
+				attempts++
+				return fn(tx)
+			})
 		}, dialect, nil
 	} else {
 		return func(ctx context.Context, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
@@ -57,3 +73,19 @@ func GetTransactionFunc(db *gorm.DB) (TransactionFunc, Dialect, error) {
 		}, dialect, nil
 	}
 }
+
+type txContextKey struct{}
+
+// WithTx returns a copy of ctx that carries the transaction tx, so that code which only
+// receives a context, like the IPAM implementations, can take part in the transaction.
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried by ctx, or db if it does not carry one.
+func TxFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok && tx != nil {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
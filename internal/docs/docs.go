@@ -22,9 +22,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/devices": {
+        "/api/admin/audit_events": {
             "get": {
-                "description": "Lists all devices",
+                "description": "Lists the uses of the admin API, most recent first, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -32,17 +32,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "Admin"
+                ],
+                "summary": "List Audit Events",
+                "operationId": "AdminListAuditEvents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JSON object of the column values to match, like {\\",
+                        "name": "filter",
+                        "in": "query"
+                    }
                 ],
-                "summary": "List Devices",
-                "operationId": "ListDevices",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Device"
+                                "$ref": "#/definitions/models.AuditEvent"
                             }
                         }
                     },
@@ -52,16 +60,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
-            },
-            "post": {
-                "description": "Adds a new device",
+            }
+        },
+        "/api/admin/devices": {
+            "get": {
+                "description": "Lists the devices of all the organizations, the filter finds the organization of a public key with {\"public_key\":\"...\"}, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -69,32 +91,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "Admin"
                 ],
-                "summary": "Add Devices",
-                "operationId": "CreateDevice",
+                "summary": "List All Devices",
+                "operationId": "AdminListDevices",
                 "parameters": [
                     {
-                        "description": "Add Device",
-                        "name": "Device",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.AddDevice"
-                        }
+                        "type": "string",
+                        "description": "JSON object of the column values to match, like {\\",
+                        "name": "filter",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/models.Device"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Device"
+                            }
                         }
                     },
                     "401": {
@@ -103,10 +119,10 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/models.ConflictsError"
+                            "$ref": "#/definitions/models.BaseError"
                         }
                     },
                     "429": {
@@ -124,9 +140,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/devices/{id}": {
-            "get": {
-                "description": "Gets a device by ID",
+        "/api/admin/devices/{id}": {
+            "delete": {
+                "description": "Deletes a device of any organization, the device is deleted even when its ipam leases can't be released, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -134,10 +150,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "Admin"
                 ],
-                "summary": "Get Devices",
-                "operationId": "GetDevice",
+                "summary": "Force Delete Device",
+                "operationId": "AdminDeleteDevice",
                 "parameters": [
                     {
                         "type": "string",
@@ -166,6 +182,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -177,11 +199,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes an existing device and associated IPAM lease",
+            }
+        },
+        "/api/admin/fflags": {
+            "get": {
+                "description": "Lists who the feature flags are enabled for, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -189,28 +219,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
-                ],
-                "summary": "Delete Device",
-                "operationId": "DeleteDevice",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Device ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "Admin"
                 ],
+                "summary": "List Feature Flag Configurations",
+                "operationId": "AdminListFeatureFlags",
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Device"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FeatureFlag"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -228,9 +258,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Updates a device by ID",
+            }
+        },
+        "/api/admin/fflags/{name}": {
+            "put": {
+                "description": "Configures who a feature flag is enabled for, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -238,25 +270,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "Admin"
                 ],
-                "summary": "Update Devices",
-                "operationId": "UpdateDevice",
+                "summary": "Update Feature Flag",
+                "operationId": "AdminUpdateFeatureFlag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Device ID",
-                        "name": "id",
+                        "description": "feature flag name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Device Update",
+                        "description": "Feature Flag Update",
                         "name": "update",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateDevice"
+                            "$ref": "#/definitions/models.UpdateFeatureFlag"
                         }
                     }
                 ],
@@ -264,7 +296,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Device"
+                            "$ref": "#/definitions/models.FeatureFlag"
                         }
                     },
                     "400": {
@@ -279,6 +311,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -290,46 +328,17 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
-                    }
-                }
-            }
-        },
-        "/api/fflags": {
-            "get": {
-                "description": "Lists all feature flags",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "FFlag"
-                ],
-                "summary": "List Feature Flags",
-                "operationId": "ListFeatureFlags",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "boolean"
-                            }
-                        }
                     },
-                    "429": {
-                        "description": "Too Many Requests",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
-            }
-        },
-        "/api/fflags/{name}": {
-            "get": {
-                "description": "Gets a Feature Flag by name",
+            },
+            "delete": {
+                "description": "Removes the configuration of a feature flag so that it uses its default, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -337,10 +346,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "FFlag"
+                    "Admin"
                 ],
-                "summary": "Get Feature Flag",
-                "operationId": "GetFeatureFlag",
+                "summary": "Reset Feature Flag",
+                "operationId": "AdminResetFeatureFlag",
                 "parameters": [
                     {
                         "type": "string",
@@ -351,17 +360,17 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "boolean"
-                            }
+                            "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -377,13 +386,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
             }
         },
-        "/api/invitations": {
+        "/api/admin/organizations": {
             "get": {
-                "description": "Lists all invitations",
+                "description": "Lists the organizations of all the users, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -391,17 +406,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "Admin"
+                ],
+                "summary": "List All Organizations",
+                "operationId": "AdminListOrganizations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JSON object of the column values to match, like {\\",
+                        "name": "filter",
+                        "in": "query"
+                    }
                 ],
-                "summary": "List Invitations",
-                "operationId": "ListInvitations",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Invitation"
+                                "$ref": "#/definitions/models.Organization"
                             }
                         }
                     },
@@ -411,16 +434,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
-            },
-            "post": {
-                "description": "Create an invitation to an organization",
+            }
+        },
+        "/api/admin/users": {
+            "get": {
+                "description": "Lists all the users, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -428,36 +465,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "Admin"
                 ],
-                "summary": "Create an invitation",
-                "operationId": "CreateInvitation",
+                "summary": "List All Users",
+                "operationId": "AdminListUsers",
                 "parameters": [
                     {
-                        "description": "Add Invitation",
-                        "name": "Invitation",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.AddInvitation"
-                        }
+                        "type": "string",
+                        "description": "JSON object of the column values to match, like {\\",
+                        "name": "filter",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Invitation"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -467,13 +504,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
             }
         },
-        "/api/invitations/:invitation/accept": {
-            "post": {
-                "description": "Accept an invitation to an organization",
+        "/api/admin/users/{id}": {
+            "get": {
+                "description": "Gets a user with the organizations it is a member of and its devices, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -481,82 +524,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "Admin"
                 ],
-                "summary": "Accept an invitation",
-                "operationId": "AcceptInvitation",
+                "summary": "Inspect User",
+                "operationId": "AdminGetUser",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Invitation ID",
-                        "name": "invitation",
+                        "description": "User ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.AdminUser"
                         }
                     },
-                    "429": {
-                        "description": "Too Many Requests",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
-                    }
-                }
-            }
-        },
-        "/api/invitations/{invitation}": {
-            "delete": {
-                "description": "Deletes an existing invitation",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Invitation"
-                ],
-                "summary": "Delete Invitation",
-                "operationId": "DeleteInvitation",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Invitation ID",
-                        "name": "invitation",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "$ref": "#/definitions/models.Organization"
-                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -576,9 +577,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations": {
+        "/api/devices": {
             "get": {
-                "description": "Lists all Organizations",
+                "description": "Lists all devices",
                 "consumes": [
                     "application/json"
                 ],
@@ -586,17 +587,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "Devices"
                 ],
-                "summary": "List Organizations",
-                "operationId": "ListOrganizations",
+                "summary": "List Devices",
+                "operationId": "ListDevices",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Organization"
+                                "$ref": "#/definitions/models.Device"
                             }
                         }
                     },
@@ -611,17 +612,11 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
                     }
                 }
             },
             "post": {
-                "description": "Creates a named organization with the given CIDR",
+                "description": "Adds a new device",
                 "consumes": [
                     "application/json"
                 ],
@@ -629,18 +624,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "Devices"
                 ],
-                "summary": "Create an Organization",
-                "operationId": "CreateOrganization",
+                "summary": "Add Devices",
+                "operationId": "CreateDevice",
                 "parameters": [
                     {
-                        "description": "Add Organization",
-                        "name": "Organization",
+                        "description": "Add Device",
+                        "name": "Device",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddOrganization"
+                            "$ref": "#/definitions/models.AddDevice"
                         }
                     }
                 ],
@@ -648,7 +643,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.Device"
                         }
                     },
                     "400": {
@@ -663,10 +658,10 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.QuotaExceededError"
                         }
                     },
                     "409": {
@@ -690,9 +685,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{id}": {
+        "/api/devices/{id}": {
             "get": {
-                "description": "Gets a Organization by Organization ID",
+                "description": "Gets a device by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -700,14 +695,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "Devices"
                 ],
-                "summary": "Get Organizations",
-                "operationId": "GetOrganizations",
+                "summary": "Get Devices",
+                "operationId": "GetDevice",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
+                        "description": "Device ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -717,7 +712,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.Device"
                         }
                     },
                     "400": {
@@ -747,7 +742,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Deletes an existing organization and associated IPAM prefix",
+                "description": "Deletes an existing device and associated IPAM lease",
                 "consumes": [
                     "application/json"
                 ],
@@ -755,14 +750,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "Devices"
                 ],
-                "summary": "Delete Organization",
-                "operationId": "DeleteOrganization",
+                "summary": "Delete Device",
+                "operationId": "DeleteDevice",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
+                        "description": "Device ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -772,7 +767,7 @@ const docTemplate = `{
                     "204": {
                         "description": "No Content",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.Device"
                         }
                     },
                     "400": {
@@ -781,12 +776,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
-                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
@@ -800,11 +789,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/organizations/{id}/devices": {
-            "get": {
-                "description": "Lists all users for this Organization",
+            },
+            "patch": {
+                "description": "Updates a device by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -812,27 +799,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Devices"
                 ],
-                "summary": "List Users",
-                "operationId": "ListUsersInOrganization",
+                "summary": "Update Devices",
+                "operationId": "UpdateDevice",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
+                        "description": "Device ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Device Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateDevice"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.User"
-                            }
+                            "$ref": "#/definitions/models.Device"
                         }
                     },
                     "400": {
@@ -847,6 +840,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.PrefixConflictError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
@@ -862,9 +867,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/devices": {
-            "get": {
-                "description": "Lists all devices for this Organization",
+        "/api/devices/{id}/heartbeat": {
+            "post": {
+                "description": "Records a heartbeat from a device and marks it as online",
                 "consumes": [
                     "application/json"
                 ],
@@ -874,19 +879,13 @@ const docTemplate = `{
                 "tags": [
                     "Devices"
                 ],
-                "summary": "List Devices",
-                "operationId": "ListDevicesInOrganization",
+                "summary": "Device Heartbeat",
+                "operationId": "HeartbeatDevice",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "greater than revision",
-                        "name": "gt_revision",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
+                        "description": "Device ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -895,10 +894,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Device"
-                            }
+                            "$ref": "#/definitions/models.Device"
                         }
                     },
                     "400": {
@@ -913,6 +909,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
@@ -928,9 +930,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/devices/{device_id}": {
-            "get": {
-                "description": "Gets a device in a organization by ID",
+        "/api/devices/{id}/peer_status": {
+            "put": {
+                "description": "Stores the latest peer connectivity summary for a device",
                 "consumes": [
                     "application/json"
                 ],
@@ -940,29 +942,31 @@ const docTemplate = `{
                 "tags": [
                     "Devices"
                 ],
-                "summary": "Get Device",
-                "operationId": "GetDeviceInOrganization",
+                "summary": "Report Device Peer Status",
+                "operationId": "ReportDevicePeerStatus",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
+                        "description": "Device ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Device ID",
-                        "name": "device_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Peer Status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReportPeerStatus"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Device"
+                            "$ref": "#/definitions/models.DevicePeerStatus"
                         }
                     },
                     "400": {
@@ -998,49 +1002,1642 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_group/{id}": {
+        "/api/fflags": {
             "get": {
-                "description": "Gets a security group in an organization by ID",
+                "description": "Lists all feature flags and whether they are enabled for the current user",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
-                ],
-                "summary": "Get SecurityGroup",
-                "operationId": "GetSecurityGroup",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Security Group ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "FFlag"
                 ],
+                "summary": "List Feature Flags",
+                "operationId": "ListFeatureFlags",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/fflags/{name}": {
+            "get": {
+                "description": "Gets a Feature Flag by name, and whether it is enabled for the current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "FFlag"
+                ],
+                "summary": "Get Feature Flag",
+                "operationId": "GetFeatureFlag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "feature flag name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations": {
+            "get": {
+                "description": "Lists all invitations",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "List Invitations",
+                "operationId": "ListInvitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Invitation"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create an invitation to an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "Create an invitation",
+                "operationId": "CreateInvitation",
+                "parameters": [
+                    {
+                        "description": "Add Invitation",
+                        "name": "Invitation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddInvitation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Invitation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations/:invitation/accept": {
+            "post": {
+                "description": "Accept an invitation to an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "Accept an invitation",
+                "operationId": "AcceptInvitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invitation ID",
+                        "name": "invitation",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations/{invitation}": {
+            "get": {
+                "description": "Gets an Invitation by Invitation ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "Get Invitation",
+                "operationId": "GetInvitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invitation ID",
+                        "name": "invitation",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes an existing invitation",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "Delete Invitation",
+                "operationId": "DeleteInvitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invitation ID",
+                        "name": "invitation",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations": {
+            "get": {
+                "description": "Lists all Organizations",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List Organizations",
+                "operationId": "ListOrganizations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Organization"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a named organization with the given CIDR",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Create an Organization",
+                "operationId": "CreateOrganization",
+                "parameters": [
+                    {
+                        "description": "Add Organization",
+                        "name": "Organization",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddOrganization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuotaExceededError"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{id}/devices": {
+            "get": {
+                "description": "Lists all users for this Organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List Users",
+                "operationId": "ListUsersInOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/connectivity": {
+            "get": {
+                "description": "Lists the connectivity status between the device pairs of an Organization, as reported by the devices",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Get Organization Connectivity",
+                "operationId": "GetOrganizationConnectivity",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Connectivity"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/devices": {
+            "get": {
+                "description": "Lists all devices for this Organization, followed by the devices imported from the organizations it is peered with",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "List Devices",
+                "operationId": "ListDevicesInOrganization",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Device"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpiredError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/devices/{device_id}": {
+            "get": {
+                "description": "Gets a device in a organization by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Get Device",
+                "operationId": "GetDeviceInOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Device"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/routes": {
+            "get": {
+                "description": "Lists the prefixes advertised by the devices of an Organization: their tunnel addresses, child prefixes and the organization cidrs forwarded by relays",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Get Organization Routes",
+                "operationId": "GetOrganizationRoutes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Route"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/security_group/{id}": {
+            "get": {
+                "description": "Gets a security group in an organization by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Get SecurityGroup",
+                "operationId": "GetSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/security_groups": {
+            "get": {
+                "description": "Lists all Security Groups",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "List Security Groups",
+                "operationId": "ListSecurityGroups",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SecurityGroup"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a new Security Group",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Add SecurityGroup",
+                "operationId": "CreateSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add SecurityGroup",
+                        "name": "SecurityGroup",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddSecurityGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuotaExceededError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/security_groups/{security_group_id}": {
+            "delete": {
+                "description": "Deletes an existing SecurityGroup",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Delete SecurityGroup",
+                "operationId": "DeleteSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "security_group_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Updates a Security Group by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Update Security Group",
+                "operationId": "UpdateSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "security_group_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Security Group Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateSecurityGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuotaExceededError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization}": {
+            "get": {
+                "description": "Gets a Organization by Organization ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Get Organizations",
+                "operationId": "GetOrganizations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes an existing organization and associated IPAM prefix",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Delete Organization",
+                "operationId": "DeleteOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Updates an Organization by ID, the cidr and cidr_v6 of an organization with a private cidr can be grown in place. A dns_suffix of \".\" resets the mesh dns suffix to the default.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Update Organizations",
+                "operationId": "UpdateOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Organization Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateOrganization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization}/peerings": {
+            "get": {
+                "description": "Lists the peerings requested by or with an Organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List Organization Peerings",
+                "operationId": "ListOrganizationPeerings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OrganizationPeering"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Requests a peering with another Organization and exports devices to it. The devices of each side are listed as peers of the other side once the owner of the peer organization approves the peering.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Add Organization Peering",
+                "operationId": "CreateOrganizationPeering",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add Organization Peering",
+                        "name": "peering",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddOrganizationPeering"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationPeering"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization}/peerings/{id}": {
+            "delete": {
+                "description": "Deletes a peering, either organization of the peering can delete it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Delete Organization Peering",
+                "operationId": "DeleteOrganizationPeering",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Peering ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationPeering"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Updates the devices and prefixes an Organization exports through a peering. The owner of the peer organization approves the peering by setting approved.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Update Organization Peering",
+                "operationId": "UpdateOrganizationPeering",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Peering ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Organization Peering Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateOrganizationPeering"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationPeering"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.PrefixConflictError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization}/renumber": {
+            "post": {
+                "description": "Moves an Organization with a private cidr to a new cidr and cidr_v6, which must not overlap the current ones. The devices are assigned new tunnel addresses from the new ranges.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Renumber Organizations",
+                "operationId": "RenumberOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Organization Renumber",
+                        "name": "renumber",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RenumberOrganization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
@@ -1055,37 +2652,37 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_groups": {
+        "/api/personal_access_tokens": {
             "get": {
-                "description": "Lists all Security Groups",
+                "description": "Lists the personal access tokens of the current user, including the expired ones",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
-                ],
-                "summary": "List Security Groups",
-                "operationId": "ListSecurityGroups",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "PersonalAccessTokens"
                 ],
+                "summary": "List Personal Access Tokens",
+                "operationId": "ListPersonalAccessTokens",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.SecurityGroup"
+                                "$ref": "#/definitions/models.PersonalAccessToken"
                             }
                         }
                     },
@@ -1100,34 +2697,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
             },
             "post": {
-                "description": "Adds a new Security Group",
+                "description": "Creates a personal access token for the current user, the token is only returned by this request",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "PersonalAccessTokens"
                 ],
-                "summary": "Add SecurityGroup",
-                "operationId": "CreateSecurityGroup",
+                "summary": "Create a Personal Access Token",
+                "operationId": "CreatePersonalAccessToken",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Add SecurityGroup",
-                        "name": "SecurityGroup",
+                        "description": "Add Personal Access Token",
+                        "name": "PersonalAccessToken",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddSecurityGroup"
+                            "$ref": "#/definitions/models.AddPersonalAccessToken"
                         }
                     }
                 ],
@@ -1135,7 +2734,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.PersonalAccessToken"
                         }
                     },
                     "400": {
@@ -1150,10 +2749,10 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/models.ConflictsError"
+                            "$ref": "#/definitions/models.BaseError"
                         }
                     },
                     "429": {
@@ -1171,38 +2770,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_groups/{security_group_id}": {
-            "delete": {
-                "description": "Deletes an existing SecurityGroup",
+        "/api/personal_access_tokens/{id}": {
+            "get": {
+                "description": "Gets a personal access token of the current user by ID, the token itself is not returned",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "PersonalAccessTokens"
                 ],
-                "summary": "Delete SecurityGroup",
-                "operationId": "DeleteSecurityGroup",
+                "summary": "Get a Personal Access Token",
+                "operationId": "GetPersonalAccessToken",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Security Group ID",
-                        "name": "security_group_id",
+                        "description": "Personal Access Token ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.PersonalAccessToken"
                         }
                     },
                     "400": {
@@ -1211,60 +2806,53 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "429": {
-                        "description": "Too Many Requests",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
             },
-            "patch": {
-                "description": "Updates a Security Group by ID",
+            "delete": {
+                "description": "Revokes a personal access token of the current user",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "PersonalAccessTokens"
                 ],
-                "summary": "Update Security Group",
-                "operationId": "UpdateSecurityGroup",
+                "summary": "Delete a Personal Access Token",
+                "operationId": "DeletePersonalAccessToken",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Security Group ID",
-                        "name": "security_group_id",
+                        "description": "Personal Access Token ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Security Group Update",
-                        "name": "update",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UpdateSecurityGroup"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.PersonalAccessToken"
                         }
                     },
                     "400": {
@@ -1290,6 +2878,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
             }
@@ -1667,6 +3261,10 @@ const docTemplate = `{
                         "172.16.42.0/24"
                     ]
                 },
+                "child_prefix_priority": {
+                    "type": "integer",
+                    "example": 100
+                },
                 "discovery": {
                     "type": "boolean"
                 },
@@ -1684,6 +3282,9 @@ const docTemplate = `{
                     "type": "string",
                     "example": "myhost"
                 },
+                "hub": {
+                    "type": "boolean"
+                },
                 "organization_id": {
                     "type": "string",
                     "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
@@ -1748,6 +3349,14 @@ const docTemplate = `{
                     "type": "string",
                     "example": "The Red Zone"
                 },
+                "device_expiry_seconds": {
+                    "type": "integer",
+                    "example": 86400
+                },
+                "dns_suffix": {
+                    "type": "string",
+                    "example": "red.example.com"
+                },
                 "hub_zone": {
                     "type": "boolean"
                 },
@@ -1763,6 +3372,57 @@ const docTemplate = `{
                 }
             }
         },
+        "models.AddOrganizationPeering": {
+            "type": "object",
+            "properties": {
+                "exported_devices": {
+                    "description": "ExportedDevices are the ids of the devices exported to the peer organization.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "694aa002-5d19-495e-980b-3d8fd508ea10"
+                    ]
+                },
+                "exported_prefixes": {
+                    "description": "ExportedPrefixes export the devices whose tunnel address or child prefixes are in one of them.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "100.100.0.0/24"
+                    ]
+                },
+                "peer_organization_id": {
+                    "type": "string",
+                    "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
+                }
+            }
+        },
+        "models.AddPersonalAccessToken": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "ci pipeline"
+                },
+                "expiry": {
+                    "description": "Expiry defaults to 90 days from now, and can be at most a year from now.",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read:devices"
+                    ]
+                }
+            }
+        },
         "models.AddSecurityGroup": {
             "type": "object",
             "properties": {
@@ -1791,6 +3451,71 @@ const docTemplate = `{
                 }
             }
         },
+        "models.AdminUser": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organizations": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "username": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "models.AuditEvent": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "DELETE"
+                },
+                "path": {
+                    "description": "Path is the request path, including the query.",
+                    "type": "string",
+                    "example": "/api/admin/devices/aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "route": {
+                    "description": "Route is the route the request matched.",
+                    "type": "string",
+                    "example": "/api/admin/devices/:id"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "user_id": {
+                    "description": "UserID is the id of the admin that made the request.",
+                    "type": "string"
+                },
+                "user_name": {
+                    "type": "string"
+                }
+            }
+        },
         "models.BaseError": {
             "type": "object",
             "properties": {
@@ -1813,9 +3538,40 @@ const docTemplate = `{
                 }
             }
         },
+        "models.Connectivity": {
+            "type": "object",
+            "properties": {
+                "destination_device_id": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string",
+                    "example": "1.2.3.4:51820"
+                },
+                "latest_handshake": {
+                    "type": "string"
+                },
+                "reported_at": {
+                    "type": "string"
+                },
+                "source_device_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "direct"
+                }
+            }
+        },
         "models.Device": {
             "type": "object",
             "properties": {
+                "active_child_prefix": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "allowed_ips": {
                     "type": "array",
                     "items": {
@@ -1828,6 +3584,9 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "child_prefix_priority": {
+                    "type": "integer"
+                },
                 "discovery": {
                     "type": "boolean"
                 },
@@ -1843,10 +3602,19 @@ const docTemplate = `{
                 "hostname": {
                     "type": "string"
                 },
+                "hub": {
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "string",
                     "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
+                "last_seen": {
+                    "type": "string"
+                },
+                "online": {
+                    "type": "boolean"
+                },
                 "organization_id": {
                     "type": "string"
                 },
@@ -1880,7 +3648,27 @@ const docTemplate = `{
                 "tunnel_ip_v6": {
                     "type": "string"
                 },
-                "user_id": {
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DevicePeerStatus": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "peers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PeerStatus"
+                    }
+                },
+                "updated_at": {
                     "type": "string"
                 }
             }
@@ -1919,6 +3707,48 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ExpiredError": {
+            "type": "object",
+            "properties": {
+                "compacted_revision": {
+                    "description": "CompactedRevision is the highest revision that was removed, the client can resume from it or later revisions.",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "something bad"
+                }
+            }
+        },
+        "models.FeatureFlag": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "organizations": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "percentage": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "models.Invitation": {
             "type": "object",
             "properties": {
@@ -1984,6 +3814,14 @@ const docTemplate = `{
                 "description": {
                     "type": "string"
                 },
+                "device_expiry_seconds": {
+                    "description": "DeviceExpirySeconds is how long a device can go unseen before it is removed, 0 disables expiry.",
+                    "type": "integer"
+                },
+                "dns_suffix": {
+                    "description": "DnsSuffix is the domain the device hostnames are resolved in by the mesh dns, empty uses\n\u003cname\u003e.nexodus.local.",
+                    "type": "string"
+                },
                 "hub_zone": {
                     "type": "boolean"
                 },
@@ -2011,6 +3849,206 @@ const docTemplate = `{
                 }
             }
         },
+        "models.OrganizationPeering": {
+            "type": "object",
+            "properties": {
+                "exported_devices": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "exported_prefixes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "description": "OrganizationID is the organization that requested the peering, it approves it by creating it.",
+                    "type": "string"
+                },
+                "peer_approved": {
+                    "type": "boolean"
+                },
+                "peer_exported_devices": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "peer_exported_prefixes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "peer_organization_id": {
+                    "description": "PeerOrganizationID is the organization that has to approve the peering.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.PeerStatus": {
+            "type": "object",
+            "properties": {
+                "endpoint": {
+                    "type": "string",
+                    "example": "1.2.3.4:51820"
+                },
+                "healthy": {
+                    "type": "boolean"
+                },
+                "latest_handshake": {
+                    "type": "string"
+                },
+                "public_key": {
+                    "type": "string"
+                },
+                "relayed": {
+                    "type": "boolean"
+                },
+                "rx_bytes": {
+                    "type": "integer",
+                    "format": "int64"
+                },
+                "tx_bytes": {
+                    "type": "integer",
+                    "format": "int64"
+                }
+            }
+        },
+        "models.PersonalAccessToken": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "expiry": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "description": "Token is only returned by the create request.",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PrefixConflictError": {
+            "type": "object",
+            "properties": {
+                "conflicts_with": {
+                    "description": "ConflictsWith is empty when the prefix conflicts with a prefix of a peer organization,\nwhich is not disclosed.",
+                    "type": "string",
+                    "example": "172.16.0.0/16"
+                },
+                "device_id": {
+                    "description": "DeviceID is the device that the conflicting prefix is routed to, it is empty when the\nprefix conflicts with the organization cidr.",
+                    "type": "string",
+                    "example": "a1fae5de-dd96-4b20-8362-95f6a574c4b1"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "something bad"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "172.16.42.0/24"
+                }
+            }
+        },
+        "models.QuotaExceededError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "something bad"
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "quota": {
+                    "type": "string",
+                    "example": "devices_per_organization"
+                }
+            }
+        },
+        "models.RenumberOrganization": {
+            "type": "object",
+            "properties": {
+                "cidr": {
+                    "type": "string",
+                    "example": "172.17.0.0/24"
+                },
+                "cidr_v6": {
+                    "type": "string",
+                    "example": "0300::/8"
+                }
+            }
+        },
+        "models.ReportPeerStatus": {
+            "type": "object",
+            "properties": {
+                "peers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PeerStatus"
+                    }
+                }
+            }
+        },
+        "models.Route": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "device_id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "hostname": {
+                    "type": "string",
+                    "example": "myhost"
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "child_prefix"
+                },
+                "online": {
+                    "type": "boolean"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "172.16.42.0/24"
+                },
+                "priority": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "tunnel_ip": {
+                    "type": "string",
+                    "example": "100.64.0.1"
+                }
+            }
+        },
         "models.SecurityGroup": {
             "type": "object",
             "properties": {
@@ -2073,6 +4111,12 @@ const docTemplate = `{
                         "172.16.42.0/24"
                     ]
                 },
+                "child_prefix_priority": {
+                    "description": "ChildPrefixPriority is left unchanged when it is not set, it can be set to 0.",
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 100
+                },
                 "endpoint_local_address_ip4": {
                     "type": "string",
                     "example": "1.2.3.4"
@@ -2099,6 +4143,93 @@ const docTemplate = `{
                 }
             }
         },
+        "models.UpdateFeatureFlag": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "organizations": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "694aa002-5d19-495e-980b-3d8fd508ea10"
+                    ]
+                },
+                "percentage": {
+                    "description": "Percentage of the users the flag is rolled out to, from 0 to 100.",
+                    "type": "integer",
+                    "example": 10
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "694aa002-5d19-495e-980b-3d8fd508ea10"
+                    ]
+                }
+            }
+        },
+        "models.UpdateOrganization": {
+            "type": "object",
+            "properties": {
+                "cidr": {
+                    "description": "IpCidr and IpCidrV6 can only grow the organization prefixes, they must contain the current ones.",
+                    "type": "string",
+                    "example": "172.16.0.0/16"
+                },
+                "cidr_v6": {
+                    "type": "string",
+                    "example": "0200::/8"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "The Red Zone"
+                },
+                "device_expiry_seconds": {
+                    "description": "DeviceExpirySeconds replaces how long a device can go unseen before it is removed, 0 disables expiry.",
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 86400
+                },
+                "dns_suffix": {
+                    "description": "DnsSuffix replaces the mesh dns suffix, \".\" resets it to the default.",
+                    "type": "string",
+                    "example": "red.example.com"
+                }
+            }
+        },
+        "models.UpdateOrganizationPeering": {
+            "type": "object",
+            "properties": {
+                "approved": {
+                    "description": "Approved approves the peering, only the peer organization can set it.",
+                    "type": "boolean"
+                },
+                "exported_devices": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "694aa002-5d19-495e-980b-3d8fd508ea10"
+                    ]
+                },
+                "exported_prefixes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "100.100.0.0/24"
+                    ]
+                }
+            }
+        },
         "models.UpdateSecurityGroup": {
             "type": "object",
             "properties": {
@@ -1,20 +1,47 @@
 package fflags
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"os"
+	"sort"
 	"strconv"
+	"sync"
 
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/signalbus"
+	"github.com/nexodus-io/nexodus/internal/util"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// This implementation a hack to be replaced later with a proper feature
-// flags backend. We will also need to pass in additional api user info
-// into this API so that the answer can differ depending on who is asking.
-// This is needed to allow admin-only access, or only partial rollouts of
-// features, for example.
+// ErrUnknownFlag is returned for the flag names that are not defined.
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// signalName is notified on the signalbus when a flag is configured, so that the apiserver
+// replicas drop their cached flags.
+const signalName = "/fflags"
+
+// FFlags evaluates the feature flags for the users that ask for them. The flags are configured in
+// the database, the ones that are not use their default, which can be overridden by an env var.
 type FFlags struct {
-	logger *zap.SugaredLogger
+	logger    *zap.SugaredLogger
+	db        *gorm.DB
+	signalBus signalbus.SignalBus
+
+	mu         sync.Mutex
+	cache      map[string]models.FeatureFlag
+	generation uint64
+}
+
+// Identity is the user a flag is evaluated for.
+type Identity struct {
+	UserID          string
+	OrganizationIDs []uuid.UUID
 }
 
 type FFlag struct {
@@ -22,42 +49,202 @@ type FFlag struct {
 	defaultValue bool
 }
 
-var hardCodedFlags = map[string]FFlag{
+var definedFlags = map[string]FFlag{
 	"multi-organization": {"NEXAPI_FFLAG_MULTI_ORGANIZATION", true},
 	"security-groups":    {"NEXAPI_FFLAG_SECURITY_GROUPS", false},
 }
 
-func NewFFlags(logger *zap.SugaredLogger) *FFlags {
+func NewFFlags(logger *zap.SugaredLogger, db *gorm.DB, signalBus signalbus.SignalBus) *FFlags {
 	return &FFlags{
-		logger: logger,
+		logger:    logger,
+		db:        db,
+		signalBus: signalBus,
+	}
+}
+
+// Start starts the background worker that drops the cached flags when they are configured
+// by any of the apiserver replicas.
+func (f *FFlags) Start(ctx context.Context, wg *sync.WaitGroup) {
+	sub := f.signalBus.Subscribe(signalName)
+	util.GoWithWaitGroup(wg, func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.Signal():
+				f.invalidate()
+			}
+		}
+	})
+}
+
+func (f *FFlags) invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cache = nil
+	f.generation++
+}
+
+// flags returns the configured flags by name, they are cached until invalidated.
+func (f *FFlags) flags(ctx context.Context) (map[string]models.FeatureFlag, error) {
+	f.mu.Lock()
+	cache, generation := f.cache, f.generation
+	f.mu.Unlock()
+	if cache != nil {
+		return cache, nil
+	}
+
+	var flags []models.FeatureFlag
+	if res := f.db.WithContext(ctx).Find(&flags); res.Error != nil {
+		return nil, res.Error
+	}
+	cache = map[string]models.FeatureFlag{}
+	for _, flag := range flags {
+		cache[flag.Name] = flag
+	}
+
+	f.mu.Lock()
+	// don't cache flags that were configured while we were loading them.
+	if generation == f.generation {
+		f.cache = cache
 	}
+	f.mu.Unlock()
+	return cache, nil
 }
 
-func (f *FFlags) getFlagValue(fflag FFlag) bool {
+func (fflag FFlag) defaultEnabled() bool {
 	if envValue, err := strconv.ParseBool(os.Getenv(fflag.env)); err == nil {
 		return envValue
 	}
 	return fflag.defaultValue
 }
 
+// evaluate returns whether a flag is enabled for the identity.
+func evaluate(name string, fflag FFlag, flag *models.FeatureFlag, identity Identity) bool {
+	if flag == nil {
+		return fflag.defaultEnabled()
+	}
+	if flag.Enabled {
+		return true
+	}
+	if identity.UserID == "" {
+		return false
+	}
+	for _, user := range flag.Users {
+		if user == identity.UserID {
+			return true
+		}
+	}
+	for _, org := range flag.Organizations {
+		for _, id := range identity.OrganizationIDs {
+			if org == id.String() {
+				return true
+			}
+		}
+	}
+	return flag.Percentage > 0 && bucket(name, identity.UserID) < flag.Percentage
+}
+
+// bucket assigns a user to one of 100 buckets, differently for each flag, so that a user stays in
+// the rollout of a flag as its percentage grows.
+func bucket(name string, userID string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name + "/" + userID))
+	return int(hash.Sum32() % 100)
+}
+
 // ListFlags returns a map of all currently defined feature flags and
-// whether those features are enabled (true) or not (false).
-func (f *FFlags) ListFlags() map[string]bool {
+// whether those features are enabled (true) or not (false) for the identity.
+func (f *FFlags) ListFlags(ctx context.Context, identity Identity) (map[string]bool, error) {
+	flags, err := f.flags(ctx)
+	if err != nil {
+		return nil, err
+	}
 	result := map[string]bool{}
-	for name, fflag := range hardCodedFlags {
-		result[name] = f.getFlagValue(fflag)
+	for name, fflag := range definedFlags {
+		var flag *models.FeatureFlag
+		if value, ok := flags[name]; ok {
+			flag = &value
+		}
+		result[name] = evaluate(name, fflag, flag, identity)
 	}
-	return result
+	return result, nil
 }
 
 // GetFlag returns whether the feature named by the string parameter
-// flag is enabled (true) or not (false). An error is returned if
-// the flag name is invalid.
-func (f *FFlags) GetFlag(flag string) (bool, error) {
-	fflag, ok := hardCodedFlags[flag]
+// flag is enabled (true) or not (false) for the identity. ErrUnknownFlag
+// is returned if the flag name is invalid.
+func (f *FFlags) GetFlag(ctx context.Context, flag string, identity Identity) (bool, error) {
+	fflag, ok := definedFlags[flag]
 	if !ok {
 		f.logger.Errorf("Invalid feature flag name: %s", flag)
-		return false, fmt.Errorf("Invalid feature flag name: %s", flag)
+		return false, fmt.Errorf("%w: %s", ErrUnknownFlag, flag)
+	}
+	flags, err := f.flags(ctx)
+	if err != nil {
+		return false, err
+	}
+	var configured *models.FeatureFlag
+	if value, ok := flags[flag]; ok {
+		configured = &value
+	}
+	return evaluate(flag, fflag, configured, identity), nil
+}
+
+// ListFlagConfigs returns the configuration of all the defined flags, the flags that are not
+// configured are only enabled by their default.
+func (f *FFlags) ListFlagConfigs(ctx context.Context) ([]models.FeatureFlag, error) {
+	flags, err := f.flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.FeatureFlag, 0, len(definedFlags))
+	for name, fflag := range definedFlags {
+		flag, ok := flags[name]
+		if !ok {
+			flag = models.FeatureFlag{
+				Name:          name,
+				Enabled:       fflag.defaultEnabled(),
+				Organizations: []string{},
+				Users:         []string{},
+			}
+		}
+		result = append(result, flag)
 	}
-	return f.getFlagValue(fflag), nil
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// SetFlag stores the configuration of a flag.
+func (f *FFlags) SetFlag(ctx context.Context, flag models.FeatureFlag) error {
+	if _, ok := definedFlags[flag.Name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFlag, flag.Name)
+	}
+	if res := f.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&flag); res.Error != nil {
+		return res.Error
+	}
+	f.changed()
+	return nil
+}
+
+// ResetFlag removes the configuration of a flag, so that it uses its default.
+func (f *FFlags) ResetFlag(ctx context.Context, name string) error {
+	if _, ok := definedFlags[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	if res := f.db.WithContext(ctx).Delete(&models.FeatureFlag{}, "name = ?", name); res.Error != nil {
+		return res.Error
+	}
+	f.changed()
+	return nil
+}
+
+// changed drops the cached flags of this replica right away, and of the other ones through
+// the signalbus.
+func (f *FFlags) changed() {
+	f.invalidate()
+	f.signalBus.Notify(signalName)
 }
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuditAdminRequests returns a middleware that records every request made to the admin API
+// in the audit trail, including the ones that failed.
+func (api *API) AuditAdminRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		event := models.AuditEvent{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UserID:    c.GetString(gin.AuthUserKey),
+			UserName:  c.GetString(AuthUserName),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.RequestURI(),
+			Route:     c.FullPath(),
+			Status:    c.Writer.Status(),
+		}
+		api.Logger(c.Request.Context()).Infow("admin api request",
+			"user_id", event.UserID,
+			"user_name", event.UserName,
+			"method", event.Method,
+			"path", event.Path,
+			"status", event.Status,
+		)
+		// the request context is canceled when the client goes away, the event is recorded anyway.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if res := api.db.WithContext(ctx).Create(&event); res.Error != nil {
+			api.Logger(c.Request.Context()).Errorf("failed to record the audit event of [ %s %s ]: %v", event.Method, event.Path, res.Error)
+		}
+	}
+}
+
+// AdminListOrganizations lists the organizations of all the users
+// @Summary      List All Organizations
+// @Description  Lists the organizations of all the users, requires the admin role
+// @Id           AdminListOrganizations
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        filter  query  string false "JSON object of the column values to match, like {\"name\":\"example\"}"
+// @Success      200  {object}  []models.Organization
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/organizations [get]
+func (api *API) AdminListOrganizations(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminListOrganizations")
+	defer span.End()
+	orgs := make([]models.Organization, 0)
+	if res := api.db.WithContext(ctx).
+		Scopes(FilterAndPaginate(&models.Organization{}, c, "name")).
+		Find(&orgs); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// AdminListDevices searches the devices of all the organizations
+// @Summary      List All Devices
+// @Description  Lists the devices of all the organizations, the filter finds the organization of a public key with {"public_key":"..."}, requires the admin role
+// @Id           AdminListDevices
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        filter  query  string false "JSON object of the column values to match, like {\"public_key\":\"...\"}"
+// @Success      200  {object}  []models.Device
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/devices [get]
+func (api *API) AdminListDevices(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminListDevices")
+	defer span.End()
+	devices := make([]models.Device, 0)
+	if res := api.db.WithContext(ctx).
+		Scopes(FilterAndPaginate(&models.Device{}, c, "hostname")).
+		Find(&devices); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+// AdminDeleteDevice force deletes a device of any organization
+// @Summary      Force Delete Device
+// @Description  Deletes a device of any organization, the device is deleted even when its ipam leases can't be released, requires the admin role
+// @Id           AdminDeleteDevice
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "Device ID"
+// @Success      200  {object}  models.Device
+// @Failure      400  {object}  models.BaseError
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/devices/{id} [delete]
+func (api *API) AdminDeleteDevice(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminDeleteDevice",
+		trace.WithAttributes(
+			attribute.String("id", c.Param("id")),
+		))
+	defer span.End()
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var device models.Device
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.First(&device, "id = ?", deviceID); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Delete(&device, "id = ?", device.ID); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Delete(&models.DevicePeerStatus{}, "device_id = ?", device.ID); res.Error != nil {
+			return res.Error
+		}
+		// another device may take over the child prefixes of the deleted device.
+		_, err := api.electChildPrefixRouters(ctx, tx, device.OrganizationID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+	api.notifyDevicesChanged(ctx, device.OrganizationID)
+
+	// the device may be stuck because its organization or leases are gone, the ipam gc
+	// releases the leases that could not be released here.
+	ipamNamespace := defaultIPAMNamespace
+	var org models.Organization
+	if res := api.db.WithContext(ctx).Unscoped().Limit(1).Find(&org, "id = ?", device.OrganizationID); res.Error == nil && org.PrivateCidr {
+		ipamNamespace = org.ID
+	}
+	if err := api.releaseDeviceAddresses(ctx, ipamNamespace, device); err != nil {
+		api.Logger(ctx).Warnf("failed to release the ipam leases of the force deleted device [ %s ]: %v", device.ID, err)
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// AdminListUsers lists all the users
+// @Summary      List All Users
+// @Description  Lists all the users, requires the admin role
+// @Id           AdminListUsers
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        filter  query  string false "JSON object of the column values to match, like {\"user_name\":\"example\"}"
+// @Success      200  {object}  []models.User
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/users [get]
+func (api *API) AdminListUsers(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminListUsers")
+	defer span.End()
+	users := make([]models.User, 0)
+	if res := api.db.WithContext(ctx).
+		Scopes(FilterAndPaginate(&models.User{}, c, "user_name")).
+		Find(&users); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// AdminGetUser gets a user with its organizations and devices
+// @Summary      Inspect User
+// @Description  Gets a user with the organizations it is a member of and its devices, requires the admin role
+// @Id           AdminGetUser
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "User ID"
+// @Success      200  {object}  models.AdminUser
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/users/{id} [get]
+func (api *API) AdminGetUser(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminGetUser",
+		trace.WithAttributes(
+			attribute.String("id", c.Param("id")),
+		))
+	defer span.End()
+
+	db := api.db.WithContext(ctx)
+	var user models.User
+	if res := db.First(&user, "id = ?", c.Param("id")); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		}
+		return
+	}
+
+	result := models.AdminUser{
+		ID:            user.ID,
+		UserName:      user.UserName,
+		CreatedAt:     user.CreatedAt,
+		Organizations: []uuid.UUID{},
+		Devices:       []uuid.UUID{},
+	}
+	if res := db.Table("user_organizations").
+		Where("user_id = ?", user.ID).
+		Pluck("organization_id", &result.Organizations); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	if res := db.Model(&models.Device{}).
+		Where("user_id = ?", user.ID).
+		Pluck("id", &result.Devices); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// AdminListAuditEvents lists the audit trail of the admin API
+// @Summary      List Audit Events
+// @Description  Lists the uses of the admin API, most recent first, requires the admin role
+// @Id           AdminListAuditEvents
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        filter  query  string false "JSON object of the column values to match, like {\"user_id\":\"...\"}"
+// @Success      200  {object}  []models.AuditEvent
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/audit_events [get]
+func (api *API) AdminListAuditEvents(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminListAuditEvents")
+	defer span.End()
+	events := make([]models.AuditEvent, 0)
+	if res := api.db.WithContext(ctx).
+		Scopes(FilterAndPaginate(&models.AuditEvent{}, c, "created_at DESC")).
+		Find(&events); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+)
+
+func (suite *HandlerTestSuite) TestAdmin() {
+	require := suite.Require()
+	require.NoError(suite.api.db.Exec("DELETE FROM audit_events").Error)
+
+	// a device of another user.
+	device := models.Device{
+		UserID:         TestUser2ID,
+		OrganizationID: suite.testUser2OrgID,
+		PublicKey:      "adminpubkey",
+		Hostname:       "admin-test",
+	}
+	require.NoError(suite.api.db.Create(&device).Error)
+
+	// find the organization of a public key.
+	filter := url.QueryEscape(`{"public_key":"adminpubkey"}`)
+	_, res, err := suite.ServeRequest(http.MethodGet, "/", "/?filter="+filter, suite.api.AdminListDevices, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var devices []models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &devices))
+	require.Len(devices, 1)
+	require.Equal(device.ID, devices[0].ID)
+	require.Equal(suite.testUser2OrgID, devices[0].OrganizationID)
+
+	// the organizations of all the users are listed.
+	_, res, err = suite.ServeRequest(http.MethodGet, "/", "/", suite.api.AdminListOrganizations, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var orgs []models.OrganizationJSON
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &orgs))
+	var orgIDs []string
+	for _, org := range orgs {
+		orgIDs = append(orgIDs, org.ID.String())
+	}
+	require.Contains(orgIDs, suite.testOrganizationID.String())
+	require.Contains(orgIDs, suite.testUser2OrgID.String())
+
+	// inspect the other user.
+	_, res, err = suite.ServeRequest(http.MethodGet, "/users/:id", "/users/"+TestUser2ID, suite.api.AdminGetUser, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var user models.AdminUser
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &user))
+	require.Equal(TestUser2ID, user.ID)
+	require.Equal("testuser2", user.UserName)
+	require.Contains(user.Organizations, suite.testUser2OrgID)
+	require.Contains(user.Devices, device.ID)
+
+	// force delete the device through the audited route.
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, TestUserID)
+		c.Set(AuthUserName, "testuser")
+		c.Next()
+	})
+	r.DELETE("/admin/devices/:id", suite.api.AuditAdminRequests(), suite.api.AdminDeleteDevice)
+	deleteDevice := func() *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/devices/%s", device.ID), nil)
+		require.NoError(err)
+		r.ServeHTTP(res, req)
+		return res
+	}
+	res = deleteDevice()
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.ErrorIs(suite.api.db.First(&models.Device{}, "id = ?", device.ID).Error, gorm.ErrRecordNotFound)
+	res = deleteDevice()
+	require.Equal(http.StatusNotFound, res.Code, "HTTP error: %s", res.Body.String())
+
+	// the requests that the policy forbids are audited too.
+	r.DELETE("/admin/users/:id", suite.api.AuditAdminRequests(), func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusForbidden)
+	}, suite.api.DeleteUser)
+	res = httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%s", TestUser2ID), nil)
+	require.NoError(err)
+	r.ServeHTTP(res, req)
+	require.Equal(http.StatusForbidden, res.Code)
+	require.NoError(suite.api.db.First(&models.User{}, "id = ?", TestUser2ID).Error)
+
+	// all the uses are in the audit trail, most recent first.
+	_, res, err = suite.ServeRequest(http.MethodGet, "/", "/", suite.api.AdminListAuditEvents, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var events []models.AuditEvent
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &events))
+	require.Len(events, 3)
+	require.Equal(TestUserID, events[0].UserID)
+	require.Equal(fmt.Sprintf("/admin/users/%s", TestUser2ID), events[0].Path)
+	require.Equal(http.StatusForbidden, events[0].Status)
+	for i, status := range []int{http.StatusNotFound, http.StatusOK} {
+		i := i + 1
+		require.Equal(TestUserID, events[i].UserID)
+		require.Equal("testuser", events[i].UserName)
+		require.Equal(http.MethodDelete, events[i].Method)
+		require.Equal(fmt.Sprintf("/admin/devices/%s", device.ID), events[i].Path)
+		require.Equal("/admin/devices/:id", events[i].Route)
+		require.Equal(status, events[i].Status)
+	}
+}
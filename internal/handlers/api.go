@@ -34,6 +34,7 @@ type API struct {
 	dialect       database.Dialect
 	store         storage.Store
 	signalBus     signalbus.SignalBus
+	quotas        Quotas
 }
 
 func NewAPI(parent context.Context, logger *zap.SugaredLogger, db *gorm.DB, ipam ipam.IPAM, fflags *fflags.FFlags, store storage.Store, signalBus signalbus.SignalBus) (*API, error) {
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// peerStatusMaxAge is how old a peer status report can be and still count towards the health of a device.
+const peerStatusMaxAge = 3 * time.Minute
+
+// electChildPrefixRouters elects the device that routes each child prefix of an organization and
+// stores the prefixes every device was elected for in its ActiveChildPrefix. It returns true if the
+// ActiveChildPrefix of any device changed.
+func (api *API) electChildPrefixRouters(ctx context.Context, tx *gorm.DB, orgID uuid.UUID) (bool, error) {
+	ctx, span := tracer.Start(ctx, "electChildPrefixRouters")
+	defer span.End()
+
+	var devices []models.Device
+	if res := tx.WithContext(ctx).Where("organization_id = ?", orgID).Find(&devices); res.Error != nil {
+		return false, res.Error
+	}
+	var statuses []models.DevicePeerStatus
+	if res := tx.WithContext(ctx).
+		Where("organization_id = ? AND updated_at > ?", orgID, time.Now().Add(-peerStatusMaxAge)).
+		Find(&statuses); res.Error != nil {
+		return false, res.Error
+	}
+
+	active := electChildPrefixRouters(devices, unhealthyDevices(devices, statuses))
+	changed := false
+	for i := range devices {
+		device := &devices[i]
+		if childPrefixEquals(device.ActiveChildPrefix, active[device.ID]) {
+			continue
+		}
+		api.Logger(ctx).Infof("Device [ %s ] now routes the child prefixes %v, was %v", device.ID, active[device.ID], device.ActiveChildPrefix)
+		device.ActiveChildPrefix = active[device.ID]
+		if res := tx.WithContext(ctx).Model(device).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Select("active_child_prefix").
+			Updates(device); res.Error != nil {
+			return false, res.Error
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// unhealthyDevices returns the devices that most of the peer status reports about them say are not
+// healthy.
+func unhealthyDevices(devices []models.Device, statuses []models.DevicePeerStatus) map[uuid.UUID]bool {
+	deviceByKey := map[string]uuid.UUID{}
+	for _, d := range devices {
+		deviceByKey[d.PublicKey] = d.ID
+	}
+	healthy := map[uuid.UUID]int{}
+	unhealthy := map[uuid.UUID]int{}
+	for _, s := range statuses {
+		for _, peer := range s.Peers {
+			id, ok := deviceByKey[peer.PublicKey]
+			if !ok || id == s.DeviceID {
+				continue
+			}
+			if peer.Healthy {
+				healthy[id]++
+			} else {
+				unhealthy[id]++
+			}
+		}
+	}
+	result := map[uuid.UUID]bool{}
+	for id, count := range unhealthy {
+		if count > healthy[id] {
+			result[id] = true
+		}
+	}
+	return result
+}
+
+// electChildPrefixRouters picks the device that routes each child prefix. Devices that are online and
+// healthy are preferred, then the highest ChildPrefixPriority, then the device already routing the
+// prefix so that an election between equals does not flap, and finally the oldest device. If none of
+// the devices advertising a prefix are online and healthy, the prefix is still routed to one of them.
+// Default routes are advertised by exit nodes, which the devices using them pick themselves, so every
+// exit node stays active for them.
+func electChildPrefixRouters(devices []models.Device, unhealthy map[uuid.UUID]bool) map[uuid.UUID]pq.StringArray {
+	candidates := map[string][]*models.Device{}
+	for i := range devices {
+		device := &devices[i]
+		for _, prefix := range device.ChildPrefix {
+			if util.IsDefaultIPRoute(prefix) {
+				continue
+			}
+			key := normalizePrefix(prefix)
+			candidates[key] = append(candidates[key], device)
+		}
+	}
+
+	winners := map[string]uuid.UUID{}
+	for key, advertisers := range candidates {
+		var eligible []*models.Device
+		for _, device := range advertisers {
+			if device.Online && !unhealthy[device.ID] {
+				eligible = append(eligible, device)
+			}
+		}
+		if len(eligible) == 0 {
+			eligible = advertisers
+		}
+		best := eligible[0]
+		for _, device := range eligible[1:] {
+			if betterChildPrefixRouter(device, best, key) {
+				best = device
+			}
+		}
+		winners[key] = best.ID
+	}
+
+	active := map[uuid.UUID]pq.StringArray{}
+	for _, device := range devices {
+		prefixes := pq.StringArray{}
+		for _, prefix := range device.ChildPrefix {
+			if util.IsDefaultIPRoute(prefix) || winners[normalizePrefix(prefix)] == device.ID {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+		active[device.ID] = prefixes
+	}
+	return active
+}
+
+func betterChildPrefixRouter(a, b *models.Device, prefix string) bool {
+	if a.ChildPrefixPriority != b.ChildPrefixPriority {
+		return a.ChildPrefixPriority > b.ChildPrefixPriority
+	}
+	aActive, bActive := routesChildPrefix(a, prefix), routesChildPrefix(b, prefix)
+	if aActive != bActive {
+		return aActive
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+func routesChildPrefix(device *models.Device, prefix string) bool {
+	for _, p := range device.ActiveChildPrefix {
+		if normalizePrefix(p) == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// childPrefixInUse returns true if a device of the organization other than deviceID advertises the prefix.
+func childPrefixInUse(tx *gorm.DB, orgID uuid.UUID, deviceID uuid.UUID, prefix string) (bool, error) {
+	var devices []models.Device
+	if res := tx.Where("organization_id = ? AND id <> ?", orgID, deviceID).Find(&devices); res.Error != nil {
+		return false, res.Error
+	}
+	prefix = normalizePrefix(prefix)
+	for _, device := range devices {
+		for _, p := range device.ChildPrefix {
+			if normalizePrefix(p) == prefix {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
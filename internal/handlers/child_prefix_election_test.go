@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestChildPrefixRouterFailover() {
+	require := suite.Require()
+	prefix := "172.16.30.0/24"
+
+	createDevice := func(key string, priority int32) models.Device {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/", "/",
+			suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{
+				OrganizationID:      suite.testOrganizationID,
+				PublicKey:           key,
+				ChildPrefix:         []string{prefix},
+				ChildPrefixPriority: priority,
+			})),
+		)
+		require.NoError(err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(err)
+		require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+		var device models.Device
+		require.NoError(json.Unmarshal(body, &device))
+		return device
+	}
+	heartbeat := func(id uuid.UUID) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/:id/heartbeat", fmt.Sprintf("/%s/heartbeat", id),
+			suite.api.HeartbeatDevice, nil,
+		)
+		require.NoError(err)
+		require.Equal(http.StatusOK, res.Code)
+	}
+	activeRouter := func() uuid.UUID {
+		var devices []models.Device
+		require.NoError(suite.api.db.Where("organization_id = ?", suite.testOrganizationID).Find(&devices).Error)
+		var active []uuid.UUID
+		for i := range devices {
+			if routesChildPrefix(&devices[i], prefix) {
+				active = append(active, devices[i].ID)
+			}
+		}
+		require.Len(active, 1, "exactly one device should route %s", prefix)
+		return active[0]
+	}
+
+	backup := createDevice("router-pubkey-backup", 10)
+	primary := createDevice("router-pubkey-primary", 20)
+
+	// none of the routers are online yet, the prefix is still routed to the highest priority one.
+	require.Equal(primary.ID, activeRouter())
+
+	heartbeat(backup.ID)
+	heartbeat(primary.ID)
+	require.Equal(primary.ID, activeRouter())
+
+	// the primary stops sending heartbeats.
+	require.NoError(suite.api.db.Model(&primary).Update("last_seen", time.Now().Add(-2*time.Minute)).Error)
+	require.NoError(suite.api.ReapDevices(context.Background(), time.Minute))
+	require.Equal(backup.ID, activeRouter())
+
+	// it comes back and takes over again.
+	heartbeat(primary.ID)
+	require.Equal(primary.ID, activeRouter())
+
+	// its peers report that they can't reach it.
+	_, res, err := suite.ServeRequest(
+		http.MethodPut, "/:id/peer_status", fmt.Sprintf("/%s/peer_status", backup.ID),
+		suite.api.ReportDevicePeerStatus, bytes.NewBuffer(suite.jsonMarshal(models.ReportPeerStatus{
+			Peers: []models.PeerStatus{{PublicKey: primary.PublicKey, Healthy: false}},
+		})),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+	require.Equal(backup.ID, activeRouter())
+
+	_, res, err = suite.ServeRequest(
+		http.MethodPut, "/:id/peer_status", fmt.Sprintf("/%s/peer_status", backup.ID),
+		suite.api.ReportDevicePeerStatus, bytes.NewBuffer(suite.jsonMarshal(models.ReportPeerStatus{
+			Peers: []models.PeerStatus{{PublicKey: primary.PublicKey, Healthy: true}},
+		})),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+	require.Equal(primary.ID, activeRouter())
+
+	// the priority of the primary can be reset to 0.
+	priority := int32(0)
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch, "/:id", fmt.Sprintf("/%s", primary.ID),
+		suite.api.UpdateDevice, bytes.NewBuffer(suite.jsonMarshal(models.UpdateDevice{ChildPrefixPriority: &priority})),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.Equal(backup.ID, activeRouter())
+
+	// deleting the primary leaves the prefix with the backup.
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete, "/:id", fmt.Sprintf("/%s", primary.ID),
+		suite.api.DeleteDevice, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+	require.Equal(backup.ID, activeRouter())
+}
+
+func (suite *HandlerTestSuite) TestElectChildPrefixRouters() {
+	require := suite.Require()
+	now := time.Now()
+	a := models.Device{Base: models.Base{ID: uuid.New(), CreatedAt: now}, ChildPrefix: []string{"10.1.0.0/24", "10.2.0.0/24"}, Online: true}
+	b := models.Device{Base: models.Base{ID: uuid.New(), CreatedAt: now.Add(time.Second)}, ChildPrefix: []string{"10.1.0.0/24"}, Online: true, ActiveChildPrefix: []string{"10.1.0.0/24"}}
+	c := models.Device{Base: models.Base{ID: uuid.New(), CreatedAt: now.Add(2 * time.Second)}, ChildPrefix: []string{"10.1.0.0/24"}, ChildPrefixPriority: 5}
+
+	// the device already routing a prefix keeps it over an equal one.
+	active := electChildPrefixRouters([]models.Device{a, b}, nil)
+	require.Equal([]string{"10.2.0.0/24"}, []string(active[a.ID]))
+	require.Equal([]string{"10.1.0.0/24"}, []string(active[b.ID]))
+
+	// offline devices are skipped even with a higher priority.
+	active = electChildPrefixRouters([]models.Device{a, b, c}, nil)
+	require.Empty(active[c.ID])
+
+	// unhealthy devices are skipped.
+	active = electChildPrefixRouters([]models.Device{a, b, c}, map[uuid.UUID]bool{b.ID: true})
+	require.Equal([]string{"10.1.0.0/24", "10.2.0.0/24"}, []string(active[a.ID]))
+	require.Empty(active[b.ID])
+
+	// exit nodes all keep their default routes.
+	x := models.Device{Base: models.Base{ID: uuid.New(), CreatedAt: now}, ChildPrefix: []string{"0.0.0.0/0", "::/0"}, Online: true, ChildPrefixPriority: 5}
+	y := models.Device{Base: models.Base{ID: uuid.New(), CreatedAt: now.Add(time.Second)}, ChildPrefix: []string{"0.0.0.0/0", "::/0"}, Online: true}
+	active = electChildPrefixRouters([]models.Device{x, y}, nil)
+	require.Equal([]string{"0.0.0.0/0", "::/0"}, []string(active[x.ID]))
+	require.Equal([]string{"0.0.0.0/0", "::/0"}, []string(active[y.ID]))
+}
+
+func (suite *HandlerTestSuite) TestReachabilityChanged() {
+	require := suite.Require()
+	now := time.Now()
+	report := func(updatedAt time.Time, peers ...models.PeerStatus) models.DevicePeerStatus {
+		return models.DevicePeerStatus{Peers: peers, UpdatedAt: updatedAt}
+	}
+	previous := report(now.Add(-time.Minute), models.PeerStatus{PublicKey: "a", Healthy: true}, models.PeerStatus{PublicKey: "b", Healthy: false})
+
+	require.True(reachabilityChanged(nil, report(now)))
+	require.False(reachabilityChanged(&previous, report(now,
+		models.PeerStatus{PublicKey: "b", Healthy: false, LatestHandshake: "1s"},
+		models.PeerStatus{PublicKey: "a", Healthy: true},
+	)))
+	require.True(reachabilityChanged(&previous, report(now,
+		models.PeerStatus{PublicKey: "a", Healthy: true},
+		models.PeerStatus{PublicKey: "b", Healthy: true},
+	)))
+	require.True(reachabilityChanged(&previous, report(now, models.PeerStatus{PublicKey: "a", Healthy: true})))
+
+	// a report that stopped counting towards the election is replaced.
+	stale := previous
+	stale.UpdatedAt = now.Add(-2 * peerStatusMaxAge)
+	require.True(reachabilityChanged(&stale, report(now, previous.Peers...)))
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
 	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/nexodus-io/nexodus/internal/util"
 	"go.opentelemetry.io/otel/attribute"
@@ -60,6 +64,21 @@ func (api *API) ListDevices(c *gin.Context) {
 	c.JSON(http.StatusOK, devices)
 }
 
+// DeviceOrganization returns the organization of a device of the current user, or an empty
+// string if there is none. The device routes without an organization path parameter are rate
+// limited by it.
+func (api *API) DeviceOrganization(c *gin.Context, id string) string {
+	var orgIDs []string
+	if res := api.db.WithContext(c.Request.Context()).
+		Model(&models.Device{}).
+		Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+		Where("id = ?", id).
+		Pluck("organization_id", &orgIDs); res.Error != nil || len(orgIDs) == 0 {
+		return ""
+	}
+	return orgIDs[0]
+}
+
 func (api *API) DeviceIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		userId := c.Value(gin.AuthUserKey).(string)
@@ -123,7 +142,9 @@ func (api *API) GetDevice(c *gin.Context) {
 // @Failure		 401  {object}  models.BaseError
 // @Failure      400  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.PrefixConflictError
 // @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
 // @Router       /api/devices/{id} [patch]
 func (api *API) UpdateDevice(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "UpdateDevice", trace.WithAttributes(
@@ -160,6 +181,7 @@ func (api *API) UpdateDevice(c *gin.Context) {
 		if org.PrivateCidr {
 			originalIpamNamespace = org.ID
 		}
+		ipamCtx := database.WithTx(ctx, tx)
 
 		if request.EndpointLocalAddressIPv4 != "" {
 			device.EndpointLocalAddressIPv4 = request.EndpointLocalAddressIPv4
@@ -191,23 +213,23 @@ func (api *API) UpdateDevice(c *gin.Context) {
 
 			// We can reuse the ip address if the ipam namespace is not changing.
 			if originalIpamNamespace != newIpamNamespace {
-				if err := api.ipam.ReleaseToPool(c.Request.Context(), originalIpamNamespace, device.TunnelIP, device.OrganizationPrefix); err != nil {
+				if err := api.ipam.ReleaseToPool(ipamCtx, originalIpamNamespace, device.TunnelIP, device.OrganizationPrefix); err != nil {
 					c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v4 address to pool: %w", err)))
 					return err
 				}
 
-				if err := api.ipam.ReleaseToPool(c.Request.Context(), originalIpamNamespace, device.TunnelIpV6, device.OrganizationPrefixV6); err != nil {
+				if err := api.ipam.ReleaseToPool(ipamCtx, originalIpamNamespace, device.TunnelIpV6, device.OrganizationPrefixV6); err != nil {
 					c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v6 address to pool: %w", err)))
 					return err
 				}
 
-				device.TunnelIP, err = api.ipam.AssignFromPool(ctx, newIpamNamespace, org.IpCidr)
+				device.TunnelIP, err = api.ipam.AssignFromPool(ipamCtx, newIpamNamespace, org.IpCidr)
 				if err != nil {
 					return fmt.Errorf("failed to request ipam address: %w", err)
 				}
 				device.OrganizationPrefix = org.IpCidr
 
-				device.TunnelIpV6, err = api.ipam.AssignFromPool(ctx, newIpamNamespace, org.IpCidrV6)
+				device.TunnelIpV6, err = api.ipam.AssignFromPool(ipamCtx, newIpamNamespace, org.IpCidrV6)
 				if err != nil {
 					return fmt.Errorf("failed to request ipam v6 address: %w", err)
 				}
@@ -224,31 +246,46 @@ func (api *API) UpdateDevice(c *gin.Context) {
 
 		device.SymmetricNat = request.SymmetricNat
 
+		if request.ChildPrefixPriority != nil {
+			device.ChildPrefixPriority = *request.ChildPrefixPriority
+		}
+
 		// check if the updated device child prefix matches the existing device prefix
 		if request.ChildPrefix != nil && !childPrefixEquals(device.ChildPrefix, request.ChildPrefix) {
+			if err := api.validateChildPrefixes(tx, org, device.ID, request.ChildPrefix); err != nil {
+				return err
+			}
 			prefixAllocated := make(map[string]struct{})
 			for _, prefix := range device.ChildPrefix {
-				if !util.IsValidPrefix(prefix) {
-					return fmt.Errorf("invalid cidr detected in the child prefix field of %s", prefix)
-				}
 				prefixAllocated[prefix] = struct{}{}
 			}
+			prefixRequested := make(map[string]struct{})
 			for _, prefix := range request.ChildPrefix {
-				isDefaultRoute := util.IsDefaultIPRoute(prefix)
-				// If the prefix is not a default route, process IPAM allocation/release
-				if isDefaultRoute {
+				prefixRequested[prefix] = struct{}{}
+			}
+			// release the prefixes that are no longer requested
+			for _, prefix := range device.ChildPrefix {
+				if _, ok := prefixRequested[prefix]; ok || util.IsDefaultIPRoute(prefix) {
 					continue
 				}
-				// lookup miss of prefix means we need to release it
-				if _, ok := prefixAllocated[prefix]; ok {
-					if err := api.ipam.ReleasePrefix(ctx, originalIpamNamespace, prefix); err != nil {
-						return err
-					}
-				} else {
-					// otherwise we need to allocate it
-					if err := api.ipam.AssignPrefix(ctx, originalIpamNamespace, prefix); err != nil {
-						return err
-					}
+				inUse, err := childPrefixInUse(tx, device.OrganizationID, device.ID, prefix)
+				if err != nil {
+					return err
+				}
+				if inUse {
+					continue
+				}
+				if err := api.ipam.ReleasePrefix(ipamCtx, originalIpamNamespace, prefix); err != nil {
+					return err
+				}
+			}
+			// and allocate the new ones
+			for _, prefix := range request.ChildPrefix {
+				if _, ok := prefixAllocated[prefix]; ok || util.IsDefaultIPRoute(prefix) {
+					continue
+				}
+				if err := api.ipam.AssignPrefix(ipamCtx, originalIpamNamespace, prefix); err != nil {
+					return ipamPrefixError(prefix, err)
 				}
 			}
 			device.ChildPrefix = request.ChildPrefix
@@ -261,19 +298,34 @@ func (api *API) UpdateDevice(c *gin.Context) {
 			return res.Error
 		}
 
-		return nil
+		if _, err := api.electChildPrefixRouters(ctx, tx, device.OrganizationID); err != nil {
+			return err
+		}
+		// a device that moved to another organization no longer routes the prefixes of the previous one.
+		if org.ID != device.OrganizationID {
+			if _, err := api.electChildPrefixRouters(ctx, tx, org.ID); err != nil {
+				return err
+			}
+		}
+		return tx.First(&device, "id = ?", device.ID).Error
 	})
 
 	if err != nil {
+		var invalid errInvalidCidr
+		var conflict errPrefixConflict
 		if errors.Is(err, errDeviceNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.field, invalid.reason))
+		} else if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, models.NewPrefixConflictError(conflict.prefix, conflict.conflictsWith, conflict.deviceID))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
 		return
 	}
 
-	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
+	api.notifyDevicesChanged(ctx, device.OrganizationID)
 	c.JSON(http.StatusOK, device)
 }
 
@@ -317,6 +369,7 @@ func getAllowedIPs(ip string, ip6 string, relay bool) ([]string, error) {
 // @Success      201  {object}  models.Device
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.QuotaExceededError
 // @Failure      409  {object}  models.ConflictsError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
@@ -357,16 +410,31 @@ func (api *API) CreateDevice(c *gin.Context) {
 			return res.Error
 		}
 
+		if err := checkQuota(tx, &models.Organization{}, org.ID, "devices_per_organization", api.quotas.DevicesPerOrganization,
+			&models.Device{}, "organization_id = ?", org.ID); err != nil {
+			return err
+		}
+
 		ipamNamespace := defaultIPAMNamespace
 		if org.PrivateCidr {
 			ipamNamespace = org.ID
 		}
+		ipamCtx := database.WithTx(ctx, tx)
 
 		var relay bool
 		// determine if the node joining is a relay node
 		if request.Relay {
 			relay = true
 		}
+		// hubs relay the traffic of all the devices of a hub organization
+		if request.Hub {
+			if !org.HubZone {
+				return errInvalidCidr{field: "hub", reason: "hub devices can only join a hub organization"}
+			}
+			if !relay {
+				return errInvalidCidr{field: "hub", reason: "a hub device must be a relay"}
+			}
+		}
 
 		var ipamIP string
 		var ipamIPv6 string
@@ -374,31 +442,35 @@ func (api *API) CreateDevice(c *gin.Context) {
 		// If this was a static address request
 		// TODO: handle a user requesting an IP not in the IPAM prefix
 		if request.TunnelIP != "" {
-			ipamIP, err = api.ipam.AssignSpecificTunnelIP(ctx, ipamNamespace, org.IpCidr, request.TunnelIP)
+			ipamIP, err = api.ipam.AssignSpecificTunnelIP(ipamCtx, ipamNamespace, org.IpCidr, request.TunnelIP)
+			if err != nil {
+				api.Logger(ctx).Warnf("failed to assign the requested address %s, assigning an address from the pool: %v", request.TunnelIP, err)
+				ipamIP, err = api.ipam.AssignFromPool(ipamCtx, ipamNamespace, org.IpCidr)
+			}
 			if err != nil {
 				return fmt.Errorf("failed to request specific ipam address: %w", err)
 			}
 		} else {
-			ipamIP, err = api.ipam.AssignFromPool(ctx, ipamNamespace, org.IpCidr)
+			ipamIP, err = api.ipam.AssignFromPool(ipamCtx, ipamNamespace, org.IpCidr)
 			if err != nil {
 				return fmt.Errorf("failed to request ipam address: %w", err)
 			}
 		}
 		// Currently only support v4 requesting of specific addresses
-		ipamIPv6, err = api.ipam.AssignFromPool(ctx, ipamNamespace, org.IpCidrV6)
+		ipamIPv6, err = api.ipam.AssignFromPool(ipamCtx, ipamNamespace, org.IpCidrV6)
 		if err != nil {
 			return fmt.Errorf("failed to request ipam v6 address: %w", err)
 		}
 
 		// allocate a child prefix if requested
+		if err := api.validateChildPrefixes(tx, org, uuid.Nil, request.ChildPrefix); err != nil {
+			return err
+		}
 		for _, prefix := range request.ChildPrefix {
-			if !util.IsValidPrefix(prefix) {
-				return fmt.Errorf("invalid cidr detected in the child prefix field of %s", prefix)
-			}
 			// Skip the prefix assignment if it's an IPv4 or IPv6 default route
 			if !util.IsDefaultIPv4Route(prefix) && !util.IsDefaultIPv6Route(prefix) {
-				if err := api.ipam.AssignPrefix(ctx, ipamNamespace, prefix); err != nil {
-					return fmt.Errorf("failed to assign child prefix: %w", err)
+				if err := api.ipam.AssignPrefix(ipamCtx, ipamNamespace, prefix); err != nil {
+					return ipamPrefixError(prefix, err)
 				}
 			}
 		}
@@ -417,7 +489,9 @@ func (api *API) CreateDevice(c *gin.Context) {
 			TunnelIP:                 ipamIP,
 			TunnelIpV6:               ipamIPv6,
 			ChildPrefix:              request.ChildPrefix,
+			ChildPrefixPriority:      request.ChildPrefixPriority,
 			Relay:                    request.Relay,
+			Hub:                      request.Hub,
 			Discovery:                request.Discovery,
 			OrganizationPrefix:       org.IpCidr,
 			OrganizationPrefixV6:     org.IpCidrV6,
@@ -437,22 +511,34 @@ func (api *API) CreateDevice(c *gin.Context) {
 			attribute.String("id", device.ID.String()),
 		)
 
-		return nil
+		if _, err := api.electChildPrefixRouters(ctx, tx, org.ID); err != nil {
+			return err
+		}
+		return tx.First(&device, "id = ?", device.ID).Error
 	})
 
 	if err != nil {
 		var duplicate errDuplicateDevice
+		var invalid errInvalidCidr
+		var conflict errPrefixConflict
+		var quota errQuotaExceeded
 		if errors.Is(err, errUserOrOrgNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotAllowedError("user or organization"))
 		} else if errors.As(err, &duplicate) {
 			c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+		} else if errors.As(err, &quota) {
+			c.JSON(http.StatusForbidden, models.NewQuotaExceededError(quota.quota, quota.limit))
+		} else if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.field, invalid.reason))
+		} else if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, models.NewPrefixConflictError(conflict.prefix, conflict.conflictsWith, conflict.deviceID))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
 		return
 	}
 
-	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
+	api.notifyDevicesChanged(ctx, device.OrganizationID)
 	c.JSON(http.StatusCreated, device)
 }
 
@@ -503,43 +589,155 @@ func (api *API) DeleteDevice(c *gin.Context) {
 		ipamNamespace = org.ID
 	}
 
-	ipamAddress := device.TunnelIP
-	orgPrefix := device.OrganizationPrefix
-	childPrefix := device.ChildPrefix
-
 	if res := api.db.WithContext(ctx).
 		Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
 		Delete(&device, "id = ?", device.Base.ID); res.Error != nil {
 		c.JSON(http.StatusBadRequest, models.NewApiInternalError(res.Error))
 		return
 	}
+	if res := api.db.WithContext(ctx).
+		Delete(&models.DevicePeerStatus{}, "device_id = ?", device.Base.ID); res.Error != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiInternalError(res.Error))
+		return
+	}
+	// another device may take over the child prefixes of the deleted device.
+	if _, err := api.electChildPrefixRouters(ctx, api.db, device.OrganizationID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+
+	api.notifyDevicesChanged(ctx, device.OrganizationID)
+
+	if err := api.releaseDeviceAddresses(c.Request.Context(), ipamNamespace, device); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
 
-	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
+// releaseDeviceAddresses releases the tunnel addresses and child prefixes leased to a device back to ipam
+func (api *API) releaseDeviceAddresses(ctx context.Context, ipamNamespace uuid.UUID, device models.Device) error {
+	if device.TunnelIP != "" && device.OrganizationPrefix != "" {
+		if err := api.ipam.ReleaseToPool(ctx, ipamNamespace, device.TunnelIP, device.OrganizationPrefix); err != nil {
+			return fmt.Errorf("failed to release the v4 address to pool: %w", err)
+		}
+	}
 
-	if ipamAddress != "" && orgPrefix != "" {
-		if err := api.ipam.ReleaseToPool(c.Request.Context(), ipamNamespace, ipamAddress, orgPrefix); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v4 address to pool: %w", err)))
-			return
+	for _, prefix := range device.ChildPrefix {
+		if util.IsDefaultIPRoute(prefix) {
+			continue
+		}
+		inUse, err := childPrefixInUse(api.db.WithContext(ctx), device.OrganizationID, device.ID, prefix)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			continue
+		}
+		if err := api.ipam.ReleasePrefix(ctx, ipamNamespace, prefix); err != nil {
+			return fmt.Errorf("failed to release child prefix: %w", err)
 		}
 	}
 
-	for _, prefix := range childPrefix {
-		if err := api.ipam.ReleasePrefix(c.Request.Context(), ipamNamespace, prefix); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release child prefix: %w", err)))
-			return
+	if device.TunnelIpV6 != "" && device.OrganizationPrefixV6 != "" {
+		if err := api.ipam.ReleaseToPool(ctx, ipamNamespace, device.TunnelIpV6, device.OrganizationPrefixV6); err != nil {
+			return fmt.Errorf("failed to release the v6 address to pool: %w", err)
 		}
 	}
+	return nil
+}
 
-	ipamAddressV6 := device.TunnelIpV6
-	orgPrefixV6 := device.OrganizationPrefixV6
+// notifyDevicesChanged signals the device watchers of the organization. The signal carries the
+// latest device revision of the organization, so the watchers that have already sent it to their
+// clients don't need to select the devices again.
+func (api *API) notifyDevicesChanged(ctx context.Context, orgID uuid.UUID) {
+	name := fmt.Sprintf("/devices/org=%s", orgID.String())
+	var revision uint64
+	if res := api.db.WithContext(ctx).Unscoped().
+		Model(&models.Device{}).
+		Select("COALESCE(MAX(revision), 0)").
+		Where("organization_id = ?", orgID.String()).
+		Scan(&revision); res.Error != nil {
+		api.Logger(ctx).Infof("failed to get the device revision of organization %s: %s", orgID, res.Error)
+		api.signalBus.Notify(name)
+		return
+	}
+	api.signalBus.NotifyWithPayload(name, strconv.FormatUint(revision, 10))
+}
 
-	if ipamAddressV6 != "" && orgPrefixV6 != "" {
-		if err := api.ipam.ReleaseToPool(c.Request.Context(), ipamNamespace, ipamAddressV6, orgPrefixV6); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v6 address to pool: %w", err)))
-			return
+// HeartbeatDevice records a heartbeat from a Device
+// @Summary      Device Heartbeat
+// @Description  Records a heartbeat from a device and marks it as online
+// @Id  		 HeartbeatDevice
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "Device ID"
+// @Success      200  {object}  models.Device
+// @Failure		 401  {object}  models.BaseError
+// @Failure      400  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/devices/{id}/heartbeat [post]
+func (api *API) HeartbeatDevice(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "HeartbeatDevice", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	k, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var device models.Device
+	wasOnline := false
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		result := tx.
+			Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			First(&device, "id = ?", k)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errDeviceNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		wasOnline = device.Online
+		now := time.Now()
+		device.LastSeen = &now
+		device.Online = true
+
+		if res := tx.Model(&device).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Select("last_seen", "online").
+			Updates(&device); res.Error != nil {
+			return res.Error
 		}
+		if !wasOnline {
+			// a router that comes back online may take its child prefixes back.
+			if _, err := api.electChildPrefixRouters(ctx, tx, device.OrganizationID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, errDeviceNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
 	}
 
+	// peers only need to be woken up when the online status changes, the updated
+	// last_seen value is picked up by their next periodic list.
+	if !wasOnline {
+		api.notifyDevicesChanged(ctx, device.OrganizationID)
+	}
 	c.JSON(http.StatusOK, device)
 }
 
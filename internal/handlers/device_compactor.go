@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"gorm.io/gorm"
+)
+
+// StartDeviceCompactor starts a background worker that periodically runs CompactDevices. When several
+// apiserver replicas share the database, only one of them compacts the devices at a time.
+func (api *API) StartDeviceCompactor(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, retention time.Duration) {
+	util.GoWithWaitGroup(wg, func() {
+		util.RunPeriodically(ctx, interval, func() {
+			var removed int64
+			_, err := database.RunExclusively(ctx, api.db, api.dialect, "device-compactor", func() error {
+				var err error
+				removed, err = api.CompactDevices(ctx, retention)
+				return err
+			})
+			if err != nil {
+				api.Logger(ctx).Warnf("device compactor failed: %v", err)
+				return
+			}
+			if removed > 0 {
+				api.Logger(ctx).Infof("device compactor removed [ %d ] device tombstones", removed)
+			}
+		})
+	})
+}
+
+// CompactDevices removes the tombstones of the devices that were deleted longer than the retention
+// ago, and records the highest removed revision of each organization so that the device watches
+// resuming from an older revision are expired instead of missing the deletes. The child prefixes
+// of the removed devices that are still leased are released first, as IpamGC can't tell that
+// nexodus created them once the devices are gone.
+func (api *API) CompactDevices(parent context.Context, retention time.Duration) (int64, error) {
+	ctx, span := tracer.Start(parent, "CompactDevices")
+	defer span.End()
+
+	cutoff := time.Now().Add(-retention)
+
+	var tombstones []struct {
+		OrganizationID uuid.UUID
+		Revision       uint64
+	}
+	if res := api.db.WithContext(ctx).Unscoped().
+		Model(&models.Device{}).
+		Select("organization_id, MAX(revision) AS revision").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Group("organization_id").
+		Scan(&tombstones); res.Error != nil {
+		return 0, res.Error
+	}
+
+	removed := int64(0)
+	for _, tombstone := range tombstones {
+		err := api.transaction(ctx, func(tx *gorm.DB) error {
+			var compaction models.DeviceCompaction
+			if res := tx.Limit(1).Find(&compaction, "organization_id = ?", tombstone.OrganizationID); res.Error != nil {
+				return res.Error
+			}
+			compaction.OrganizationID = tombstone.OrganizationID
+			if tombstone.Revision > compaction.Revision {
+				compaction.Revision = tombstone.Revision
+			}
+			if res := tx.Save(&compaction); res.Error != nil {
+				return res.Error
+			}
+
+			var compacted []models.Device
+			if res := tx.Unscoped().
+				Select("id", "organization_id", "child_prefix").
+				Where("organization_id = ? AND deleted_at IS NOT NULL AND deleted_at < ? AND revision <= ?",
+					tombstone.OrganizationID, cutoff, compaction.Revision).
+				Where("child_prefix IS NOT NULL AND child_prefix <> '{}'").
+				Find(&compacted); res.Error != nil {
+				return res.Error
+			}
+			if err := api.releaseCompactedPrefixes(database.WithTx(ctx, tx), tx, tombstone.OrganizationID, compacted); err != nil {
+				return err
+			}
+
+			res := tx.Unscoped().
+				Where("organization_id = ? AND deleted_at IS NOT NULL AND deleted_at < ? AND revision <= ?",
+					tombstone.OrganizationID, cutoff, compaction.Revision).
+				Delete(&models.Device{})
+			if res.Error != nil {
+				return res.Error
+			}
+			removed += res.RowsAffected
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+	deviceTombstonesCompacted.Add(float64(removed))
+	return removed, nil
+}
+
+// releaseCompactedPrefixes releases the child prefixes of the compacted devices of an organization
+// that are still leased and that no other device of the organization uses.
+func (api *API) releaseCompactedPrefixes(ctx context.Context, tx *gorm.DB, orgID uuid.UUID, devices []models.Device) error {
+	if len(devices) == 0 {
+		return nil
+	}
+	var org models.Organization
+	if res := tx.Unscoped().Limit(1).Find(&org, "id = ?", orgID); res.Error != nil {
+		return res.Error
+	}
+	namespace := defaultIPAMNamespace
+	if org.PrivateCidr {
+		if org.DeletedAt.Valid || org.ID == uuid.Nil {
+			// the namespace was removed with the organization.
+			return nil
+		}
+		namespace = org.ID
+	}
+
+	lister, ok := api.ipam.(ipam.Lister)
+	if !ok {
+		return ipam.ErrListingNotSupported
+	}
+	prefixes, err := lister.ListPrefixes(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	leased := map[string]bool{}
+	for _, prefix := range prefixes {
+		leased[normalizePrefix(prefix)] = true
+	}
+
+	for _, device := range devices {
+		for _, prefix := range device.ChildPrefix {
+			prefix = normalizePrefix(prefix)
+			if util.IsDefaultIPRoute(prefix) || !leased[prefix] {
+				continue
+			}
+			inUse, err := childPrefixInUse(tx, orgID, device.ID, prefix)
+			if err != nil {
+				return err
+			}
+			if inUse {
+				continue
+			}
+			if err := api.ipam.ReleasePrefix(ctx, namespace, prefix); err != nil {
+				return fmt.Errorf("failed to release the child prefix %s of device %s: %w", prefix, device.ID, err)
+			}
+			delete(leased, prefix)
+		}
+	}
+	return nil
+}
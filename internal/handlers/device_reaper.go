@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"gorm.io/gorm/clause"
+)
+
+// StartDeviceReaper starts a background worker that periodically runs ReapDevices. When several
+// apiserver replicas share the database, only one of them reaps the devices at a time.
+func (api *API) StartDeviceReaper(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, offlineTimeout time.Duration) {
+	util.GoWithWaitGroup(wg, func() {
+		util.RunPeriodically(ctx, interval, func() {
+			_, err := database.RunExclusively(ctx, api.db, api.dialect, "device-reaper", func() error {
+				return api.ReapDevices(ctx, offlineTimeout)
+			})
+			if err != nil {
+				api.Logger(ctx).Warnf("device reaper failed: %v", err)
+			}
+		})
+	})
+}
+
+// ReapDevices marks devices that have not sent a heartbeat within the offlineTimeout as offline,
+// and deletes the devices that have not been seen within their organization's device expiry.
+func (api *API) ReapDevices(parent context.Context, offlineTimeout time.Duration) error {
+	ctx, span := tracer.Start(parent, "ReapDevices")
+	defer span.End()
+
+	now := time.Now()
+	changedOrgs := map[uuid.UUID]struct{}{}
+
+	var stale []models.Device
+	if res := api.db.WithContext(ctx).
+		Where("online = ? AND (last_seen IS NULL OR last_seen < ?)", true, now.Add(-offlineTimeout)).
+		Find(&stale); res.Error != nil {
+		return res.Error
+	}
+	for _, device := range stale {
+		if res := api.db.WithContext(ctx).
+			Model(&device).
+			Where("online = ?", true).
+			Update("online", false); res.Error != nil {
+			return res.Error
+		}
+		changedOrgs[device.OrganizationID] = struct{}{}
+	}
+
+	var orgs []models.Organization
+	if res := api.db.WithContext(ctx).
+		Where("device_expiry_seconds > 0").
+		Find(&orgs); res.Error != nil {
+		return res.Error
+	}
+	for _, org := range orgs {
+		expired, err := api.expireDevices(ctx, org, now.Add(-time.Duration(org.DeviceExpirySeconds)*time.Second))
+		if err != nil {
+			return err
+		}
+		if expired > 0 {
+			changedOrgs[org.ID] = struct{}{}
+		}
+	}
+
+	for orgID := range changedOrgs {
+		// the devices that went offline or were expired may have been routing child prefixes.
+		if _, err := api.electChildPrefixRouters(ctx, api.db, orgID); err != nil {
+			return err
+		}
+		api.notifyDevicesChanged(ctx, orgID)
+	}
+	return nil
+}
+
+// expireDevices deletes the devices in the organization that have not been seen since the cutoff
+// and releases their ipam leases.
+func (api *API) expireDevices(ctx context.Context, org models.Organization, cutoff time.Time) (int, error) {
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).
+		Where("organization_id = ?", org.ID).
+		Where("last_seen < ? OR (last_seen IS NULL AND created_at < ?)", cutoff, cutoff).
+		Find(&devices); res.Error != nil {
+		return 0, res.Error
+	}
+
+	ipamNamespace := defaultIPAMNamespace
+	if org.PrivateCidr {
+		ipamNamespace = org.ID
+	}
+
+	for _, device := range devices {
+		if res := api.db.WithContext(ctx).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Delete(&device, "id = ?", device.ID); res.Error != nil {
+			return 0, res.Error
+		}
+		if res := api.db.WithContext(ctx).
+			Delete(&models.DevicePeerStatus{}, "device_id = ?", device.ID); res.Error != nil {
+			return 0, res.Error
+		}
+		api.Logger(ctx).Infof("Expired device [ %s ] last seen [ %v ] in organization [ %s ]", device.ID, device.LastSeen, org.ID)
+		if err := api.releaseDeviceAddresses(ctx, ipamNamespace, device); err != nil {
+			return 0, err
+		}
+	}
+	return len(devices), nil
+}
@@ -2,14 +2,21 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/nexodus-io/nexodus/internal/fflags"
+	"github.com/nexodus-io/nexodus/internal/ipam"
 	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/signalbus"
+	"github.com/open-policy-agent/opa/storage/inmem"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func (suite *HandlerTestSuite) TestCreateGetDevice() {
@@ -60,6 +67,116 @@ func (suite *HandlerTestSuite) TestCreateGetDevice() {
 	assert.Equal(actual, device)
 }
 
+func (suite *HandlerTestSuite) TestDeviceHeartbeatAndReaper() {
+	require := suite.Require()
+	assert := suite.Assert()
+	newDevice := models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "heartbeatpubkey",
+	}
+
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/", "/",
+		suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(newDevice)),
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+	var device models.Device
+	err = json.Unmarshal(body, &device)
+	require.NoError(err)
+	assert.False(device.Online)
+	assert.Nil(device.LastSeen)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodPost, "/:id/heartbeat", fmt.Sprintf("/%s/heartbeat", device.ID),
+		suite.api.HeartbeatDevice, nil,
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	err = json.Unmarshal(body, &device)
+	require.NoError(err)
+	assert.True(device.Online)
+	require.NotNil(device.LastSeen)
+
+	// devices that have recently sent a heartbeat stay online
+	err = suite.api.ReapDevices(context.Background(), time.Minute)
+	require.NoError(err)
+	require.NoError(suite.api.db.First(&device, "id = ?", device.ID).Error)
+	assert.True(device.Online)
+
+	// devices that have missed their heartbeats are marked offline
+	err = suite.api.ReapDevices(context.Background(), 0)
+	require.NoError(err)
+	require.NoError(suite.api.db.First(&device, "id = ?", device.ID).Error)
+	assert.False(device.Online)
+
+	// devices unseen for longer than the org device expiry are deleted
+	require.NoError(suite.api.db.Model(&models.Organization{}).
+		Where("id = ?", suite.testOrganizationID).
+		Update("device_expiry_seconds", 60).Error)
+	require.NoError(suite.api.db.Model(&device).
+		Update("last_seen", time.Now().Add(-2*time.Minute)).Error)
+
+	err = suite.api.ReapDevices(context.Background(), time.Minute)
+	require.NoError(err)
+	err = suite.api.db.First(&device, "id = ?", device.ID).Error
+	assert.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func (suite *HandlerTestSuite) TestCreateHubDevice() {
+	require := suite.Require()
+	createDevice := func(device models.AddDevice) (int, []byte) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost,
+			"/", "/",
+			suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(device)),
+		)
+		require.NoError(err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(err)
+		return res.Code, body
+	}
+
+	// a hub has to be a relay
+	code, body := createDevice(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "hubpubkey1",
+		Hub:            true,
+	})
+	require.Equal(http.StatusBadRequest, code, "HTTP error: %s", string(body))
+
+	code, body = createDevice(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "hubpubkey1",
+		Relay:          true,
+		Hub:            true,
+	})
+	require.Equal(http.StatusCreated, code, "HTTP error: %s", string(body))
+	var device models.Device
+	require.NoError(json.Unmarshal(body, &device))
+	require.True(device.Hub)
+	require.True(device.Relay)
+
+	// only hub organizations have hubs
+	require.NoError(suite.api.db.Model(&models.Organization{}).
+		Where("id = ?", suite.testOrganizationID).
+		Update("hub_zone", false).Error)
+	code, body = createDevice(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "hubpubkey2",
+		Relay:          true,
+		Hub:            true,
+	})
+	require.Equal(http.StatusBadRequest, code, "HTTP error: %s", string(body))
+}
+
 func TestChildPrefixEquals(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -100,3 +217,60 @@ func TestChildPrefixEquals(t *testing.T) {
 		})
 	}
 }
+
+func (suite *HandlerTestSuite) TestCompactDevices() {
+	require := suite.Require()
+	ctx := context.Background()
+
+	suite.api.db.Exec("DELETE FROM ipam_addresses")
+	suite.api.db.Exec("DELETE FROM ipam_prefixes")
+	embedded := ipam.NewEmbeddedIPAM(suite.logger, suite.api.db)
+	signalBus := signalbus.NewSignalBus()
+	api, err := NewAPI(ctx, suite.logger, suite.api.db, embedded, fflags.NewFFlags(suite.logger, suite.api.db, signalBus), inmem.New(), signalBus)
+	require.NoError(err)
+
+	now := time.Now()
+	devices := []models.Device{
+		{OrganizationID: suite.testOrganizationID, PublicKey: "compactpubkeyA", Revision: 5, ChildPrefix: []string{"172.16.60.0/24", "172.16.61.0/24"}},
+		{OrganizationID: suite.testOrganizationID, PublicKey: "compactpubkeyB", Revision: 7},
+		{OrganizationID: suite.testOrganizationID, PublicKey: "compactpubkeyC", Revision: 8, ChildPrefix: []string{"172.16.61.0/24"}},
+	}
+	require.NoError(embedded.AssignPrefix(ctx, defaultIPAMNamespace, "172.16.60.0/24"))
+	require.NoError(embedded.AssignPrefix(ctx, defaultIPAMNamespace, "172.16.61.0/24"))
+	for i := range devices {
+		require.NoError(suite.api.db.Create(&devices[i]).Error)
+	}
+	defer suite.api.db.Unscoped().Delete(&models.Device{}, "public_key LIKE ?", "compactpubkey%")
+	defer suite.api.db.Delete(&models.DeviceCompaction{}, "organization_id = ?", suite.testOrganizationID)
+
+	// A was deleted long ago, B recently, and C is still there.
+	require.NoError(suite.api.db.Model(&devices[0]).Update("deleted_at", now.Add(-2*time.Hour)).Error)
+	require.NoError(suite.api.db.Model(&devices[1]).Update("deleted_at", now.Add(-time.Minute)).Error)
+
+	removed, err := api.CompactDevices(ctx, time.Hour)
+	require.NoError(err)
+	require.Equal(int64(1), removed)
+
+	var remaining []models.Device
+	require.NoError(suite.api.db.Unscoped().Order("revision").Find(&remaining, "public_key LIKE ?", "compactpubkey%").Error)
+	require.Len(remaining, 2)
+	require.Equal(devices[1].ID, remaining[0].ID)
+	require.Equal(devices[2].ID, remaining[1].ID)
+
+	var compaction models.DeviceCompaction
+	require.NoError(suite.api.db.First(&compaction, "organization_id = ?", suite.testOrganizationID).Error)
+	require.Equal(uint64(5), compaction.Revision)
+
+	// the child prefixes of the removed device are released, unless another device uses them.
+	prefixes, err := embedded.ListPrefixes(ctx, defaultIPAMNamespace)
+	require.NoError(err)
+	require.NotContains(prefixes, "172.16.60.0/24")
+	require.Contains(prefixes, "172.16.61.0/24")
+
+	// nothing else is old enough to be removed, the compacted revision is kept.
+	removed, err = api.CompactDevices(ctx, time.Hour)
+	require.NoError(err)
+	require.Equal(int64(0), removed)
+	require.NoError(suite.api.db.First(&compaction, "organization_id = ?", suite.testOrganizationID).Error)
+	require.Equal(uint64(5), compaction.Revision)
+}
@@ -1,29 +1,83 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/fflags"
 	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// flagIdentity returns the identity the feature flags are evaluated for, the current user and
+// the organizations they belong to.
+func (api *API) flagIdentity(ctx context.Context, c *gin.Context) (fflags.Identity, error) {
+	identity := fflags.Identity{UserID: c.GetString(gin.AuthUserKey)}
+	if identity.UserID == "" {
+		return identity, nil
+	}
+	if res := api.db.WithContext(ctx).Table("user_organizations").
+		Where("user_id = ?", identity.UserID).
+		Pluck("organization_id", &identity.OrganizationIDs); res.Error != nil {
+		return identity, res.Error
+	}
+	return identity, nil
+}
+
+// flagCheck returns true if the feature flag is enabled for the current user, otherwise
+// it responds that the feature is disabled.
+func (api *API) flagCheck(ctx context.Context, c *gin.Context, flag string) bool {
+	identity, err := api.flagIdentity(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return false
+	}
+	enabled, err := api.fflags.GetFlag(ctx, flag, identity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return false
+	}
+	if !enabled {
+		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError(fmt.Sprintf("%s support is disabled", flag)))
+		return false
+	}
+	return true
+}
+
 // ListFeatureFlags lists all feature flags
 // @Summary      List Feature Flags
-// @Description  Lists all feature flags
+// @Description  Lists all feature flags and whether they are enabled for the current user
 // @Id           ListFeatureFlags
 // @Tags         FFlag
 // @Accept       json
 // @Produce      json
 // @Success      200  {object} map[string]bool
 // @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
 // @Router       /api/fflags [get]
 func (api *API) ListFeatureFlags(c *gin.Context) {
-	c.JSON(http.StatusOK, api.fflags.ListFlags())
+	ctx, span := tracer.Start(c.Request.Context(), "ListFeatureFlags")
+	defer span.End()
+	identity, err := api.flagIdentity(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	flags, err := api.fflags.ListFlags(ctx, identity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, flags)
 }
 
 // GetFeatureFlag gets a feature flag by name
 // @Summary      Get Feature Flag
-// @Description  Gets a Feature Flag by name
+// @Description  Gets a Feature Flag by name, and whether it is enabled for the current user
 // @Id           GetFeatureFlag
 // @Tags         FFlag
 // @Accept       json
@@ -33,19 +87,146 @@ func (api *API) ListFeatureFlags(c *gin.Context) {
 // @Failure      400  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
 // @Router       /api/fflags/{name} [get]
 func (api *API) GetFeatureFlag(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetFeatureFlag",
+		trace.WithAttributes(
+			attribute.String("name", c.Param("name")),
+		))
+	defer span.End()
 	flagName := c.Param("name")
 	if flagName == "" {
 		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("name"))
 		return
 	}
 
-	enabled, err := api.fflags.GetFlag(flagName)
+	identity, err := api.flagIdentity(ctx, c)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	enabled, err := api.fflags.GetFlag(ctx, flagName, identity)
+	if errors.Is(err, fflags.ErrUnknownFlag) {
 		c.JSON(http.StatusNotFound, models.NewNotFoundError("flag"))
 		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
 	}
 
 	c.JSON(http.StatusOK, map[string]bool{flagName: enabled})
 }
+
+// AdminListFeatureFlags lists the configuration of the feature flags
+// @Summary      List Feature Flag Configurations
+// @Description  Lists who the feature flags are enabled for, requires the admin role
+// @Id           AdminListFeatureFlags
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  []models.FeatureFlag
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/fflags [get]
+func (api *API) AdminListFeatureFlags(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminListFeatureFlags")
+	defer span.End()
+	flags, err := api.fflags.ListFlagConfigs(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, flags)
+}
+
+// AdminUpdateFeatureFlag configures a feature flag
+// @Summary      Update Feature Flag
+// @Description  Configures who a feature flag is enabled for, requires the admin role
+// @Id           AdminUpdateFeatureFlag
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string  true "feature flag name"
+// @Param        update body     models.UpdateFeatureFlag true "Feature Flag Update"
+// @Success      200  {object}  models.FeatureFlag
+// @Failure      400  {object}  models.BaseError
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/fflags/{name} [put]
+func (api *API) AdminUpdateFeatureFlag(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminUpdateFeatureFlag",
+		trace.WithAttributes(
+			attribute.String("name", c.Param("name")),
+		))
+	defer span.End()
+	var request models.UpdateFeatureFlag
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.Percentage < 0 || request.Percentage > 100 {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("percentage", "must be between 0 and 100"))
+		return
+	}
+
+	flag := models.FeatureFlag{
+		Name:          c.Param("name"),
+		Enabled:       request.Enabled,
+		Organizations: make([]string, 0, len(request.Organizations)),
+		Users:         make([]string, 0, len(request.Users)),
+		Percentage:    request.Percentage,
+	}
+	for _, org := range request.Organizations {
+		flag.Organizations = append(flag.Organizations, org.String())
+	}
+	flag.Users = append(flag.Users, request.Users...)
+
+	err := api.fflags.SetFlag(ctx, flag)
+	if errors.Is(err, fflags.ErrUnknownFlag) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("flag"))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	api.Logger(ctx).Infof("feature flag %s updated: %+v", flag.Name, request)
+	c.JSON(http.StatusOK, flag)
+}
+
+// AdminResetFeatureFlag removes the configuration of a feature flag
+// @Summary      Reset Feature Flag
+// @Description  Removes the configuration of a feature flag so that it uses its default, requires the admin role
+// @Id           AdminResetFeatureFlag
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string  true "feature flag name"
+// @Success      204
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/admin/fflags/{name} [delete]
+func (api *API) AdminResetFeatureFlag(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AdminResetFeatureFlag",
+		trace.WithAttributes(
+			attribute.String("name", c.Param("name")),
+		))
+	defer span.End()
+	err := api.fflags.ResetFlag(ctx, c.Param("name"))
+	if errors.Is(err, fflags.ErrUnknownFlag) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("flag"))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/fflags"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/signalbus"
+)
+
+func (suite *HandlerTestSuite) TestFeatureFlags() {
+	require := suite.Require()
+
+	listFlags := func() map[string]bool {
+		_, res, err := suite.ServeRequest(http.MethodGet, "/", "/", suite.api.ListFeatureFlags, nil)
+		require.NoError(err)
+		require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+		var flags map[string]bool
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &flags))
+		return flags
+	}
+	updateFlag := func(name string, update models.UpdateFeatureFlag) *httptest.ResponseRecorder {
+		_, res, err := suite.ServeRequest(http.MethodPut, "/:name", "/"+name, suite.api.AdminUpdateFeatureFlag, bytes.NewBuffer(suite.jsonMarshal(update)))
+		require.NoError(err)
+		return res
+	}
+
+	// the flags that are not configured use their default.
+	require.Equal(map[string]bool{"multi-organization": true, "security-groups": false}, listFlags())
+
+	// targeting the organization of the user.
+	res := updateFlag("security-groups", models.UpdateFeatureFlag{Organizations: []uuid.UUID{suite.testOrganizationID}})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.True(listFlags()["security-groups"])
+	_, res, err := suite.ServeRequest(http.MethodGet, "/:name", "/security-groups", suite.api.GetFeatureFlag, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.JSONEq(`{"security-groups": true}`, res.Body.String())
+
+	// targeting another organization.
+	res = updateFlag("security-groups", models.UpdateFeatureFlag{Organizations: []uuid.UUID{suite.testUser2OrgID}})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.False(listFlags()["security-groups"])
+
+	// targeting the user.
+	res = updateFlag("security-groups", models.UpdateFeatureFlag{Users: []string{TestUserID}})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.True(listFlags()["security-groups"])
+
+	// a percentage rollout keeps the users that were in it as it grows.
+	ctx := context.Background()
+	enabled := func(userID string) bool {
+		value, err := suite.api.fflags.GetFlag(ctx, "security-groups", fflags.Identity{UserID: userID})
+		require.NoError(err)
+		return value
+	}
+	users := make([]string, 200)
+	for i := range users {
+		users[i] = uuid.New().String()
+	}
+	rolledOut := func() map[string]bool {
+		result := map[string]bool{}
+		for _, user := range users {
+			if enabled(user) {
+				result[user] = true
+			}
+		}
+		return result
+	}
+	res = updateFlag("security-groups", models.UpdateFeatureFlag{Percentage: 10})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	tenPercent := rolledOut()
+	require.Greater(len(tenPercent), 0)
+	require.Less(len(tenPercent), 60)
+	res = updateFlag("security-groups", models.UpdateFeatureFlag{Percentage: 50})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	fiftyPercent := rolledOut()
+	require.Greater(len(fiftyPercent), len(tenPercent))
+	for user := range tenPercent {
+		require.True(fiftyPercent[user])
+	}
+
+	// invalid updates.
+	require.Equal(http.StatusBadRequest, updateFlag("security-groups", models.UpdateFeatureFlag{Percentage: 101}).Code)
+	require.Equal(http.StatusNotFound, updateFlag("unknown", models.UpdateFeatureFlag{Enabled: true}).Code)
+	_, res, err = suite.ServeRequest(http.MethodGet, "/:name", "/unknown", suite.api.GetFeatureFlag, nil)
+	require.NoError(err)
+	require.Equal(http.StatusNotFound, res.Code)
+
+	// the admins list the configuration of all the flags.
+	_, res, err = suite.ServeRequest(http.MethodGet, "/", "/", suite.api.AdminListFeatureFlags, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var configs []models.FeatureFlag
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &configs))
+	require.Len(configs, 2)
+	require.Equal("multi-organization", configs[0].Name)
+	require.True(configs[0].Enabled)
+	require.Equal("security-groups", configs[1].Name)
+	require.Equal(50, configs[1].Percentage)
+
+	// a reset flag uses its default again.
+	_, res, err = suite.ServeRequest(http.MethodDelete, "/:name", "/security-groups", suite.api.AdminResetFeatureFlag, nil)
+	require.NoError(err)
+	require.Equal(http.StatusNoContent, res.Code, "HTTP error: %s", res.Body.String())
+	require.False(listFlags()["security-groups"])
+}
+
+func (suite *HandlerTestSuite) TestFeatureFlagsCacheInvalidation() {
+	require := suite.Require()
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	// two replicas sharing the database and the signalbus.
+	signalBus := signalbus.NewSignalBus()
+	replica1 := fflags.NewFFlags(suite.logger, suite.api.db, signalBus)
+	replica2 := fflags.NewFFlags(suite.logger, suite.api.db, signalBus)
+	replica2.Start(ctx, wg)
+
+	enabled, err := replica2.GetFlag(ctx, "security-groups", fflags.Identity{UserID: TestUserID})
+	require.NoError(err)
+	require.False(enabled)
+
+	require.NoError(replica1.SetFlag(ctx, models.FeatureFlag{Name: "security-groups", Enabled: true}))
+	require.Eventually(func() bool {
+		enabled, err := replica2.GetFlag(ctx, "security-groups", fflags.Identity{UserID: TestUserID})
+		return err == nil && enabled
+	}, 2*time.Second, 10*time.Millisecond)
+}
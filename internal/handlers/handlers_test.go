@@ -18,6 +18,7 @@ import (
 	"github.com/nexodus-io/nexodus/internal/database"
 	"github.com/nexodus-io/nexodus/internal/fflags"
 	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/open-policy-agent/opa/storage/inmem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -61,11 +62,12 @@ func (suite *HandlerTestSuite) SetupSuite() {
 		}
 	}()
 
-	ipamClient := ipam.NewIPAM(suite.logger, ipamClientAddr)
+	ipamClient := ipam.NewRemoteIPAM(suite.logger, ipamClientAddr)
 
-	fflags := fflags.NewFFlags(suite.logger)
+	signalBus := signalbus.NewSignalBus()
+	fflags := fflags.NewFFlags(suite.logger, db, signalBus)
 	store := inmem.New()
-	suite.api, err = NewAPI(context.Background(), suite.logger, db, ipamClient, fflags, store, signalbus.NewSignalBus())
+	suite.api, err = NewAPI(context.Background(), suite.logger, db, ipamClient, fflags, store, signalBus)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
@@ -76,13 +78,24 @@ func (suite *HandlerTestSuite) BeforeTest(_, _ string) {
 	suite.api.db.Exec("DELETE FROM organizations")
 	suite.api.db.Exec("DELETE FROM user_organizations")
 	suite.api.db.Exec("DELETE FROM devices")
-	var err error
+	suite.api.db.Exec("DELETE FROM device_peer_statuses")
+	suite.api.db.Exec("DELETE FROM organization_peerings")
+	flags, err := suite.api.fflags.ListFlagConfigs(context.Background())
+	suite.Require().NoError(err)
+	for _, flag := range flags {
+		suite.Require().NoError(suite.api.fflags.ResetFlag(context.Background(), flag.Name))
+	}
 	suite.testOrganizationID, err = suite.api.createUserIfNotExists(context.Background(), TestUserID, "testuser")
 	suite.Require().NoError(err)
 	suite.testUser2OrgID, err = suite.api.createUserIfNotExists(context.Background(), TestUser2ID, "testuser2")
 	suite.Require().NoError(err)
 }
 
+// setFeatureFlag enables or disables a feature flag for everyone.
+func (suite *HandlerTestSuite) setFeatureFlag(name string, enabled bool) {
+	suite.Require().NoError(suite.api.fflags.SetFlag(context.Background(), models.FeatureFlag{Name: name, Enabled: enabled}))
+}
+
 func (suite *HandlerTestSuite) ServeRequest(method, path string, uri string, handler func(*gin.Context), body io.Reader) (*http.Request, *httptest.ResponseRecorder, error) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
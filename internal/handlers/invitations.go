@@ -30,14 +30,7 @@ import (
 func (api *API) CreateInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "InviteUserToOrganization")
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
-		return
-	}
-	allowForTests := c.GetString("_apex.testCreateOrganization")
-	if !multiOrganizationEnabled && allowForTests != "true" {
-		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError("multi-organization support is disabled"))
+	if !api.flagCheck(ctx, c, "multi-organization") {
 		return
 	}
 	var request models.AddInvitation
@@ -92,7 +85,7 @@ func (api *API) CreateInvitation(c *gin.Context) {
 
 	invite := models.NewInvitation(user.ID, request.OrganizationID)
 	if res := api.db.WithContext(ctx).Create(&invite); res.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
 		return
 	}
 	c.JSON(http.StatusCreated, invite)
@@ -131,13 +124,13 @@ func (api *API) ListInvitations(c *gin.Context) {
 // @Tags         Invitation
 // @Accept       json
 // @Produce      json
-// @Param		 id   path      string true "Invitation ID"
+// @Param		 invitation   path      string true "Invitation ID"
 // @Success      200  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Router       /api/organizations/{id} [get]
+// @Router       /api/invitations/{invitation} [get]
 func (api *API) GetInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "GetOrganizations",
 		trace.WithAttributes(
@@ -166,6 +159,21 @@ func (api *API) GetInvitation(c *gin.Context) {
 	c.JSON(http.StatusOK, org)
 }
 
+// InvitationOrganization returns the organization of an invitation for the current user or of
+// an organization they own, or an empty string if there is none. The invitation routes are rate
+// limited by it.
+func (api *API) InvitationOrganization(c *gin.Context, id string) string {
+	var orgIDs []string
+	if res := api.db.WithContext(c.Request.Context()).
+		Model(&models.Invitation{}).
+		Scopes(api.InvitationIsForCurrentUserOrOrgOwner(c)).
+		Where("id = ?", id).
+		Pluck("organization_id", &orgIDs); res.Error != nil || len(orgIDs) == 0 {
+		return ""
+	}
+	return orgIDs[0]
+}
+
 func (api *API) InvitationIsForCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		userId := c.Value(gin.AuthUserKey).(string)
@@ -204,14 +212,7 @@ func (api *API) InvitationIsForCurrentUserOrOrgOwner(c *gin.Context) func(db *go
 func (api *API) AcceptInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "InviteUserToOrganization")
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
-		return
-	}
-	allowForTests := c.GetString("_apex.testCreateOrganization")
-	if !multiOrganizationEnabled && allowForTests != "true" {
-		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError("multi-organization support is disabled"))
+	if !api.flagCheck(ctx, c, "multi-organization") {
 		return
 	}
 	k, err := uuid.Parse(c.Param("invitation"))
@@ -259,6 +260,7 @@ func (api *API) AcceptInvitation(c *gin.Context) {
 		return
 	}
 
+	invitationsAccepted.Inc()
 	c.Status(http.StatusNoContent)
 }
 
@@ -279,14 +281,7 @@ func (api *API) AcceptInvitation(c *gin.Context) {
 func (api *API) DeleteInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "DeleteInvitation")
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
-		return
-	}
-	allowForTests := c.GetString("_apex.testCreateOrganization")
-	if !multiOrganizationEnabled && allowForTests != "true" {
-		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError("multi-organization support is disabled"))
+	if !api.flagCheck(ctx, c, "multi-organization") {
 		return
 	}
 	k, err := uuid.Parse(c.Param("invitation"))
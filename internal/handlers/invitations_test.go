@@ -95,7 +95,6 @@ func (suite *HandlerTestSuite) TestCreateAcceptRefuseInvitation() {
 				http.MethodPost,
 				"/", "/",
 				func(ctx *gin.Context) {
-					ctx.Set("_apex.testCreateOrganization", "true")
 					if c.login != "" {
 						ctx.Set(gin.AuthUserKey, c.login)
 					}
@@ -119,10 +118,7 @@ func (suite *HandlerTestSuite) TestCreateAcceptRefuseInvitation() {
 			_, res, err = suite.ServeRequest(
 				http.MethodPost,
 				"/:invitation", fmt.Sprintf("/%s", inviteID.String()),
-				func(c *gin.Context) {
-					c.Set("_apex.testCreateOrganization", "true")
-					suite.api.AcceptInvitation(c)
-				}, nil,
+				suite.api.AcceptInvitation, nil,
 			)
 			require.NoError(err)
 			body, err := io.ReadAll(res.Body)
@@ -134,10 +130,7 @@ func (suite *HandlerTestSuite) TestCreateAcceptRefuseInvitation() {
 			_, res, err = suite.ServeRequest(
 				http.MethodPost,
 				"/:invitation", fmt.Sprintf("/%s", inviteID.String()),
-				func(c *gin.Context) {
-					c.Set("_apex.testCreateOrganization", "true")
-					suite.api.DeleteInvitation(c)
-				}, nil,
+				suite.api.DeleteInvitation, nil,
 			)
 			require.NoError(err)
 			body, err := io.ReadAll(res.Body)
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+)
+
+// IpamGCOptions configures IpamGC.
+type IpamGCOptions struct {
+	// DryRun only reports the orphaned leases, it does not release them.
+	DryRun bool
+	// GracePeriod is waited between listing the ipam leases and the devices, so that the leases
+	// of devices that are still being created are not mistaken for orphans.
+	GracePeriod time.Duration
+}
+
+// IpamLease is an address or prefix leased from ipam.
+type IpamLease struct {
+	Namespace uuid.UUID `json:"namespace"`
+	Cidr      string    `json:"cidr"`
+	Address   string    `json:"address,omitempty"`
+}
+
+// IpamConflict is an address or prefix that ipam and the devices disagree on.
+type IpamConflict struct {
+	Namespace uuid.UUID   `json:"namespace"`
+	Lease     string      `json:"lease"`
+	Devices   []uuid.UUID `json:"devices"`
+	Reason    string      `json:"reason"`
+}
+
+// IpamGCReport is the result of IpamGC.
+type IpamGCReport struct {
+	DryRun            bool           `json:"dry_run"`
+	ReleasedAddresses []IpamLease    `json:"released_addresses"`
+	ReleasedPrefixes  []IpamLease    `json:"released_prefixes"`
+	Conflicts         []IpamConflict `json:"conflicts"`
+	Errors            []string       `json:"errors"`
+}
+
+// StartIpamGC starts a background worker that periodically runs IpamGC. When several apiserver
+// replicas share the database, only one of them collects the leases at a time.
+func (api *API) StartIpamGC(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, options IpamGCOptions) {
+	util.GoWithWaitGroup(wg, func() {
+		util.RunPeriodically(ctx, interval, func() {
+			var report IpamGCReport
+			ran, err := database.RunExclusively(ctx, api.db, api.dialect, "ipam-gc", func() error {
+				var err error
+				report, err = api.IpamGC(ctx, options)
+				return err
+			})
+			if err != nil {
+				api.Logger(ctx).Warnf("ipam gc failed: %v", err)
+				return
+			}
+			if !ran {
+				return
+			}
+			api.Logger(ctx).Infof("ipam gc released [ %d ] addresses and [ %d ] prefixes, found [ %d ] conflicts and [ %d ] errors (dry run: %v)",
+				len(report.ReleasedAddresses), len(report.ReleasedPrefixes), len(report.Conflicts), len(report.Errors), report.DryRun)
+			for _, conflict := range report.Conflicts {
+				api.Logger(ctx).Warnf("ipam conflict in namespace [ %s ] for [ %s ] devices %v: %s", conflict.Namespace, conflict.Lease, conflict.Devices, conflict.Reason)
+			}
+		})
+	})
+}
+
+// ipamLeases holds the leases of one ipam namespace, the addresses are keyed by prefix.
+type ipamLeases map[string][]string
+
+// IpamGC compares the ipam leases of each namespace with the addresses and child prefixes of
+// the devices, releases the leases that no device or organization uses, and reports conflicts.
+// Only the leases nexodus created are released: the ones in the namespaces of the organizations,
+// and in the default namespace, which other ipam users may share, the cidrs of the organizations
+// and the child prefixes of the devices, including the deleted ones.
+func (api *API) IpamGC(parent context.Context, options IpamGCOptions) (IpamGCReport, error) {
+	ctx, span := tracer.Start(parent, "IpamGC")
+	defer span.End()
+
+	report := IpamGCReport{DryRun: options.DryRun}
+	lister, ok := api.ipam.(ipam.Lister)
+	if !ok {
+		return report, fmt.Errorf("the ipam backend does not support listing leases")
+	}
+
+	listedAt := time.Now()
+	namespaces, err := lister.ListNamespaces(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to list the ipam namespaces: %w", err)
+	}
+	addressesListed := true
+	leases := map[uuid.UUID]ipamLeases{}
+	for _, namespace := range namespaces {
+		prefixes, err := lister.ListPrefixes(ctx, namespace)
+		if err != nil {
+			return report, fmt.Errorf("failed to list the ipam prefixes of namespace %s: %w", namespace, err)
+		}
+		leases[namespace] = ipamLeases{}
+		for _, prefix := range prefixes {
+			var addresses []string
+			if addressesListed {
+				addresses, err = lister.ListAddresses(ctx, namespace, prefix)
+				if errors.Is(err, ipam.ErrListingNotSupported) {
+					api.Logger(ctx).Info("the ipam backend can not list addresses, only orphaned prefixes will be released")
+					addressesListed = false
+				} else if err != nil {
+					return report, fmt.Errorf("failed to list the ipam addresses of prefix %s: %w", prefix, err)
+				}
+			}
+			leases[namespace][normalizePrefix(prefix)] = addresses
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return report, ctx.Err()
+	case <-time.After(options.GracePeriod):
+	}
+
+	var orgs []models.Organization
+	if res := api.db.WithContext(ctx).Find(&orgs); res.Error != nil {
+		return report, res.Error
+	}
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).Find(&devices); res.Error != nil {
+		return report, res.Error
+	}
+	var deletedOrgs []models.Organization
+	if res := api.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Find(&deletedOrgs); res.Error != nil {
+		return report, res.Error
+	}
+	var deletedDevices []models.Device
+	if res := api.db.WithContext(ctx).Unscoped().
+		Select("child_prefix").
+		Where("deleted_at IS NOT NULL AND child_prefix IS NOT NULL AND child_prefix <> '{}'").
+		Find(&deletedDevices); res.Error != nil {
+		return report, res.Error
+	}
+
+	createdNamespaces := map[uuid.UUID]bool{defaultIPAMNamespace: true}
+	createdPrefixes := map[string]bool{
+		normalizePrefix(defaultIPAMv4Cidr): true,
+		normalizePrefix(defaultIPAMv6Cidr): true,
+	}
+	for _, org := range append(orgs, deletedOrgs...) {
+		createdNamespaces[org.ID] = true
+		for _, cidr := range []string{org.IpCidr, org.IpCidrV6} {
+			if cidr != "" {
+				createdPrefixes[normalizePrefix(cidr)] = true
+			}
+		}
+	}
+	for _, device := range append(devices, deletedDevices...) {
+		for _, prefix := range device.ChildPrefix {
+			createdPrefixes[normalizePrefix(prefix)] = true
+		}
+	}
+	created := func(namespace uuid.UUID, prefix string) bool {
+		if namespace != defaultIPAMNamespace {
+			return createdNamespaces[namespace]
+		}
+		return createdPrefixes[prefix]
+	}
+
+	// the prefixes and addresses in use, by namespace, with the devices using them.
+	usedPrefixes := map[uuid.UUID]map[string][]uuid.UUID{
+		defaultIPAMNamespace: {
+			normalizePrefix(defaultIPAMv4Cidr): nil,
+			normalizePrefix(defaultIPAMv6Cidr): nil,
+		},
+	}
+	usedAddresses := map[uuid.UUID]map[string][]uuid.UUID{}
+	use := func(used map[uuid.UUID]map[string][]uuid.UUID, namespace uuid.UUID, lease string, deviceID *uuid.UUID) {
+		if used[namespace] == nil {
+			used[namespace] = map[string][]uuid.UUID{}
+		}
+		if deviceID == nil {
+			if _, ok := used[namespace][lease]; !ok {
+				used[namespace][lease] = nil
+			}
+			return
+		}
+		used[namespace][lease] = append(used[namespace][lease], *deviceID)
+	}
+
+	orgNamespaces := map[uuid.UUID]uuid.UUID{}
+	for _, org := range orgs {
+		namespace := defaultIPAMNamespace
+		if org.PrivateCidr {
+			namespace = org.ID
+		}
+		orgNamespaces[org.ID] = namespace
+		for _, cidr := range []string{org.IpCidr, org.IpCidrV6} {
+			if cidr != "" {
+				use(usedPrefixes, namespace, normalizePrefix(cidr), nil)
+			}
+		}
+	}
+
+	// devices created after the leases were listed may not show up in them.
+	listedDevices := map[uuid.UUID]bool{}
+	for i := range devices {
+		device := &devices[i]
+		namespace, ok := orgNamespaces[device.OrganizationID]
+		if !ok {
+			// the device of a deleted organization, the namespace of its leases is unknown.
+			continue
+		}
+		listedDevices[device.ID] = device.CreatedAt.Before(listedAt)
+		for _, address := range []string{device.TunnelIP, device.TunnelIpV6} {
+			if address != "" {
+				use(usedAddresses, namespace, normalizeAddress(address), &device.ID)
+			}
+		}
+		for _, prefix := range device.ChildPrefix {
+			if !util.IsDefaultIPRoute(prefix) {
+				use(usedPrefixes, namespace, normalizePrefix(prefix), &device.ID)
+			}
+		}
+	}
+
+	conflicts := func(used map[uuid.UUID]map[string][]uuid.UUID, kind string, leased func(namespace uuid.UUID, lease string) bool) {
+		for namespace, inUse := range used {
+			for lease, deviceIDs := range inUse {
+				if len(deviceIDs) > 1 {
+					report.Conflicts = append(report.Conflicts, IpamConflict{
+						Namespace: namespace,
+						Lease:     lease,
+						Devices:   deviceIDs,
+						Reason:    fmt.Sprintf("the %s is used by more than one device", kind),
+					})
+				}
+				var listed []uuid.UUID
+				for _, id := range deviceIDs {
+					if listedDevices[id] {
+						listed = append(listed, id)
+					}
+				}
+				if len(listed) > 0 && !leased(namespace, lease) {
+					report.Conflicts = append(report.Conflicts, IpamConflict{
+						Namespace: namespace,
+						Lease:     lease,
+						Devices:   listed,
+						Reason:    fmt.Sprintf("the %s is not leased from ipam", kind),
+					})
+				}
+			}
+		}
+	}
+	conflicts(usedPrefixes, "prefix", func(namespace uuid.UUID, prefix string) bool {
+		_, ok := leases[namespace][prefix]
+		return ok
+	})
+	if addressesListed {
+		conflicts(usedAddresses, "address", func(namespace uuid.UUID, address string) bool {
+			for _, addresses := range leases[namespace] {
+				for _, a := range addresses {
+					if normalizeAddress(a) == address {
+						return true
+					}
+				}
+			}
+			return false
+		})
+	}
+
+	for _, namespace := range sortedNamespaces(leases) {
+		for _, prefix := range sortedPrefixes(leases[namespace]) {
+			if !created(namespace, prefix) {
+				continue
+			}
+			remaining := 0
+			for _, address := range leases[namespace][prefix] {
+				if _, ok := usedAddresses[namespace][normalizeAddress(address)]; ok {
+					remaining++
+					continue
+				}
+				lease := IpamLease{Namespace: namespace, Cidr: prefix, Address: address}
+				if !options.DryRun {
+					if err := api.ipam.ReleaseToPool(ctx, namespace, address, prefix); err != nil {
+						report.Errors = append(report.Errors, fmt.Sprintf("failed to release address %s of prefix %s in namespace %s: %v", address, prefix, namespace, err))
+						remaining++
+						continue
+					}
+				}
+				report.ReleasedAddresses = append(report.ReleasedAddresses, lease)
+			}
+
+			if _, ok := usedPrefixes[namespace][prefix]; ok || remaining > 0 {
+				continue
+			}
+			lease := IpamLease{Namespace: namespace, Cidr: prefix}
+			if !options.DryRun {
+				if err := api.ipam.ReleasePrefix(ctx, namespace, prefix); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("failed to release prefix %s in namespace %s: %v", prefix, namespace, err))
+					continue
+				}
+			}
+			report.ReleasedPrefixes = append(report.ReleasedPrefixes, lease)
+		}
+	}
+
+	sort.Slice(report.Conflicts, func(i, j int) bool {
+		if report.Conflicts[i].Namespace != report.Conflicts[j].Namespace {
+			return report.Conflicts[i].Namespace.String() < report.Conflicts[j].Namespace.String()
+		}
+		if report.Conflicts[i].Lease != report.Conflicts[j].Lease {
+			return report.Conflicts[i].Lease < report.Conflicts[j].Lease
+		}
+		return report.Conflicts[i].Reason < report.Conflicts[j].Reason
+	})
+	return report, nil
+}
+
+func normalizePrefix(prefix string) string {
+	if p, err := netip.ParsePrefix(prefix); err == nil {
+		return p.Masked().String()
+	}
+	return prefix
+}
+
+func normalizeAddress(address string) string {
+	if a, err := netip.ParseAddr(address); err == nil {
+		return a.String()
+	}
+	return address
+}
+
+func sortedNamespaces(leases map[uuid.UUID]ipamLeases) []uuid.UUID {
+	namespaces := make([]uuid.UUID, 0, len(leases))
+	for namespace := range leases {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].String() < namespaces[j].String()
+	})
+	return namespaces
+}
+
+func sortedPrefixes(leases ipamLeases) []string {
+	prefixes := make([]string, 0, len(leases))
+	for prefix := range leases {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/fflags"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/signalbus"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+func (suite *HandlerTestSuite) TestIpamGC() {
+	require := suite.Require()
+	ctx := context.Background()
+
+	suite.api.db.Exec("DELETE FROM ipam_addresses")
+	suite.api.db.Exec("DELETE FROM ipam_prefixes")
+	embedded := ipam.NewEmbeddedIPAM(suite.logger, suite.api.db)
+	signalBus := signalbus.NewSignalBus()
+	api, err := NewAPI(ctx, suite.logger, suite.api.db, embedded, fflags.NewFFlags(suite.logger, suite.api.db, signalBus), inmem.New(), signalBus)
+	require.NoError(err)
+
+	require.NoError(embedded.AssignPrefix(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr))
+	require.NoError(embedded.AssignPrefix(ctx, defaultIPAMNamespace, "172.16.0.0/24"))
+	require.NoError(embedded.AssignPrefix(ctx, defaultIPAMNamespace, "10.99.0.0/24"))
+	// the leases nexodus did not create are left alone.
+	require.NoError(embedded.AssignPrefix(ctx, defaultIPAMNamespace, "10.98.0.0/24"))
+	foreign := uuid.New()
+	require.NoError(embedded.CreateNamespace(ctx, foreign))
+	require.NoError(embedded.AssignPrefix(ctx, foreign, "10.97.0.0/24"))
+
+	used, err := embedded.AssignFromPool(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr)
+	require.NoError(err)
+	orphan, err := embedded.AssignFromPool(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr)
+	require.NoError(err)
+
+	devices := []models.Device{
+		{OrganizationID: suite.testOrganizationID, PublicKey: "pubkeyA", TunnelIP: used, ChildPrefix: []string{"172.16.0.0/24"}},
+		{OrganizationID: suite.testOrganizationID, PublicKey: "pubkeyB", ChildPrefix: []string{"172.16.0.0/24", "0.0.0.0/0"}},
+		{OrganizationID: suite.testOrganizationID, PublicKey: "pubkeyC", TunnelIP: "100.64.0.200"},
+	}
+	for i := range devices {
+		require.NoError(suite.api.db.Create(&devices[i]).Error)
+	}
+	deleted := models.Device{OrganizationID: suite.testOrganizationID, PublicKey: "pubkeyD", ChildPrefix: []string{"10.99.0.0/24"}}
+	require.NoError(suite.api.db.Create(&deleted).Error)
+	require.NoError(suite.api.db.Delete(&deleted).Error)
+
+	report, err := api.IpamGC(ctx, IpamGCOptions{DryRun: true})
+	require.NoError(err)
+	require.True(report.DryRun)
+	require.Equal([]IpamLease{{Namespace: defaultIPAMNamespace, Cidr: defaultIPAMv4Cidr, Address: orphan}}, report.ReleasedAddresses)
+	require.Equal([]IpamLease{{Namespace: defaultIPAMNamespace, Cidr: "10.99.0.0/24"}}, report.ReleasedPrefixes)
+	require.Len(report.Conflicts, 2)
+	require.Equal(IpamConflict{
+		Namespace: defaultIPAMNamespace,
+		Lease:     "100.64.0.200",
+		Devices:   []uuid.UUID{devices[2].ID},
+		Reason:    "the address is not leased from ipam",
+	}, report.Conflicts[0])
+	require.Equal("172.16.0.0/24", report.Conflicts[1].Lease)
+	require.Equal("the prefix is used by more than one device", report.Conflicts[1].Reason)
+	require.ElementsMatch(report.Conflicts[1].Devices, []uuid.UUID{devices[0].ID, devices[1].ID})
+	require.Empty(report.Errors)
+
+	// a dry run releases nothing.
+	addresses, err := embedded.ListAddresses(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr)
+	require.NoError(err)
+	require.Contains(addresses, orphan)
+
+	report, err = api.IpamGC(ctx, IpamGCOptions{})
+	require.NoError(err)
+	require.False(report.DryRun)
+	require.Len(report.ReleasedAddresses, 1)
+	require.Len(report.ReleasedPrefixes, 1)
+	require.Empty(report.Errors)
+
+	addresses, err = embedded.ListAddresses(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr)
+	require.NoError(err)
+	require.Equal([]string{used}, addresses)
+	prefixes, err := embedded.ListPrefixes(ctx, defaultIPAMNamespace)
+	require.NoError(err)
+	require.ElementsMatch([]string{defaultIPAMv4Cidr, "172.16.0.0/24", "10.98.0.0/24"}, prefixes)
+	prefixes, err = embedded.ListPrefixes(ctx, foreign)
+	require.NoError(err)
+	require.Equal([]string{"10.97.0.0/24"}, prefixes)
+
+	// nothing is left to release.
+	report, err = api.IpamGC(ctx, IpamGCOptions{})
+	require.NoError(err)
+	require.Empty(report.ReleasedAddresses)
+	require.Empty(report.ReleasedPrefixes)
+}
+
+func (suite *HandlerTestSuite) TestIpamImport() {
+	require := suite.Require()
+	ctx := context.Background()
+
+	suite.api.db.Exec("DELETE FROM ipam_addresses")
+	suite.api.db.Exec("DELETE FROM ipam_prefixes")
+	embedded := ipam.NewEmbeddedIPAM(suite.logger, suite.api.db)
+	signalBus := signalbus.NewSignalBus()
+	api, err := NewAPI(ctx, suite.logger, suite.api.db, embedded, fflags.NewFFlags(suite.logger, suite.api.db, signalBus), inmem.New(), signalBus)
+	require.NoError(err)
+
+	device := models.Device{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "import-pubkey",
+		TunnelIP:       "100.64.0.20",
+		ChildPrefix:    []string{"172.16.40.0/24", "0.0.0.0/0"},
+	}
+	require.NoError(suite.api.db.Create(&device).Error)
+
+	// the embedded ipam would lease the address of the device again.
+	require.Error(api.CheckIpamImported(ctx))
+
+	require.NoError(api.ImportIpamLeases(ctx))
+	require.NoError(api.CheckIpamImported(ctx))
+	addresses, err := embedded.ListAddresses(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr)
+	require.NoError(err)
+	require.Contains(addresses, device.TunnelIP)
+	prefixes, err := embedded.ListPrefixes(ctx, defaultIPAMNamespace)
+	require.NoError(err)
+	require.Contains(prefixes, "172.16.40.0/24")
+
+	// the leases are only imported once.
+	require.Error(api.ImportIpamLeases(ctx))
+}
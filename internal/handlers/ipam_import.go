@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"gorm.io/gorm"
+)
+
+// CheckIpamImported fails when the embedded ipam backend has no leases while devices already
+// have addresses. That happens when the apiserver is switched from the remote backend without
+// running ImportIpamLeases, and the embedded ipam would lease the addresses of the devices again.
+func (api *API) CheckIpamImported(parent context.Context) error {
+	ctx, span := tracer.Start(parent, "CheckIpamImported")
+	defer span.End()
+
+	if !ipam.Transactional(api.ipam) {
+		return nil
+	}
+	empty, err := api.ipamEmpty(ctx)
+	if err != nil || !empty {
+		return err
+	}
+	var count int64
+	if res := api.db.WithContext(ctx).Model(&models.Device{}).
+		Where("tunnel_ip <> '' OR tunnel_ip_v6 <> ''").
+		Count(&count); res.Error != nil {
+		return res.Error
+	}
+	if count > 0 {
+		return fmt.Errorf("the embedded ipam has no leases but %d devices have addresses, run the ipam-import command first", count)
+	}
+	return nil
+}
+
+// ImportIpamLeases leases the cidrs of the organizations and the addresses and child prefixes of
+// the devices from the embedded ipam backend. It is run once when switching from the remote
+// backend, and fails if the embedded ipam already has leases.
+func (api *API) ImportIpamLeases(parent context.Context) error {
+	ctx, span := tracer.Start(parent, "ImportIpamLeases")
+	defer span.End()
+
+	if !ipam.Transactional(api.ipam) {
+		return fmt.Errorf("the leases can only be imported into the embedded ipam backend")
+	}
+	prefixes, addresses := 0, 0
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		ipamCtx := database.WithTx(ctx, tx)
+		empty, err := api.ipamEmpty(ipamCtx)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("the embedded ipam already has leases")
+		}
+
+		var orgs []models.Organization
+		if res := tx.Find(&orgs); res.Error != nil {
+			return res.Error
+		}
+		for _, org := range orgs {
+			namespace := defaultIPAMNamespace
+			if org.PrivateCidr {
+				namespace = org.ID
+			}
+			for _, cidr := range []string{org.IpCidr, org.IpCidrV6} {
+				if cidr == "" {
+					continue
+				}
+				if err := api.ipam.AssignPrefix(ipamCtx, namespace, cidr); err != nil {
+					return fmt.Errorf("failed to import the cidr %s of organization %s: %w", cidr, org.ID, err)
+				}
+				prefixes++
+			}
+
+			var devices []models.Device
+			if res := tx.Where("organization_id = ?", org.ID).Find(&devices); res.Error != nil {
+				return res.Error
+			}
+			for _, device := range devices {
+				for _, prefix := range device.ChildPrefix {
+					if util.IsDefaultIPRoute(prefix) {
+						continue
+					}
+					if err := api.ipam.AssignPrefix(ipamCtx, namespace, prefix); err != nil {
+						return fmt.Errorf("failed to import the child prefix %s of device %s: %w", prefix, device.ID, err)
+					}
+					prefixes++
+				}
+				for _, lease := range [][2]string{{device.TunnelIP, org.IpCidr}, {device.TunnelIpV6, org.IpCidrV6}} {
+					if lease[0] == "" {
+						continue
+					}
+					if _, err := api.ipam.AssignSpecificTunnelIP(ipamCtx, namespace, lease[1], lease[0]); err != nil {
+						return fmt.Errorf("failed to import the address %s of device %s: %w", lease[0], device.ID, err)
+					}
+					addresses++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	api.Logger(ctx).Infof("Imported [ %d ] prefixes and [ %d ] addresses into the embedded ipam", prefixes, addresses)
+	return nil
+}
+
+// ipamEmpty returns true if the ipam backend has no prefixes in any namespace.
+func (api *API) ipamEmpty(ctx context.Context) (bool, error) {
+	lister, ok := api.ipam.(ipam.Lister)
+	if !ok {
+		return false, ipam.ErrListingNotSupported
+	}
+	namespaces, err := lister.ListNamespaces(ctx)
+	if err != nil {
+		return false, err
+	}
+	return len(namespaces) == 0, nil
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "apiserver"
+
+var (
+	watchStreams = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "watch_streams",
+		Help:      "Number of open watch streams by resource.",
+	}, []string{"resource"})
+	invitationsAccepted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "invitations_accepted_total",
+		Help:      "Number of organization invitations accepted.",
+	})
+	deviceTombstonesCompacted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "device_tombstones_compacted_total",
+		Help:      "Number of deleted devices removed from the database by the device compactor.",
+	})
+)
+
+var (
+	organizationsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "organizations"),
+		"Number of organizations.",
+		nil, nil,
+	)
+	usersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "users"),
+		"Number of users.",
+		nil, nil,
+	)
+	devicesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "devices"),
+		"Number of devices by online state.",
+		[]string{"online"}, nil,
+	)
+	pendingInvitationsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "invitations_pending"),
+		"Number of invitations that have not been accepted and have not expired.",
+		nil, nil,
+	)
+	// The device count of every organization is reported as a histogram so that
+	// the series count does not grow with the number of organizations.
+	organizationDevicesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "organization", "devices"),
+		"Distribution of the number of devices per organization.",
+		nil, nil,
+	)
+	organizationDevicesBuckets = []float64{0, 1, 2, 5, 10, 25, 50, 100, 250, 500, 1000}
+)
+
+// metricsCollector queries the database on each scrape.
+type metricsCollector struct {
+	api     *API
+	timeout time.Duration
+}
+
+// MetricsCollector returns a prometheus collector for the resources stored in the database.
+func (api *API) MetricsCollector() prometheus.Collector {
+	return &metricsCollector{
+		api:     api,
+		timeout: 10 * time.Second,
+	}
+}
+
+func (m *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- organizationsDesc
+	ch <- usersDesc
+	ch <- devicesDesc
+	ch <- pendingInvitationsDesc
+	ch <- organizationDevicesDesc
+}
+
+func (m *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "CollectMetrics")
+	defer span.End()
+	db := m.api.db.WithContext(ctx)
+
+	var organizations int64
+	if res := db.Model(&models.Organization{}).Count(&organizations); res.Error != nil {
+		ch <- prometheus.NewInvalidMetric(organizationsDesc, res.Error)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(organizationsDesc, prometheus.GaugeValue, float64(organizations))
+
+	var users int64
+	if res := db.Model(&models.User{}).Count(&users); res.Error != nil {
+		ch <- prometheus.NewInvalidMetric(usersDesc, res.Error)
+	} else {
+		ch <- prometheus.MustNewConstMetric(usersDesc, prometheus.GaugeValue, float64(users))
+	}
+
+	var pending int64
+	if res := db.Model(&models.Invitation{}).Where("expiry > ?", time.Now()).Count(&pending); res.Error != nil {
+		ch <- prometheus.NewInvalidMetric(pendingInvitationsDesc, res.Error)
+	} else {
+		ch <- prometheus.MustNewConstMetric(pendingInvitationsDesc, prometheus.GaugeValue, float64(pending))
+	}
+
+	var counts []struct {
+		OrganizationID string
+		Online         bool
+		Count          int64
+	}
+	if res := db.Model(&models.Device{}).
+		Select("organization_id, online, count(*) as count").
+		Group("organization_id, online").
+		Scan(&counts); res.Error != nil {
+		ch <- prometheus.NewInvalidMetric(devicesDesc, res.Error)
+		return
+	}
+
+	var online, offline int64
+	perOrg := map[string]int64{}
+	for _, c := range counts {
+		if c.Online {
+			online += c.Count
+		} else {
+			offline += c.Count
+		}
+		perOrg[c.OrganizationID] += c.Count
+	}
+	ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(online), "true")
+	ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(offline), "false")
+
+	// organizations without devices do not show up in the group by.
+	withoutDevices := organizations - int64(len(perOrg))
+	if withoutDevices < 0 {
+		withoutDevices = 0
+	}
+	buckets := map[float64]uint64{}
+	for _, bound := range organizationDevicesBuckets {
+		buckets[bound] = uint64(withoutDevices)
+	}
+	for _, count := range perOrg {
+		for _, bound := range organizationDevicesBuckets {
+			if float64(count) <= bound {
+				buckets[bound]++
+			}
+		}
+	}
+	ch <- prometheus.MustNewConstHistogram(organizationDevicesDesc,
+		uint64(withoutDevices)+uint64(len(perOrg)), float64(online+offline), buckets)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func (suite *HandlerTestSuite) TestMetricsCollector() {
+	require := suite.Require()
+
+	var devices []models.Device
+	for _, key := range []string{"pubkeyA", "pubkeyB"} {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/", "/",
+			suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{
+				OrganizationID: suite.testOrganizationID,
+				PublicKey:      key,
+			})),
+		)
+		require.NoError(err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(err)
+		require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+		var device models.Device
+		require.NoError(json.Unmarshal(body, &device))
+		devices = append(devices, device)
+	}
+
+	_, res, err := suite.ServeRequest(
+		http.MethodPost, "/:id/heartbeat", fmt.Sprintf("/%s/heartbeat", devices[0].ID),
+		suite.api.HeartbeatDevice, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+
+	expected := `
+# HELP apiserver_devices Number of devices by online state.
+# TYPE apiserver_devices gauge
+apiserver_devices{online="false"} 1
+apiserver_devices{online="true"} 1
+`
+	require.NoError(testutil.CollectAndCompare(suite.api.MetricsCollector(), strings.NewReader(expected), "apiserver_devices"))
+	require.Greater(testutil.CollectAndCount(suite.api.MetricsCollector(), "apiserver_organization_devices"), 0)
+}
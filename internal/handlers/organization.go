@@ -1,16 +1,22 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/ipam"
 	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/signalbus"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
@@ -43,6 +49,7 @@ func (e errDuplicateOrganization) Error() string {
 // @Success      201  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.QuotaExceededError
 // @Failure		 405  {object}  models.BaseError
 // @Failure      409  {object}  models.ConflictsError
 // @Failure		 429  {object}  models.BaseError
@@ -51,14 +58,7 @@ func (e errDuplicateOrganization) Error() string {
 func (api *API) CreateOrganization(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "CreateOrganization")
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
-		return
-	}
-	allowForTests := c.GetString("nexodus.testCreateOrganization")
-	if (!multiOrganizationEnabled && allowForTests != "true") || allowForTests == "false" {
-		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError("multi-organization support is disabled"))
+	if !api.flagCheck(ctx, c, "multi-organization") {
 		return
 	}
 	userId := c.GetString(gin.AuthUserKey)
@@ -97,6 +97,17 @@ func (api *API) CreateOrganization(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("name"))
 		return
 	}
+	if request.DeviceExpirySeconds < 0 {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("device_expiry_seconds", "must not be negative"))
+		return
+	}
+	var err error
+	if request.DnsSuffix != "" {
+		if request.DnsSuffix, err = normalizeDnsSuffix(request.DnsSuffix); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("dns_suffix", err.Error()))
+			return
+		}
+	}
 
 	var org models.Organization
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
@@ -105,15 +116,22 @@ func (api *API) CreateOrganization(c *gin.Context) {
 			return errUserNotFound
 		}
 
+		if err := checkQuota(tx, &models.User{}, user.ID, "organizations_per_user", api.quotas.OrganizationsPerUser,
+			&models.Organization{}, "owner_id = ?", userId); err != nil {
+			return err
+		}
+
 		org = models.Organization{
-			Name:        request.Name,
-			OwnerID:     userId,
-			Description: request.Description,
-			PrivateCidr: request.PrivateCidr,
-			IpCidr:      request.IpCidr,
-			IpCidrV6:    request.IpCidrV6,
-			HubZone:     request.HubZone,
-			Users:       []*models.User{&user},
+			Name:                request.Name,
+			OwnerID:             userId,
+			Description:         request.Description,
+			PrivateCidr:         request.PrivateCidr,
+			IpCidr:              request.IpCidr,
+			IpCidrV6:            request.IpCidrV6,
+			HubZone:             request.HubZone,
+			Users:               []*models.User{&user},
+			DeviceExpirySeconds: request.DeviceExpirySeconds,
+			DnsSuffix:           request.DnsSuffix,
 		}
 
 		if res := tx.Create(&org); res.Error != nil {
@@ -123,20 +141,21 @@ func (api *API) CreateOrganization(c *gin.Context) {
 			return res.Error
 		}
 
+		ipamCtx := database.WithTx(ctx, tx)
 		ipamNamespace := defaultIPAMNamespace
 		if org.PrivateCidr {
 			ipamNamespace = org.ID
-			if err := api.ipam.CreateNamespace(ctx, ipamNamespace); err != nil {
+			if err := api.ipam.CreateNamespace(ipamCtx, ipamNamespace); err != nil {
 				return err
 			}
 		}
 
-		if err := api.ipam.AssignPrefix(ctx, ipamNamespace, request.IpCidr); err != nil {
-			return err
+		if err := api.ipam.AssignPrefix(ipamCtx, ipamNamespace, request.IpCidr); err != nil {
+			return ipamCidrError("cidr", request.IpCidr, err)
 		}
 
-		if err := api.ipam.AssignPrefix(ctx, ipamNamespace, request.IpCidrV6); err != nil {
-			return err
+		if err := api.ipam.AssignPrefix(ipamCtx, ipamNamespace, request.IpCidrV6); err != nil {
+			return ipamCidrError("cidr_v6", request.IpCidrV6, err)
 		}
 
 		// Create a default security group for the organization
@@ -157,10 +176,16 @@ func (api *API) CreateOrganization(c *gin.Context) {
 
 	if err != nil {
 		var duplicate errDuplicateOrganization
+		var quota errQuotaExceeded
+		var invalid errInvalidCidr
 		if errors.Is(err, errUserNotFound) {
 			c.JSON(http.StatusNotFound, models.NewApiInternalError(err))
 		} else if errors.As(err, &duplicate) {
 			c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+		} else if errors.As(err, &quota) {
+			c.JSON(http.StatusForbidden, models.NewQuotaExceededError(quota.quota, quota.limit))
+		} else if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.field, invalid.reason))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
@@ -191,6 +216,30 @@ func (api *API) OrganizationIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.D
 	}
 }
 
+// OrganizationRole returns the role of a user in an organization, owner or member, or an empty role
+// when the user is not a member of the organization.
+func (api *API) OrganizationRole(ctx context.Context, userID string, orgID uuid.UUID) (string, error) {
+	var org models.Organization
+	if res := api.db.WithContext(ctx).Limit(1).Find(&org, "id = ?", orgID); res.Error != nil {
+		return "", res.Error
+	} else if res.RowsAffected == 0 {
+		return "", nil
+	}
+	if org.OwnerID == userID {
+		return "owner", nil
+	}
+	var count int64
+	if res := api.db.WithContext(ctx).Table("user_organizations").
+		Where("user_id = ? AND organization_id = ?", userID, orgID).
+		Count(&count); res.Error != nil {
+		return "", res.Error
+	}
+	if count > 0 {
+		return "member", nil
+	}
+	return "", nil
+}
+
 // ListOrganizations lists all Organizations
 // @Summary      List Organizations
 // @Description  Lists all Organizations
@@ -231,13 +280,13 @@ func (api *API) ListOrganizations(c *gin.Context) {
 // @Tags         Organizations
 // @Accept       json
 // @Produce      json
-// @Param		 id   path      string true "Organization ID"
+// @Param		 organization   path      string true "Organization ID"
 // @Success      200  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Router       /api/organizations/{id} [get]
+// @Router       /api/organizations/{organization} [get]
 func (api *API) GetOrganizations(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "GetOrganizations",
 		trace.WithAttributes(
@@ -268,7 +317,7 @@ func (api *API) GetOrganizations(c *gin.Context) {
 
 // ListDevicesInOrganization lists all devices in an Organization
 // @Summary      List Devices
-// @Description  Lists all devices for this Organization
+// @Description  Lists all devices for this Organization, followed by the devices imported from the organizations it is peered with
 // @Id           ListDevicesInOrganization
 // @Tags         Devices
 // @Accept       json
@@ -278,6 +327,7 @@ func (api *API) GetOrganizations(c *gin.Context) {
 // @Success      200  {object}  []models.Device
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
+// @Failure		 410  {object}  models.ExpiredError
 // @Failure		 429  {object}  models.BaseError
 // @Failure		 500  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/devices [get]
@@ -316,6 +366,19 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 	if v := c.Query("gt_revision"); v != "" {
 		gtRevision, _ = strconv.ParseUint(v, 10, 0)
 	}
+	if gtRevision != 0 {
+		// the tombstones of the deleted devices may have been removed since that revision.
+		var compaction models.DeviceCompaction
+		result = api.db.WithContext(ctx).Limit(1).Find(&compaction, "organization_id = ?", k.String())
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+			return
+		}
+		if gtRevision < compaction.Revision {
+			c.JSON(http.StatusGone, models.NewExpiredError(compaction.Revision))
+			return
+		}
+	}
 
 	includeDeleted := false
 
@@ -347,10 +410,79 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 		includeDeleted = true
 		sub := api.signalBus.Subscribe(fmt.Sprintf("/devices/org=%s", k.String()))
 		defer sub.Close()
+		// the devices imported from peered organizations change when their devices do.
+		peerSubs := map[uuid.UUID]*signalbus.Subscription{}
+		defer func() {
+			for _, peerSub := range peerSubs {
+				peerSub.Close()
+			}
+		}()
+		watchStreams.WithLabelValues("devices").Inc()
+		defer watchStreams.WithLabelValues("devices").Dec()
 
 		idx := 1
 		var list []*models.Device
 		bookmarkSent := false
+		// the imported devices don't get a new revision when a peering changes, so they are compared
+		// with the ones already sent to the client instead. They are only listed again when the
+		// stream is signaled, as the peerings and the devices of both organizations signal it.
+		imported := map[uuid.UUID]*models.Device{}
+		var importEvents []models.WatchEvent
+		importsStale := true
+		getImportEvents := func() ([]models.WatchEvent, error) {
+			if !importsStale {
+				return nil, nil
+			}
+			devices, peerOrgIDs, err := api.importedDevices(ctx, k)
+			if err != nil {
+				return nil, err
+			}
+			importsStale = false
+			current := map[uuid.UUID]bool{}
+			for _, peerOrgID := range peerOrgIDs {
+				current[peerOrgID] = true
+				if _, ok := peerSubs[peerOrgID]; !ok {
+					peerSubs[peerOrgID] = api.signalBus.Subscribe(fmt.Sprintf("/devices/org=%s", peerOrgID.String()))
+				}
+			}
+			for peerOrgID, peerSub := range peerSubs {
+				if !current[peerOrgID] {
+					peerSub.Close()
+					delete(peerSubs, peerOrgID)
+				}
+			}
+
+			var events []models.WatchEvent
+			seen := map[uuid.UUID]bool{}
+			for _, device := range devices {
+				seen[device.ID] = true
+				if sent, ok := imported[device.ID]; ok && reflect.DeepEqual(sent, device) {
+					continue
+				}
+				imported[device.ID] = device
+				events = append(events, models.WatchEvent{
+					Type:  "change",
+					Value: device,
+				})
+			}
+			for id, device := range imported {
+				if !seen[id] {
+					delete(imported, id)
+					events = append(events, models.WatchEvent{
+						Type:  "delete",
+						Value: device,
+					})
+				}
+			}
+			return events, nil
+		}
+		waitSubs := func() []*signalbus.Subscription {
+			subs := []*signalbus.Subscription{sub}
+			for _, peerSub := range peerSubs {
+				subs = append(subs, peerSub)
+			}
+			return subs
+		}
 
 		c.Header("Content-Type", "application/json;stream=watch")
 		c.Status(http.StatusOK)
@@ -379,6 +511,10 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 							Value: result,
 						}
 					}
+				} else if len(importEvents) > 0 {
+					result := importEvents[0]
+					importEvents = importEvents[1:]
+					return result
 				} else {
 
 					// get the next list...
@@ -390,24 +526,49 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 						}
 					}
 					idx = 0
+					importEvents, err = getImportEvents()
+					if err != nil {
+						return models.WatchEvent{
+							Type:  "error",
+							Value: err.Error(),
+						}
+					}
 
 					// did we run out of items to send?
-					if len(list) == 0 {
+					if len(list) == 0 && len(importEvents) == 0 {
 
 						// bookmark idea taken from: https://kubernetes.io/docs/reference/using-api/api-concepts/#watch-bookmarks
 						if !bookmarkSent {
 							bookmarkSent = true
 							return models.WatchEvent{
-								Type: "bookmark",
+								Type:  "bookmark",
+								Value: models.WatchBookmark{Revision: gtRevision},
 							}
 						}
 
 						// Wait for some items to come into the list
-						if waitForCancelOrTimeoutOrNotification(ctx, 30*time.Second, sub) {
-							// ctx was canceled... likely due to the http connection being closed by
-							// the client.  Signal the event stream is done.
-							return models.WatchEvent{
-								Type: "close",
+						for {
+							canceled, signaled := waitForCancelOrTimeoutOrNotification(ctx, 30*time.Second, waitSubs()...)
+							if canceled {
+								// ctx was canceled... likely due to the http connection being closed by
+								// the client.  Signal the event stream is done.
+								return models.WatchEvent{
+									Type: "close",
+								}
+							}
+							// the device signals carry the latest revision of the organization, no need
+							// to select the devices again if that revision was already sent.
+							if signaled == nil {
+								// nothing changed for a while, let the client know the revision it can
+								// resume from, this also keeps idle connections alive.
+								return models.WatchEvent{
+									Type:  "bookmark",
+									Value: models.WatchBookmark{Revision: gtRevision},
+								}
+							}
+							if signaled != sub || !revisionsSent(sub, gtRevision) {
+								importsStale = true
+								break
 							}
 						}
 					}
@@ -416,15 +577,46 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 		})
 
 	} else {
+		// the imported devices are listed after the devices of the organization, so the range
+		// applies to both of them.
+		pageSize, offset, rangeErr := query.GetRange()
+		query.Range = ""
 		devices, err := getList()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 			return
 		}
+		imported, _, err := api.importedDevices(ctx, k)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+			return
+		}
+		for _, device := range imported {
+			if gtRevision == 0 || device.Revision > gtRevision {
+				devices = append(devices, device)
+			}
+		}
+		totalCount := len(devices)
+		if rangeErr == nil {
+			start, end := offset, offset+pageSize
+			if start < 0 {
+				start = 0
+			}
+			if start > totalCount {
+				start = totalCount
+			}
+			if end < start {
+				end = start
+			}
+			if end > totalCount {
+				end = totalCount
+			}
+			devices = devices[start:end]
+		}
 
 		// For pagination
 		c.Header("Access-Control-Expose-Headers", TotalCountHeader)
-		c.Header(TotalCountHeader, strconv.Itoa(len(devices)))
+		c.Header(TotalCountHeader, strconv.Itoa(totalCount))
 		c.JSON(http.StatusOK, devices)
 	}
 
@@ -554,13 +746,13 @@ func (api *API) ListUsersInOrganization(c *gin.Context) {
 // @Tags         Organizations
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true "Organization ID"
+// @Param        organization  path      string  true "Organization ID"
 // @Success      204  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure      405  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
-// @Router       /api/organizations/{id} [delete]
+// @Router       /api/organizations/{organization} [delete]
 func (api *API) DeleteOrganization(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "DeleteOrganization",
 		trace.WithAttributes(
@@ -568,13 +760,7 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 			attribute.String("id", c.Param("id")),
 		))
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
-		return
-	}
-	if !multiOrganizationEnabled {
-		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError("multi-organization support is disabled"))
+	if !api.flagCheck(ctx, c, "multi-organization") {
 		return
 	}
 
@@ -613,10 +799,28 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 		return
 	}
 
-	if res := api.db.Select(clause.Associations).Delete(&org); res.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to delete the organization: %w", err)))
+	var peerings []models.OrganizationPeering
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Where("organization_id = ? OR peer_organization_id = ?", org.ID, org.ID).Find(&peerings); res.Error != nil {
+			return res.Error
+		}
+		if len(peerings) > 0 {
+			if res := tx.Delete(&peerings); res.Error != nil {
+				return res.Error
+			}
+		}
+		if res := tx.Select(clause.Associations).Delete(&org); res.Error != nil {
+			return fmt.Errorf("failed to delete the organization: %w", res.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
 	}
+	for _, peering := range peerings {
+		api.notifyPeering(peering)
+	}
 
 	orgCIDR := org.IpCidr
 
@@ -628,3 +832,420 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, org)
 }
+
+type errInvalidCidr struct {
+	field  string
+	reason string
+}
+
+func (e errInvalidCidr) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.field, e.reason)
+}
+
+// ipamCidrError maps the ipam error of an organization cidr assignment, a cidr that overlaps a child
+// prefix or the cidr of another organization of the ipam namespace is invalid.
+func ipamCidrError(field string, cidr string, err error) error {
+	if errors.Is(err, ipam.ErrPrefixOverlaps) {
+		return errInvalidCidr{field: field, reason: fmt.Sprintf("%s overlaps another prefix of the ipam namespace", cidr)}
+	}
+	return err
+}
+
+// normalizeDnsSuffix validates a mesh dns suffix and returns it in lower case without a trailing dot.
+func normalizeDnsSuffix(suffix string) (string, error) {
+	suffix = strings.TrimSuffix(strings.ToLower(suffix), ".")
+	// leave room for a 63 character hostname label in a 253 character name.
+	if suffix == "" || len(suffix) > 189 {
+		return "", fmt.Errorf("must be a domain name of at most 189 characters")
+	}
+	for _, label := range strings.Split(suffix, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", fmt.Errorf("%q is not a valid domain name label", label)
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return "", fmt.Errorf("%q is not a valid domain name label", label)
+			}
+		}
+	}
+	return suffix, nil
+}
+
+// parseOrganizationCidr parses a requested organization prefix, it must be of the same
+// address family as the current prefix.
+func parseOrganizationCidr(field string, cidr string, current string) (netip.Prefix, netip.Prefix, error) {
+	next, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, netip.Prefix{}, errInvalidCidr{field: field, reason: "must be a valid cidr"}
+	}
+	prev, err := netip.ParsePrefix(current)
+	if err != nil {
+		return netip.Prefix{}, netip.Prefix{}, fmt.Errorf("the organization %s %s is not valid: %w", field, current, err)
+	}
+	if next.Addr().Is4() != prev.Addr().Is4() {
+		return netip.Prefix{}, netip.Prefix{}, errInvalidCidr{field: field, reason: "must be of the same address family as the current cidr"}
+	}
+	return next.Masked(), prev.Masked(), nil
+}
+
+// UpdateOrganization updates an Organization
+// @Summary      Update Organizations
+// @Description  Updates an Organization by ID, the cidr and cidr_v6 of an organization with a private cidr can be grown in place. A dns_suffix of "." resets the mesh dns suffix to the default.
+// @Id  		 UpdateOrganization
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization  path      string  true "Organization ID"
+// @Param		 update body models.UpdateOrganization true "Organization Update"
+// @Success      200  {object}  models.Organization
+// @Failure		 401  {object}  models.BaseError
+// @Failure      400  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization} [patch]
+func (api *API) UpdateOrganization(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "UpdateOrganization",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+	orgID, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	var request models.UpdateOrganization
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.DnsSuffix != "" && request.DnsSuffix != "." {
+		if request.DnsSuffix, err = normalizeDnsSuffix(request.DnsSuffix); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("dns_suffix", err.Error()))
+			return
+		}
+	}
+	if request.DeviceExpirySeconds != nil && *request.DeviceExpirySeconds < 0 {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("device_expiry_seconds", "must not be negative"))
+		return
+	}
+
+	var org models.Organization
+	move := &prefixMove{}
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			First(&org, "id = ?", orgID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errOrgNotFound
+			}
+			return res.Error
+		}
+
+		if request.Description != "" {
+			org.Description = request.Description
+		}
+		if request.DnsSuffix == "." {
+			org.DnsSuffix = ""
+		} else if request.DnsSuffix != "" {
+			org.DnsSuffix = request.DnsSuffix
+		}
+		if request.DeviceExpirySeconds != nil {
+			org.DeviceExpirySeconds = *request.DeviceExpirySeconds
+		}
+
+		for _, change := range []struct {
+			field   string
+			request string
+			current *string
+			v6      bool
+		}{
+			{"cidr", request.IpCidr, &org.IpCidr, false},
+			{"cidr_v6", request.IpCidrV6, &org.IpCidrV6, true},
+		} {
+			if change.request == "" || change.request == *change.current {
+				continue
+			}
+			if !org.PrivateCidr {
+				return errInvalidCidr{field: change.field, reason: "can only be changed when private_cidr is enabled"}
+			}
+			next, prev, err := parseOrganizationCidr(change.field, change.request, *change.current)
+			if err != nil {
+				return err
+			}
+			if next == prev {
+				continue
+			}
+			if next.Bits() > prev.Bits() || !next.Contains(prev.Addr()) {
+				return errInvalidCidr{field: change.field, reason: fmt.Sprintf("must contain the current cidr %s, renumber the organization to move it to a new range", prev)}
+			}
+			if err := api.moveOrganizationPrefix(ctx, tx, org, prev.String(), next.String(), change.v6, true, move); err != nil {
+				return err
+			}
+			*change.current = next.String()
+		}
+
+		return tx.Save(&org).Error
+	})
+	api.finishPrefixMove(ctx, move, err)
+
+	if err != nil {
+		var invalid errInvalidCidr
+		if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.field, invalid.reason))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", org.ID.String()))
+	c.JSON(http.StatusOK, org)
+}
+
+// RenumberOrganization moves an Organization to new prefixes
+// @Summary      Renumber Organizations
+// @Description  Moves an Organization with a private cidr to a new cidr and cidr_v6, which must not overlap the current ones. The devices are assigned new tunnel addresses from the new ranges.
+// @Id  		 RenumberOrganization
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization  path      string  true "Organization ID"
+// @Param		 renumber body models.RenumberOrganization true "Organization Renumber"
+// @Success      200  {object}  models.Organization
+// @Failure		 401  {object}  models.BaseError
+// @Failure      400  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization}/renumber [post]
+func (api *API) RenumberOrganization(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "RenumberOrganization",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+	orgID, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	var request models.RenumberOrganization
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.IpCidr == "" && request.IpCidrV6 == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("cidr"))
+		return
+	}
+
+	var org models.Organization
+	move := &prefixMove{}
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			First(&org, "id = ?", orgID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errOrgNotFound
+			}
+			return res.Error
+		}
+		if !org.PrivateCidr {
+			return errInvalidCidr{field: "cidr", reason: "can only be changed when private_cidr is enabled"}
+		}
+
+		for _, change := range []struct {
+			field   string
+			request string
+			current *string
+			v6      bool
+		}{
+			{"cidr", request.IpCidr, &org.IpCidr, false},
+			{"cidr_v6", request.IpCidrV6, &org.IpCidrV6, true},
+		} {
+			if change.request == "" {
+				continue
+			}
+			next, prev, err := parseOrganizationCidr(change.field, change.request, *change.current)
+			if err != nil {
+				return err
+			}
+			if next.Overlaps(prev) {
+				return errInvalidCidr{field: change.field, reason: fmt.Sprintf("must not overlap the current cidr %s", prev)}
+			}
+			if err := api.moveOrganizationPrefix(ctx, tx, org, prev.String(), next.String(), change.v6, false, move); err != nil {
+				return err
+			}
+			*change.current = next.String()
+		}
+
+		api.logger.Infof("Renumbered organization [ %s ] ipam v4 [ %s ] ipam v6 [ %s ]", org.Name, org.IpCidr, org.IpCidrV6)
+		return tx.Save(&org).Error
+	})
+	api.finishPrefixMove(ctx, move, err)
+
+	if err != nil {
+		var invalid errInvalidCidr
+		if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.field, invalid.reason))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	// the devices pick up their new tunnel addresses from the watch stream.
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", org.ID.String()))
+	c.JSON(http.StatusOK, org)
+}
+
+// prefixMove records the ipam changes made by moveOrganizationPrefix. A remote ipam is not part of
+// the database transaction, so its changes are undone if the transaction is rolled back, and the
+// leases in the previous prefix are only released once it is committed.
+type prefixMove struct {
+	undo    []func(ctx context.Context) error
+	release []func(ctx context.Context) error
+}
+
+// finishPrefixMove releases the previous leases of a committed move, or undoes the ipam changes of a move
+// that was rolled back.
+func (api *API) finishPrefixMove(ctx context.Context, move *prefixMove, err error) {
+	if err != nil {
+		if ipam.Transactional(api.ipam) {
+			return
+		}
+		for i := len(move.undo) - 1; i >= 0; i-- {
+			if err := move.undo[i](ctx); err != nil {
+				api.Logger(ctx).Warnf("failed to undo an ipam change of the organization prefix: %v", err)
+			}
+		}
+		return
+	}
+	for _, release := range move.release {
+		if err := release(ctx); err != nil {
+			api.Logger(ctx).Warnf("failed to release a previous ipam lease of the organization: %v", err)
+		}
+	}
+}
+
+// moveOrganizationPrefix moves the tunnel addresses of the devices of an organization from the
+// from prefix to the to prefix. When keepAddresses is set the to prefix contains the from prefix,
+// and since ipam does not allow overlapping prefixes, the leases are released and then requested
+// again for the same addresses in the to prefix. Otherwise, the leases in the from prefix are
+// released by finishPrefixMove once the transaction is committed.
+func (api *API) moveOrganizationPrefix(ctx context.Context, tx *gorm.DB, org models.Organization, from, to string, v6 bool, keepAddresses bool, move *prefixMove) error {
+	ipamCtx := database.WithTx(ctx, tx)
+	namespace := org.ID
+
+	var devices []models.Device
+	if res := tx.Where("organization_id = ?", org.ID).Find(&devices); res.Error != nil {
+		return res.Error
+	}
+	tunnelIP := func(device *models.Device) (*string, *string) {
+		if v6 {
+			return &device.TunnelIpV6, &device.OrganizationPrefixV6
+		}
+		return &device.TunnelIP, &device.OrganizationPrefix
+	}
+
+	type lease struct {
+		address string
+		prefix  string
+	}
+	leases := make([]lease, len(devices))
+	for i := range devices {
+		address, prefix := tunnelIP(&devices[i])
+		leases[i] = lease{address: *address, prefix: *prefix}
+		if leases[i].prefix == "" {
+			leases[i].prefix = from
+		}
+	}
+
+	if keepAddresses {
+		for i, l := range leases {
+			if l.address == "" {
+				continue
+			}
+			if err := api.ipam.ReleaseToPool(ipamCtx, namespace, l.address, l.prefix); err != nil {
+				return fmt.Errorf("failed to release the address of device %s: %w", devices[i].ID, err)
+			}
+			l := l
+			move.undo = append(move.undo, func(ctx context.Context) error {
+				_, err := api.ipam.AssignSpecificTunnelIP(ctx, namespace, l.prefix, l.address)
+				return err
+			})
+		}
+		if err := api.ipam.ReleasePrefix(ipamCtx, namespace, from); err != nil {
+			return fmt.Errorf("failed to release ipam organization prefix: %w", err)
+		}
+		move.undo = append(move.undo, func(ctx context.Context) error {
+			return api.ipam.AssignPrefix(ctx, namespace, from)
+		})
+	} else {
+		move.release = append(move.release, func(ctx context.Context) error {
+			for _, l := range leases {
+				if l.address == "" {
+					continue
+				}
+				if err := api.ipam.ReleaseToPool(ctx, namespace, l.address, l.prefix); err != nil {
+					return err
+				}
+			}
+			return api.ipam.ReleasePrefix(ctx, namespace, from)
+		})
+	}
+
+	if err := api.ipam.AssignPrefix(ipamCtx, namespace, to); err != nil {
+		if errors.Is(err, ipam.ErrPrefixOverlaps) {
+			field := "cidr"
+			if v6 {
+				field = "cidr_v6"
+			}
+			return ipamCidrError(field, to, err)
+		}
+		return fmt.Errorf("failed to assign ipam organization prefix: %w", err)
+	}
+	move.undo = append(move.undo, func(ctx context.Context) error {
+		return api.ipam.ReleasePrefix(ctx, namespace, to)
+	})
+
+	for i := range devices {
+		device := &devices[i]
+		address, prefix := tunnelIP(device)
+		if *address != "" {
+			var err error
+			if keepAddresses {
+				*address, err = api.ipam.AssignSpecificTunnelIP(ipamCtx, namespace, to, *address)
+			} else {
+				*address, err = api.ipam.AssignFromPool(ipamCtx, namespace, to)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to request ipam address for device %s: %w", device.ID, err)
+			}
+			assigned := *address
+			move.undo = append(move.undo, func(ctx context.Context) error {
+				return api.ipam.ReleaseToPool(ctx, namespace, assigned, to)
+			})
+		}
+		*prefix = to
+	}
+
+	for i := range devices {
+		device := &devices[i]
+		var err error
+		device.AllowedIPs, err = getAllowedIPs(device.TunnelIP, device.TunnelIpV6, device.Relay)
+		if err != nil {
+			return err
+		}
+		if res := tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Save(device); res.Error != nil {
+			return res.Error
+		}
+	}
+	return nil
+}
@@ -0,0 +1,591 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var errPeeringNotFound = errors.New("organization peering not found")
+
+type errDuplicatePeering struct {
+	ID string
+}
+
+func (e errDuplicatePeering) Error() string {
+	return "organization peering already exists"
+}
+
+// peeringExports returns the devices and prefixes exported by an organization of the peering.
+func peeringExports(peering *models.OrganizationPeering, orgID uuid.UUID) (*pq.StringArray, *pq.StringArray) {
+	if orgID == peering.OrganizationID {
+		return &peering.ExportedDevices, &peering.ExportedPrefixes
+	}
+	return &peering.PeerExportedDevices, &peering.PeerExportedPrefixes
+}
+
+// peeringPeer returns the other organization of the peering.
+func peeringPeer(peering *models.OrganizationPeering, orgID uuid.UUID) uuid.UUID {
+	if orgID == peering.OrganizationID {
+		return peering.PeerOrganizationID
+	}
+	return peering.OrganizationID
+}
+
+// normalizePeeringExports validates the exports of a peering and returns them in canonical form.
+func normalizePeeringExports(devices []string, prefixes []string) ([]string, []string, error) {
+	normalizedDevices := make([]string, 0, len(devices))
+	for _, device := range devices {
+		id, err := uuid.Parse(device)
+		if err != nil {
+			return nil, nil, errInvalidCidr{field: "exported_devices", reason: fmt.Sprintf("%s is not a valid device id", device)}
+		}
+		normalizedDevices = append(normalizedDevices, id.String())
+	}
+	normalizedPrefixes := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		p, err := netip.ParsePrefix(prefix)
+		if err != nil || !util.IsValidPrefix(prefix) {
+			return nil, nil, errInvalidCidr{field: "exported_prefixes", reason: fmt.Sprintf("%s is not a valid cidr", prefix)}
+		}
+		if p.Bits() == 0 {
+			return nil, nil, errInvalidCidr{field: "exported_prefixes", reason: "default routes can't be exported"}
+		}
+		normalizedPrefixes = append(normalizedPrefixes, p.Masked().String())
+	}
+	return normalizedDevices, normalizedPrefixes, nil
+}
+
+// exportDevices returns the devices exported by id or by prefix. Relays are never exported, as they
+// only forward traffic within their organization, and the child prefixes of devices exported by
+// prefix are limited to the ones within the exported prefixes.
+func exportDevices(devices []models.Device, ids []string, prefixes []string) []models.Device {
+	exportedIDs := map[string]bool{}
+	for _, id := range ids {
+		exportedIDs[id] = true
+	}
+	var exportedPrefixes []netip.Prefix
+	for _, prefix := range prefixes {
+		if p, err := netip.ParsePrefix(prefix); err == nil {
+			exportedPrefixes = append(exportedPrefixes, p.Masked())
+		}
+	}
+	exported := func(prefix netip.Prefix) bool {
+		for _, p := range exportedPrefixes {
+			if p.Bits() <= prefix.Bits() && p.Contains(prefix.Addr()) {
+				return true
+			}
+		}
+		return false
+	}
+	filter := func(childPrefixes []string, byID bool) pq.StringArray {
+		var result pq.StringArray
+		for _, prefix := range childPrefixes {
+			if util.IsDefaultIPRoute(prefix) {
+				continue
+			}
+			p, err := netip.ParsePrefix(prefix)
+			if err != nil {
+				continue
+			}
+			if byID || exported(p.Masked()) {
+				result = append(result, prefix)
+			}
+		}
+		return result
+	}
+
+	var result []models.Device
+	for _, device := range devices {
+		if device.Relay {
+			continue
+		}
+		byID := exportedIDs[device.ID.String()]
+		childPrefix := filter(device.ChildPrefix, byID)
+		byPrefix := len(childPrefix) > 0
+		for _, ip := range []string{device.TunnelIP, device.TunnelIpV6} {
+			if addr, err := netip.ParseAddr(ip); err == nil && exported(netip.PrefixFrom(addr, addr.BitLen())) {
+				byPrefix = true
+			}
+		}
+		if !byID && !byPrefix {
+			continue
+		}
+		device.ChildPrefix = childPrefix
+		device.ActiveChildPrefix = filter(device.ActiveChildPrefix, byID)
+		result = append(result, device)
+	}
+	return result
+}
+
+// devicePrefixes returns the tunnel addresses and child prefixes of devices, other than default routes.
+func devicePrefixes(devices ...models.Device) []netip.Prefix {
+	var result []netip.Prefix
+	for _, device := range devices {
+		for _, ip := range []string{device.TunnelIP, device.TunnelIpV6} {
+			if addr, err := netip.ParseAddr(ip); err == nil {
+				result = append(result, netip.PrefixFrom(addr, addr.BitLen()))
+			}
+		}
+		for _, prefix := range device.ChildPrefix {
+			if util.IsDefaultIPRoute(prefix) {
+				continue
+			}
+			if p, err := netip.ParsePrefix(prefix); err == nil {
+				result = append(result, p.Masked())
+			}
+		}
+	}
+	return result
+}
+
+// overlappingPrefix returns the first prefix of a that overlaps one of b.
+func overlappingPrefix(a []netip.Prefix, b []netip.Prefix) (netip.Prefix, netip.Prefix, bool) {
+	for _, p := range a {
+		for _, other := range b {
+			if p.Overlaps(other) {
+				return p, other, true
+			}
+		}
+	}
+	return netip.Prefix{}, netip.Prefix{}, false
+}
+
+// exportedDevices returns the devices an organization exports through a peering.
+func exportedDevices(tx *gorm.DB, peering *models.OrganizationPeering, orgID uuid.UUID) ([]models.Device, error) {
+	var devices []models.Device
+	if res := tx.Where("organization_id = ?", orgID).Find(&devices); res.Error != nil {
+		return nil, res.Error
+	}
+	ids, prefixes := peeringExports(peering, orgID)
+	return exportDevices(devices, *ids, *prefixes), nil
+}
+
+// validatePeering checks that the devices orgID exports through a peering belong to it. Once the
+// peering is approved, it also checks that the devices exported by each side do not overlap the
+// addresses and child prefixes of the devices of the other side. A conflict only names a prefix
+// of orgID, so that the devices of the other organization are not disclosed.
+func validatePeering(tx *gorm.DB, peering *models.OrganizationPeering, orgID uuid.UUID) error {
+	ids, _ := peeringExports(peering, orgID)
+	if len(*ids) > 0 {
+		var count int64
+		if res := tx.Model(&models.Device{}).
+			Where("organization_id = ? AND id IN ?", orgID, []string(*ids)).
+			Count(&count); res.Error != nil {
+			return res.Error
+		}
+		if count != int64(len(*ids)) {
+			return errInvalidCidr{field: "exported_devices", reason: "the devices must belong to the organization"}
+		}
+	}
+	if !peering.PeerApproved {
+		return nil
+	}
+
+	peerOrgID := peeringPeer(peering, orgID)
+	var own, peer []models.Device
+	if res := tx.Where("organization_id = ?", orgID).Find(&own); res.Error != nil {
+		return res.Error
+	}
+	if res := tx.Where("organization_id = ?", peerOrgID).Find(&peer); res.Error != nil {
+		return res.Error
+	}
+	ownIDs, ownPrefixes := peeringExports(peering, orgID)
+	if p, _, ok := overlappingPrefix(devicePrefixes(exportDevices(own, *ownIDs, *ownPrefixes)...), devicePrefixes(peer...)); ok {
+		return errPrefixConflict{prefix: p.String()}
+	}
+	peerIDs, peerPrefixes := peeringExports(peering, peerOrgID)
+	if _, p, ok := overlappingPrefix(devicePrefixes(exportDevices(peer, *peerIDs, *peerPrefixes)...), devicePrefixes(own...)); ok {
+		return errPrefixConflict{prefix: p.String()}
+	}
+	return nil
+}
+
+// importedDevices returns the devices exported to an organization by its approved peerings and the
+// organizations they come from. Devices that overlap the addresses of the organization, or of a
+// device imported before them, are left out.
+func (api *API) importedDevices(ctx context.Context, orgID uuid.UUID) ([]*models.Device, []uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "importedDevices")
+	defer span.End()
+
+	db := api.db.WithContext(ctx)
+	var peerings []models.OrganizationPeering
+	if res := db.Where("peer_approved = ? AND (organization_id = ? OR peer_organization_id = ?)", true, orgID, orgID).
+		Order("created_at").
+		Find(&peerings); res.Error != nil {
+		return nil, nil, res.Error
+	}
+	if len(peerings) == 0 {
+		return nil, nil, nil
+	}
+
+	var own []models.Device
+	if res := db.Where("organization_id = ?", orgID).Find(&own); res.Error != nil {
+		return nil, nil, res.Error
+	}
+	taken := devicePrefixes(own...)
+
+	var result []*models.Device
+	var peerOrgIDs []uuid.UUID
+	for i := range peerings {
+		peerOrgID := peeringPeer(&peerings[i], orgID)
+		peerOrgIDs = append(peerOrgIDs, peerOrgID)
+		exported, err := exportedDevices(db, &peerings[i], peerOrgID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for j := range exported {
+			device := exported[j]
+			prefixes := devicePrefixes(device)
+			if p, other, ok := overlappingPrefix(prefixes, taken); ok {
+				api.Logger(ctx).Debugf("Not importing device [ %s ] into organization [ %s ], %s overlaps %s", device.ID, orgID, p, other)
+				continue
+			}
+			taken = append(taken, prefixes...)
+			result = append(result, &device)
+		}
+	}
+	return result, peerOrgIDs, nil
+}
+
+// notifyPeering signals the device watchers of both organizations of a peering.
+func (api *API) notifyPeering(peering models.OrganizationPeering) {
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", peering.OrganizationID.String()))
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", peering.PeerOrganizationID.String()))
+}
+
+func (api *API) handlePeeringError(c *gin.Context, err error) {
+	var invalid errInvalidCidr
+	var conflict errPrefixConflict
+	var duplicate errDuplicatePeering
+	if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+	} else if errors.Is(err, errPeeringNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("peering"))
+	} else if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.field, invalid.reason))
+	} else if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, models.NewPrefixConflictError(conflict.prefix, conflict.conflictsWith, conflict.deviceID))
+	} else if errors.As(err, &duplicate) {
+		c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+	} else {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+	}
+}
+
+// CreateOrganizationPeering requests a peering with another Organization
+// @Summary      Add Organization Peering
+// @Description  Requests a peering with another Organization and exports devices to it. The devices of each side are listed as peers of the other side once the owner of the peer organization approves the peering.
+// @Id           CreateOrganizationPeering
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param		 organization path   string true "Organization ID"
+// @Param        peering body models.AddOrganizationPeering true "Add Organization Peering"
+// @Success      201  {object}  models.OrganizationPeering
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization}/peerings [post]
+func (api *API) CreateOrganizationPeering(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateOrganizationPeering",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+
+	orgID, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	var request models.AddOrganizationPeering
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.PeerOrganizationID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("peer_organization_id"))
+		return
+	}
+	if request.PeerOrganizationID == orgID {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("peer_organization_id", "an organization can't peer with itself"))
+		return
+	}
+	devices, prefixes, err := normalizePeeringExports(request.ExportedDevices, request.ExportedPrefixes)
+	if err != nil {
+		api.handlePeeringError(c, err)
+		return
+	}
+
+	peering := models.OrganizationPeering{
+		OrganizationID:       orgID,
+		PeerOrganizationID:   request.PeerOrganizationID,
+		ExportedDevices:      devices,
+		ExportedPrefixes:     prefixes,
+		PeerExportedDevices:  pq.StringArray{},
+		PeerExportedPrefixes: pq.StringArray{},
+	}
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			First(&org, "id = ?", orgID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errOrgNotFound
+			}
+			return res.Error
+		}
+		// the peer organization is not looked up, so that a peering request does not tell whether
+		// it exists, a request to an organization that does not exist is never approved.
+		var existing models.OrganizationPeering
+		res := tx.Where("(organization_id = ? AND peer_organization_id = ?) OR (organization_id = ? AND peer_organization_id = ?)",
+			orgID, request.PeerOrganizationID, request.PeerOrganizationID, orgID).
+			First(&existing)
+		if res.Error == nil {
+			return errDuplicatePeering{ID: existing.ID.String()}
+		} else if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return res.Error
+		}
+
+		if err := validatePeering(tx, &peering, org.ID); err != nil {
+			return err
+		}
+		if res := tx.Create(&peering); res.Error != nil {
+			return res.Error
+		}
+		span.SetAttributes(attribute.String("id", peering.ID.String()))
+		api.logger.Infof("Organization [ %s ] requested a peering [ %s ] with organization [ %s ]", org.ID, peering.ID, request.PeerOrganizationID)
+		return nil
+	})
+	if err != nil {
+		api.handlePeeringError(c, err)
+		return
+	}
+
+	api.notifyPeering(peering)
+	c.JSON(http.StatusCreated, peering)
+}
+
+// ListOrganizationPeerings lists the peerings of an Organization
+// @Summary      List Organization Peerings
+// @Description  Lists the peerings requested by or with an Organization
+// @Id           ListOrganizationPeerings
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param		 organization path   string true "Organization ID"
+// @Success      200  {object}  []models.OrganizationPeering
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization}/peerings [get]
+func (api *API) ListOrganizationPeerings(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListOrganizationPeerings",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+
+	orgID, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	var org models.Organization
+	result := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+		}
+		return
+	}
+
+	peerings := make([]models.OrganizationPeering, 0)
+	if res := api.db.WithContext(ctx).
+		Where("organization_id = ? OR peer_organization_id = ?", org.ID, org.ID).
+		Order("created_at").
+		Find(&peerings); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, peerings)
+}
+
+// UpdateOrganizationPeering updates the side of a peering owned by an Organization
+// @Summary      Update Organization Peering
+// @Description  Updates the devices and prefixes an Organization exports through a peering. The owner of the peer organization approves the peering by setting approved.
+// @Id           UpdateOrganizationPeering
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param		 organization path   string true "Organization ID"
+// @Param		 id           path   string true "Peering ID"
+// @Param        update body models.UpdateOrganizationPeering true "Organization Peering Update"
+// @Success      200  {object}  models.OrganizationPeering
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.PrefixConflictError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization}/peerings/{id} [patch]
+func (api *API) UpdateOrganizationPeering(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "UpdateOrganizationPeering",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+			attribute.String("id", c.Param("id")),
+		))
+	defer span.End()
+
+	orgID, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	peeringID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	var request models.UpdateOrganizationPeering
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	devices, prefixes, err := normalizePeeringExports(request.ExportedDevices, request.ExportedPrefixes)
+	if err != nil {
+		api.handlePeeringError(c, err)
+		return
+	}
+
+	var peering models.OrganizationPeering
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			First(&org, "id = ?", orgID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errOrgNotFound
+			}
+			return res.Error
+		}
+		if res := tx.Where("organization_id = ? OR peer_organization_id = ?", org.ID, org.ID).
+			First(&peering, "id = ?", peeringID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errPeeringNotFound
+			}
+			return res.Error
+		}
+
+		currentDevices, currentPrefixes := peeringExports(&peering, org.ID)
+		if request.ExportedDevices != nil {
+			*currentDevices = devices
+		}
+		if request.ExportedPrefixes != nil {
+			*currentPrefixes = prefixes
+		}
+		if request.Approved {
+			if org.ID != peering.PeerOrganizationID {
+				return errInvalidCidr{field: "approved", reason: "the peering can only be approved by the peer organization"}
+			}
+			if !peering.PeerApproved {
+				api.logger.Infof("Organization [ %s ] approved the peering [ %s ] with organization [ %s ]", org.ID, peering.ID, peering.OrganizationID)
+			}
+			peering.PeerApproved = true
+		}
+
+		if err := validatePeering(tx, &peering, org.ID); err != nil {
+			return err
+		}
+		return tx.Save(&peering).Error
+	})
+	if err != nil {
+		api.handlePeeringError(c, err)
+		return
+	}
+
+	api.notifyPeering(peering)
+	c.JSON(http.StatusOK, peering)
+}
+
+// DeleteOrganizationPeering deletes a peering of an Organization
+// @Summary      Delete Organization Peering
+// @Description  Deletes a peering, either organization of the peering can delete it
+// @Id           DeleteOrganizationPeering
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param		 organization path   string true "Organization ID"
+// @Param		 id           path   string true "Peering ID"
+// @Success      200  {object}  models.OrganizationPeering
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization}/peerings/{id} [delete]
+func (api *API) DeleteOrganizationPeering(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteOrganizationPeering",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+			attribute.String("id", c.Param("id")),
+		))
+	defer span.End()
+
+	orgID, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	peeringID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var peering models.OrganizationPeering
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			First(&org, "id = ?", orgID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errOrgNotFound
+			}
+			return res.Error
+		}
+		if res := tx.Where("organization_id = ? OR peer_organization_id = ?", org.ID, org.ID).
+			First(&peering, "id = ?", peeringID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errPeeringNotFound
+			}
+			return res.Error
+		}
+		return tx.Delete(&peering).Error
+	})
+	if err != nil {
+		api.handlePeeringError(c, err)
+		return
+	}
+
+	api.notifyPeering(peering)
+	c.JSON(http.StatusOK, peering)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestOrganizationPeering() {
+	require := suite.Require()
+
+	as := func(userID string, handler func(*gin.Context)) func(*gin.Context) {
+		return func(c *gin.Context) {
+			c.Set(gin.AuthUserKey, userID)
+			handler(c)
+		}
+	}
+	serve := func(method, path, uri string, handler func(*gin.Context), request interface{}, code int) []byte {
+		var reqBody io.Reader
+		if request != nil {
+			reqBody = bytes.NewBuffer(suite.jsonMarshal(request))
+		}
+		_, res, err := suite.ServeRequest(method, path, uri, handler, reqBody)
+		require.NoError(err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(err)
+		require.Equal(code, res.Code, "HTTP error: %s", string(body))
+		return body
+	}
+	createDevice := func(userID string, orgID uuid.UUID, key string, childPrefix []string) models.Device {
+		body := serve(http.MethodPost, "/", "/", as(userID, suite.api.CreateDevice), models.AddDevice{
+			OrganizationID: orgID,
+			PublicKey:      key,
+			ChildPrefix:    childPrefix,
+		}, http.StatusCreated)
+		var device models.Device
+		require.NoError(json.Unmarshal(body, &device))
+		return device
+	}
+	listDevices := func(userID string, orgID uuid.UUID) []uuid.UUID {
+		body := serve(http.MethodGet, "/:organization/devices", fmt.Sprintf("/%s/devices", orgID),
+			as(userID, suite.api.ListDevicesInOrganization), nil, http.StatusOK)
+		var devices []models.Device
+		require.NoError(json.Unmarshal(body, &devices))
+		var ids []uuid.UUID
+		for _, d := range devices {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+	updatePeering := func(userID string, orgID uuid.UUID, id uuid.UUID, request models.UpdateOrganizationPeering, code int) {
+		serve(http.MethodPatch, "/:organization/peerings/:id", fmt.Sprintf("/%s/peerings/%s", orgID, id),
+			as(userID, suite.api.UpdateOrganizationPeering), request, code)
+	}
+
+	exported := createDevice(TestUserID, suite.testOrganizationID, "peering-pubkey-a1", []string{"172.16.50.0/24"})
+	private := createDevice(TestUserID, suite.testOrganizationID, "peering-pubkey-a2", nil)
+	peer := createDevice(TestUser2ID, suite.testUser2OrgID, "peering-pubkey-b1", nil)
+
+	body := serve(http.MethodPost, "/:organization/peerings", fmt.Sprintf("/%s/peerings", suite.testOrganizationID),
+		as(TestUserID, suite.api.CreateOrganizationPeering), models.AddOrganizationPeering{
+			PeerOrganizationID: suite.testUser2OrgID,
+			ExportedDevices:    []string{exported.ID.String()},
+		}, http.StatusCreated)
+	var peering models.OrganizationPeering
+	require.NoError(json.Unmarshal(body, &peering))
+	require.False(peering.PeerApproved)
+
+	// only one peering between two organizations.
+	serve(http.MethodPost, "/:organization/peerings", fmt.Sprintf("/%s/peerings", suite.testUser2OrgID),
+		as(TestUser2ID, suite.api.CreateOrganizationPeering), models.AddOrganizationPeering{
+			PeerOrganizationID: suite.testOrganizationID,
+		}, http.StatusConflict)
+
+	// nothing is imported until the peer organization approves.
+	require.ElementsMatch([]uuid.UUID{peer.ID}, listDevices(TestUser2ID, suite.testUser2OrgID))
+	updatePeering(TestUserID, suite.testOrganizationID, peering.ID, models.UpdateOrganizationPeering{Approved: true}, http.StatusBadRequest)
+	updatePeering(TestUser2ID, suite.testUser2OrgID, peering.ID, models.UpdateOrganizationPeering{
+		ExportedPrefixes: []string{peer.TunnelIP + "/32"},
+		Approved:         true,
+	}, http.StatusOK)
+
+	require.ElementsMatch([]uuid.UUID{peer.ID, exported.ID}, listDevices(TestUser2ID, suite.testUser2OrgID))
+	require.ElementsMatch([]uuid.UUID{exported.ID, private.ID, peer.ID}, listDevices(TestUserID, suite.testOrganizationID))
+
+	// devices that overlap the addresses of the peer organization can't be exported.
+	conflicting := models.Device{
+		OrganizationID: suite.testUser2OrgID,
+		UserID:         TestUser2ID,
+		PublicKey:      "peering-pubkey-b2",
+		TunnelIP:       private.TunnelIP,
+	}
+	require.NoError(suite.api.db.Create(&conflicting).Error)
+	body = serve(http.MethodPatch, "/:organization/peerings/:id", fmt.Sprintf("/%s/peerings/%s", suite.testOrganizationID, peering.ID),
+		as(TestUserID, suite.api.UpdateOrganizationPeering), models.UpdateOrganizationPeering{
+			ExportedDevices: []string{exported.ID.String(), private.ID.String()},
+		}, http.StatusConflict)
+	var conflict models.PrefixConflictError
+	require.NoError(json.Unmarshal(body, &conflict))
+	require.Equal(private.TunnelIP+"/32", conflict.Prefix)
+	require.Empty(conflict.ConflictsWith)
+
+	// the range applies to the devices of the organization and the imported devices.
+	_, res, err := suite.ServeRequest(http.MethodGet, "/:organization/devices", fmt.Sprintf("/%s/devices?range=[1,5]", suite.testUser2OrgID),
+		as(TestUser2ID, suite.api.ListDevicesInOrganization), nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+	var devices []models.Device
+	require.NoError(json.NewDecoder(res.Body).Decode(&devices))
+	require.Len(devices, 2)
+	require.Equal("3", res.Header().Get(TotalCountHeader))
+
+	serve(http.MethodDelete, "/:organization/peerings/:id", fmt.Sprintf("/%s/peerings/%s", suite.testUser2OrgID, peering.ID),
+		as(TestUser2ID, suite.api.DeleteOrganizationPeering), nil, http.StatusOK)
+	require.ElementsMatch([]uuid.UUID{peer.ID, conflicting.ID}, listDevices(TestUser2ID, suite.testUser2OrgID))
+	require.ElementsMatch([]uuid.UUID{exported.ID, private.ID}, listDevices(TestUserID, suite.testOrganizationID))
+
+	// the overlaps are checked when the peering is approved, the peer organization only learns
+	// about its own conflicting prefix.
+	body = serve(http.MethodPost, "/:organization/peerings", fmt.Sprintf("/%s/peerings", suite.testOrganizationID),
+		as(TestUserID, suite.api.CreateOrganizationPeering), models.AddOrganizationPeering{
+			PeerOrganizationID: suite.testUser2OrgID,
+			ExportedDevices:    []string{private.ID.String()},
+		}, http.StatusCreated)
+	require.NoError(json.Unmarshal(body, &peering))
+	body = serve(http.MethodPatch, "/:organization/peerings/:id", fmt.Sprintf("/%s/peerings/%s", suite.testUser2OrgID, peering.ID),
+		as(TestUser2ID, suite.api.UpdateOrganizationPeering), models.UpdateOrganizationPeering{Approved: true}, http.StatusConflict)
+	require.NoError(json.Unmarshal(body, &conflict))
+	require.Equal(conflicting.TunnelIP+"/32", conflict.Prefix)
+	require.Empty(conflict.ConflictsWith)
+
+	// a peering request does not tell whether the peer organization exists.
+	serve(http.MethodPost, "/:organization/peerings", fmt.Sprintf("/%s/peerings", suite.testUser2OrgID),
+		as(TestUser2ID, suite.api.CreateOrganizationPeering), models.AddOrganizationPeering{
+			PeerOrganizationID: uuid.New(),
+		}, http.StatusCreated)
+}
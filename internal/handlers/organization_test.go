@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
 
-	"github.com/gin-gonic/gin"
 	"github.com/nexodus-io/nexodus/internal/models"
 )
 
@@ -59,20 +62,18 @@ func (suite *HandlerTestSuite) TestListOrganizations() {
 	}
 
 	{
+		suite.setFeatureFlag("multi-organization", false)
 		resBody, err := json.Marshal(organizationDenied)
 		assert.NoError(err)
 		_, res, err := suite.ServeRequest(
 			http.MethodPost,
 			"/", "/",
-			func(c *gin.Context) {
-				c.Set("nexodus.testCreateOrganization", "false")
-				suite.api.CreateOrganization(c)
-			},
-
+			suite.api.CreateOrganization,
 			bytes.NewBuffer(resBody),
 		)
 		assert.NoError(err)
 		assert.Equal(http.StatusMethodNotAllowed, res.Code)
+		suite.setFeatureFlag("multi-organization", true)
 	}
 
 	{
@@ -197,3 +198,240 @@ func (suite *HandlerTestSuite) TestListOrganizations() {
 
 	}
 }
+
+func (suite *HandlerTestSuite) TestUpdateAndRenumberOrganization() {
+	require := suite.Require()
+
+	_, res, err := suite.ServeRequest(
+		http.MethodPost, "/", "/",
+		suite.api.CreateOrganization,
+		bytes.NewBuffer(suite.jsonMarshal(models.AddOrganization{
+			Name:        "organization-renumber",
+			PrivateCidr: true,
+			IpCidr:      "10.20.0.0/24",
+			IpCidrV6:    "fc00:4000::/64",
+		})),
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+	var org models.OrganizationJSON
+	require.NoError(json.Unmarshal(body, &org))
+
+	_, res, err = suite.ServeRequest(
+		http.MethodPost, "/", "/",
+		suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{
+			OrganizationID: org.ID,
+			PublicKey:      "renumber-pubkey",
+		})),
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+	var device models.Device
+	require.NoError(json.Unmarshal(body, &device))
+
+	getDevice := func() models.Device {
+		var d models.Device
+		require.NoError(suite.api.db.First(&d, "id = ?", device.ID).Error)
+		return d
+	}
+	updateOrg := func(update models.UpdateOrganization) (int, models.OrganizationJSON) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPatch, "/:organization", fmt.Sprintf("/%s", org.ID),
+			suite.api.UpdateOrganization, bytes.NewBuffer(suite.jsonMarshal(update)),
+		)
+		require.NoError(err)
+		var o models.OrganizationJSON
+		if res.Code == http.StatusOK {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &o))
+		}
+		return res.Code, o
+	}
+	renumberOrg := func(renumber models.RenumberOrganization) (int, models.OrganizationJSON) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/:organization/renumber", fmt.Sprintf("/%s/renumber", org.ID),
+			suite.api.RenumberOrganization, bytes.NewBuffer(suite.jsonMarshal(renumber)),
+		)
+		require.NoError(err)
+		var o models.OrganizationJSON
+		if res.Code == http.StatusOK {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &o))
+		}
+		return res.Code, o
+	}
+
+	// growing the cidr keeps the device addresses.
+	code, updated := updateOrg(models.UpdateOrganization{IpCidr: "10.20.0.0/23", Description: "grown"})
+	require.Equal(http.StatusOK, code)
+	require.Equal("10.20.0.0/23", updated.IpCidr)
+	require.Equal("grown", updated.Description)
+	d := getDevice()
+	require.Equal(device.TunnelIP, d.TunnelIP)
+	require.Equal("10.20.0.0/23", d.OrganizationPrefix)
+
+	// a failed update restores the ipam leases it released, a cidr that overlaps another prefix of
+	// the namespace is invalid.
+	ctx := context.Background()
+	require.NoError(suite.api.ipam.AssignPrefix(ctx, org.ID, "fc00:4000:0:1::/64"))
+	code, _ = updateOrg(models.UpdateOrganization{IpCidr: "10.20.0.0/22", IpCidrV6: "fc00:4000::/48"})
+	require.Equal(http.StatusBadRequest, code)
+	d = getDevice()
+	require.Equal("10.20.0.0/23", d.OrganizationPrefix)
+	_, err = suite.api.ipam.AssignSpecificTunnelIP(ctx, org.ID, "10.20.0.0/23", device.TunnelIP)
+	require.Error(err)
+	_, err = suite.api.ipam.AssignSpecificTunnelIP(ctx, org.ID, "fc00:4000::/64", device.TunnelIpV6)
+	require.Error(err)
+	require.NoError(suite.api.ipam.ReleasePrefix(ctx, org.ID, "fc00:4000:0:1::/64"))
+	code, updated = updateOrg(models.UpdateOrganization{IpCidr: "10.20.0.0/22", IpCidrV6: "fc00:4000::/48"})
+	require.Equal(http.StatusOK, code)
+	require.Equal("fc00:4000::/48", updated.IpCidrV6)
+	d = getDevice()
+	require.Equal(device.TunnelIP, d.TunnelIP)
+	require.Equal(device.TunnelIpV6, d.TunnelIpV6)
+
+	// the device expiry can be set and disabled again.
+	expiry := int64(3600)
+	code, updated = updateOrg(models.UpdateOrganization{DeviceExpirySeconds: &expiry})
+	require.Equal(http.StatusOK, code)
+	require.Equal(int64(3600), updated.DeviceExpirySeconds)
+	expiry = 0
+	code, updated = updateOrg(models.UpdateOrganization{DeviceExpirySeconds: &expiry})
+	require.Equal(http.StatusOK, code)
+	require.Equal(int64(0), updated.DeviceExpirySeconds)
+	expiry = -1
+	code, _ = updateOrg(models.UpdateOrganization{DeviceExpirySeconds: &expiry})
+	require.Equal(http.StatusBadRequest, code)
+
+	// a cidr that does not contain the current one needs a renumber.
+	code, _ = updateOrg(models.UpdateOrganization{IpCidr: "10.30.0.0/24"})
+	require.Equal(http.StatusBadRequest, code)
+	code, _ = updateOrg(models.UpdateOrganization{IpCidr: "fc00::/8"})
+	require.Equal(http.StatusBadRequest, code)
+
+	code, _ = renumberOrg(models.RenumberOrganization{IpCidr: "10.20.1.0/24"})
+	require.Equal(http.StatusBadRequest, code)
+
+	// the new cidr can't overlap a child prefix of the organization.
+	require.NoError(suite.api.ipam.AssignPrefix(ctx, org.ID, "10.40.1.0/24"))
+	_, res, err = suite.ServeRequest(
+		http.MethodPost, "/:organization/renumber", fmt.Sprintf("/%s/renumber", org.ID),
+		suite.api.RenumberOrganization, bytes.NewBuffer(suite.jsonMarshal(models.RenumberOrganization{IpCidr: "10.40.0.0/16"})),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusBadRequest, res.Code)
+	var validation models.ValidationError
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &validation))
+	require.Equal("cidr", validation.Field)
+	require.NoError(suite.api.ipam.ReleasePrefix(ctx, org.ID, "10.40.1.0/24"))
+	require.Equal("10.20.0.0/22", getDevice().OrganizationPrefix)
+
+	code, updated = renumberOrg(models.RenumberOrganization{IpCidr: "10.30.0.0/24", IpCidrV6: "fc00:5000::/64"})
+	require.Equal(http.StatusOK, code)
+	require.Equal("10.30.0.0/24", updated.IpCidr)
+	require.Equal("fc00:5000::/64", updated.IpCidrV6)
+	d = getDevice()
+	require.Equal("10.30.0.0/24", d.OrganizationPrefix)
+	require.Equal("fc00:5000::/64", d.OrganizationPrefixV6)
+	require.True(netip.MustParsePrefix("10.30.0.0/24").Contains(netip.MustParseAddr(d.TunnelIP)), d.TunnelIP)
+	require.True(netip.MustParsePrefix("fc00:5000::/64").Contains(netip.MustParseAddr(d.TunnelIpV6)), d.TunnelIpV6)
+	require.Contains(d.AllowedIPs, d.TunnelIP+"/32")
+
+	// the cidr of an organization without a private cidr can not change.
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch, "/:organization", fmt.Sprintf("/%s", suite.testOrganizationID),
+		suite.api.UpdateOrganization, bytes.NewBuffer(suite.jsonMarshal(models.UpdateOrganization{IpCidr: "100.64.0.0/9"})),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusBadRequest, res.Code)
+}
+
+func (suite *HandlerTestSuite) TestOrganizationDnsSuffix() {
+	require := suite.Require()
+
+	createOrg := func(add models.AddOrganization) (int, models.OrganizationJSON) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/", "/",
+			suite.api.CreateOrganization, bytes.NewBuffer(suite.jsonMarshal(add)),
+		)
+		require.NoError(err)
+		var o models.OrganizationJSON
+		if res.Code == http.StatusCreated {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &o))
+		}
+		return res.Code, o
+	}
+
+	code, _ := createOrg(models.AddOrganization{Name: "organization-dns-bad", DnsSuffix: "not_valid.example.com"})
+	require.Equal(http.StatusBadRequest, code)
+
+	code, org := createOrg(models.AddOrganization{Name: "organization-dns", DnsSuffix: "Red.Example.com."})
+	require.Equal(http.StatusCreated, code)
+	require.Equal("red.example.com", org.DnsSuffix)
+
+	updateOrg := func(suffix string) (int, models.OrganizationJSON) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPatch, "/:organization", fmt.Sprintf("/%s", org.ID),
+			suite.api.UpdateOrganization, bytes.NewBuffer(suite.jsonMarshal(models.UpdateOrganization{DnsSuffix: suffix})),
+		)
+		require.NoError(err)
+		var o models.OrganizationJSON
+		if res.Code == http.StatusOK {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &o))
+		}
+		return res.Code, o
+	}
+
+	code, _ = updateOrg("-blue.example.com")
+	require.Equal(http.StatusBadRequest, code)
+
+	code, org = updateOrg("blue.example.com")
+	require.Equal(http.StatusOK, code)
+	require.Equal("blue.example.com", org.DnsSuffix)
+
+	// other updates leave the suffix alone.
+	code, org = updateOrg("")
+	require.Equal(http.StatusOK, code)
+	require.Equal("blue.example.com", org.DnsSuffix)
+
+	code, org = updateOrg(".")
+	require.Equal(http.StatusOK, code)
+	require.Equal("", org.DnsSuffix)
+}
+
+func (suite *HandlerTestSuite) TestListDevicesExpiredRevision() {
+	require := suite.Require()
+
+	listDevices := func(gtRevision uint64) *httptest.ResponseRecorder {
+		_, res, err := suite.ServeRequest(
+			http.MethodGet, "/:organization",
+			fmt.Sprintf("/%s?gt_revision=%d", suite.testOrganizationID, gtRevision),
+			suite.api.ListDevicesInOrganization, nil,
+		)
+		require.NoError(err)
+		return res
+	}
+
+	res := listDevices(5)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	require.NoError(suite.api.db.Create(&models.DeviceCompaction{
+		OrganizationID: suite.testOrganizationID,
+		Revision:       10,
+	}).Error)
+	defer suite.api.db.Delete(&models.DeviceCompaction{}, "organization_id = ?", suite.testOrganizationID)
+
+	// the tombstones up to revision 10 are gone, the client has to list the devices again.
+	res = listDevices(5)
+	require.Equal(http.StatusGone, res.Code, "HTTP error: %s", res.Body.String())
+	var expired models.ExpiredError
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &expired))
+	require.Equal(uint64(10), expired.CompactedRevision)
+
+	res = listDevices(0)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	res = listDevices(10)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+}
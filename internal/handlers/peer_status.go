@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReportDevicePeerStatus stores the peer status summary uploaded by a Device
+// @Summary      Report Device Peer Status
+// @Description  Stores the latest peer connectivity summary for a device
+// @Id  		 ReportDevicePeerStatus
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "Device ID"
+// @Param		 status body models.ReportPeerStatus true "Peer Status"
+// @Success      200  {object}  models.DevicePeerStatus
+// @Failure		 401  {object}  models.BaseError
+// @Failure      400  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/devices/{id}/peer_status [put]
+func (api *API) ReportDevicePeerStatus(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ReportDevicePeerStatus", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	k, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	var request models.ReportPeerStatus
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+
+	var status models.DevicePeerStatus
+	routersChanged := false
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var device models.Device
+		result := tx.
+			Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			First(&device, "id = ?", k)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errDeviceNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		var previous *models.DevicePeerStatus
+		var found models.DevicePeerStatus
+		if res := tx.First(&found, "device_id = ?", device.ID); res.Error == nil {
+			previous = &found
+		} else if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return res.Error
+		}
+
+		status = models.DevicePeerStatus{
+			DeviceID:       device.ID,
+			OrganizationID: device.OrganizationID,
+			Peers:          request.Peers,
+			UpdatedAt:      time.Now(),
+		}
+		if status.Peers == nil {
+			status.Peers = []models.PeerStatus{}
+		}
+		if res := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&status); res.Error != nil {
+			return res.Error
+		}
+		if !reachabilityChanged(previous, status) {
+			return nil
+		}
+		// fail over the child prefixes of routers that the peers can no longer reach.
+		routersChanged, err = api.electChildPrefixRouters(ctx, tx, device.OrganizationID)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, errDeviceNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+	if routersChanged {
+		api.notifyDevicesChanged(ctx, status.OrganizationID)
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// reachabilityChanged returns true if a peer status report changes which peers are reachable from
+// the device, compared to its previous report. A previous report that is too old to count towards
+// the health of the peers is treated as missing.
+func reachabilityChanged(previous *models.DevicePeerStatus, current models.DevicePeerStatus) bool {
+	if previous == nil || current.UpdatedAt.Sub(previous.UpdatedAt) > peerStatusMaxAge {
+		return true
+	}
+	healthy := func(peers []models.PeerStatus) map[string]bool {
+		result := map[string]bool{}
+		for _, peer := range peers {
+			result[peer.PublicKey] = peer.Healthy
+		}
+		return result
+	}
+	return !reflect.DeepEqual(healthy(previous.Peers), healthy(current.Peers))
+}
+
+// GetOrganizationConnectivity returns the connectivity matrix of an Organization
+// @Summary      Get Organization Connectivity
+// @Description  Lists the connectivity status between the device pairs of an Organization, as reported by the devices
+// @Id           GetOrganizationConnectivity
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param		 organization_id path   string true "Organization ID"
+// @Success      200  {object}  []models.Connectivity
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/connectivity [get]
+func (api *API) GetOrganizationConnectivity(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetOrganizationConnectivity")
+	defer span.End()
+
+	k, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	var org models.Organization
+	result := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", k.String())
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+		}
+		return
+	}
+
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).Where("organization_id = ?", org.ID).Find(&devices); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	var statuses []models.DevicePeerStatus
+	if res := api.db.WithContext(ctx).Where("organization_id = ?", org.ID).Find(&statuses); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+
+	c.JSON(http.StatusOK, connectivityMatrix(devices, statuses))
+}
+
+// connectivityMatrix joins the peer status reports of the devices into a list of device pairs,
+// reports from or about devices that no longer exist are ignored.
+func connectivityMatrix(devices []models.Device, statuses []models.DevicePeerStatus) []models.Connectivity {
+	deviceIDs := map[uuid.UUID]struct{}{}
+	deviceByKey := map[string]uuid.UUID{}
+	for _, d := range devices {
+		deviceIDs[d.ID] = struct{}{}
+		deviceByKey[d.PublicKey] = d.ID
+	}
+
+	matrix := make([]models.Connectivity, 0)
+	for _, s := range statuses {
+		if _, ok := deviceIDs[s.DeviceID]; !ok {
+			continue
+		}
+		for _, peer := range s.Peers {
+			dst, ok := deviceByKey[peer.PublicKey]
+			if !ok || dst == s.DeviceID {
+				continue
+			}
+			status := models.ConnectivityBroken
+			if peer.Healthy && peer.Relayed {
+				status = models.ConnectivityRelayed
+			} else if peer.Healthy {
+				status = models.ConnectivityDirect
+			}
+			matrix = append(matrix, models.Connectivity{
+				SourceDeviceID:      s.DeviceID,
+				DestinationDeviceID: dst,
+				Status:              status,
+				Endpoint:            peer.Endpoint,
+				LatestHandshake:     peer.LatestHandshake,
+				ReportedAt:          s.UpdatedAt,
+			})
+		}
+	}
+
+	sort.Slice(matrix, func(i, j int) bool {
+		if matrix[i].SourceDeviceID != matrix[j].SourceDeviceID {
+			return matrix[i].SourceDeviceID.String() < matrix[j].SourceDeviceID.String()
+		}
+		return matrix[i].DestinationDeviceID.String() < matrix[j].DestinationDeviceID.String()
+	})
+	return matrix
+}
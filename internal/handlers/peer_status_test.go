@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestReportPeerStatusConnectivity() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	devices := []models.Device{}
+	for _, key := range []string{"pubkeyA", "pubkeyB", "pubkeyC"} {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/", "/",
+			suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{
+				OrganizationID: suite.testOrganizationID,
+				PublicKey:      key,
+			})),
+		)
+		require.NoError(err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(err)
+		require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+		var device models.Device
+		require.NoError(json.Unmarshal(body, &device))
+		devices = append(devices, device)
+	}
+
+	report := models.ReportPeerStatus{
+		Peers: []models.PeerStatus{
+			{PublicKey: "pubkeyB", Endpoint: "1.2.3.4:51820", Healthy: true},
+			{PublicKey: "pubkeyC", Relayed: true, Healthy: true},
+			{PublicKey: "unknownkey", Healthy: true},
+		},
+	}
+	_, res, err := suite.ServeRequest(
+		http.MethodPut, "/:id/peer_status", fmt.Sprintf("/%s/peer_status", devices[0].ID),
+		suite.api.ReportDevicePeerStatus, bytes.NewBuffer(suite.jsonMarshal(report)),
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	// reporting more than once replaces the previous report
+	report = models.ReportPeerStatus{
+		Peers: []models.PeerStatus{
+			{PublicKey: "pubkeyA", Healthy: false},
+		},
+	}
+	_, res, err = suite.ServeRequest(
+		http.MethodPut, "/:id/peer_status", fmt.Sprintf("/%s/peer_status", devices[1].ID),
+		suite.api.ReportDevicePeerStatus, bytes.NewBuffer(suite.jsonMarshal(report)),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+	_, res, err = suite.ServeRequest(
+		http.MethodPut, "/:id/peer_status", fmt.Sprintf("/%s/peer_status", devices[1].ID),
+		suite.api.ReportDevicePeerStatus, bytes.NewBuffer(suite.jsonMarshal(report)),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/:organization/connectivity", fmt.Sprintf("/%s/connectivity", suite.testOrganizationID),
+		suite.api.GetOrganizationConnectivity, nil,
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	var matrix []models.Connectivity
+	require.NoError(json.Unmarshal(body, &matrix))
+	require.Len(matrix, 3)
+
+	status := map[string]string{}
+	for _, conn := range matrix {
+		status[conn.SourceDeviceID.String()+"->"+conn.DestinationDeviceID.String()] = conn.Status
+	}
+	assert.Equal(models.ConnectivityDirect, status[devices[0].ID.String()+"->"+devices[1].ID.String()])
+	assert.Equal(models.ConnectivityRelayed, status[devices[0].ID.String()+"->"+devices[2].ID.String()])
+	assert.Equal(models.ConnectivityBroken, status[devices[1].ID.String()+"->"+devices[0].ID.String()])
+}
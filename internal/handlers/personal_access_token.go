@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPersonalAccessTokenLifetime = 90 * 24 * time.Hour
+	maxPersonalAccessTokenLifetime     = 365 * 24 * time.Hour
+)
+
+// ErrInvalidPersonalAccessToken is returned by LookupPersonalAccessToken for the tokens that
+// don't exist, have been deleted or have expired.
+var ErrInvalidPersonalAccessToken = errors.New("invalid personal access token")
+
+func (api *API) PersonalAccessTokenIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		userId := c.Value(gin.AuthUserKey).(string)
+		return db.Where("user_id = ?", userId)
+	}
+}
+
+func hashPersonalAccessToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func newPersonalAccessToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return models.PersonalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// LookupPersonalAccessToken returns a valid personal access token, and the name of the user it
+// was created by.
+func (api *API) LookupPersonalAccessToken(ctx context.Context, token string) (models.PersonalAccessToken, string, error) {
+	ctx, span := tracer.Start(ctx, "LookupPersonalAccessToken")
+	defer span.End()
+
+	var pat models.PersonalAccessToken
+	if res := api.db.WithContext(ctx).
+		First(&pat, "token_hash = ?", hashPersonalAccessToken(token)); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return pat, "", ErrInvalidPersonalAccessToken
+		}
+		return pat, "", res.Error
+	}
+	if time.Now().After(pat.Expiry) {
+		return pat, "", ErrInvalidPersonalAccessToken
+	}
+
+	var user models.User
+	if res := api.db.WithContext(ctx).First(&user, "id = ?", pat.UserID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return pat, "", ErrInvalidPersonalAccessToken
+		}
+		return pat, "", res.Error
+	}
+	span.SetAttributes(attribute.String("user-id", user.ID))
+	return pat, user.UserName, nil
+}
+
+// CreatePersonalAccessToken creates a personal access token
+// @Summary      Create a Personal Access Token
+// @Description  Creates a personal access token for the current user, the token is only returned by this request
+// @Id           CreatePersonalAccessToken
+// @Tags         PersonalAccessTokens
+// @Accept       json
+// @Produce      json
+// @Param        PersonalAccessToken  body   models.AddPersonalAccessToken  true "Add Personal Access Token"
+// @Success      201  {object}  models.PersonalAccessToken
+// @Failure      400  {object}  models.BaseError
+// @Failure      401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/personal_access_tokens [post]
+func (api *API) CreatePersonalAccessToken(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreatePersonalAccessToken")
+	defer span.End()
+
+	var request models.AddPersonalAccessToken
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if len(request.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("scopes"))
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !validPersonalAccessTokenScope(scope) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("scopes", "must be some of "+strings.Join(models.PersonalAccessTokenScopes, ", ")))
+			return
+		}
+	}
+	// a token can't be granted more than the token of the user that creates it.
+	callerScopes := c.GetStringSlice(AuthUserScopes)
+	for _, scope := range request.Scopes {
+		if !containsString(callerScopes, scope) {
+			c.JSON(http.StatusForbidden, models.NewFieldValidationError("scopes", fmt.Sprintf("the current token is not granted the %s scope", scope)))
+			return
+		}
+	}
+	now := time.Now()
+	expiry := now.Add(defaultPersonalAccessTokenLifetime)
+	if request.Expiry != nil {
+		expiry = *request.Expiry
+	}
+	if !expiry.After(now) || expiry.After(now.Add(maxPersonalAccessTokenLifetime)) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("expiry", "must be in the next 365 days"))
+		return
+	}
+
+	token, err := newPersonalAccessToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	pat := models.PersonalAccessToken{
+		UserID:      c.GetString(gin.AuthUserKey),
+		Description: request.Description,
+		Scopes:      request.Scopes,
+		Expiry:      expiry.UTC(),
+		TokenHash:   hashPersonalAccessToken(token),
+	}
+	if res := api.db.WithContext(ctx).Create(&pat); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	api.Logger(ctx).Infof("Created personal access token [ %s ] for user [ %s ] with scopes %v", pat.ID, pat.UserID, request.Scopes)
+
+	pat.Token = token
+	c.JSON(http.StatusCreated, pat)
+}
+
+func validPersonalAccessTokenScope(scope string) bool {
+	return containsString(models.PersonalAccessTokenScopes, scope)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ListPersonalAccessTokens lists the personal access tokens of the current user
+// @Summary      List Personal Access Tokens
+// @Description  Lists the personal access tokens of the current user, including the expired ones
+// @Id           ListPersonalAccessTokens
+// @Tags         PersonalAccessTokens
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  []models.PersonalAccessToken
+// @Failure      401  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/personal_access_tokens [get]
+func (api *API) ListPersonalAccessTokens(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListPersonalAccessTokens")
+	defer span.End()
+	tokens := make([]models.PersonalAccessToken, 0)
+	if res := api.db.WithContext(ctx).
+		Scopes(api.PersonalAccessTokenIsOwnedByCurrentUser(c)).
+		Scopes(FilterAndPaginate(&models.PersonalAccessToken{}, c, "expiry")).
+		Find(&tokens); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// GetPersonalAccessToken gets a personal access token
+// @Summary      Get a Personal Access Token
+// @Description  Gets a personal access token of the current user by ID, the token itself is not returned
+// @Id           GetPersonalAccessToken
+// @Tags         PersonalAccessTokens
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string true "Personal Access Token ID"
+// @Success      200  {object}  models.PersonalAccessToken
+// @Failure      400  {object}  models.BaseError
+// @Failure      401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Router       /api/personal_access_tokens/{id} [get]
+func (api *API) GetPersonalAccessToken(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetPersonalAccessToken",
+		trace.WithAttributes(
+			attribute.String("id", c.Param("id")),
+		))
+	defer span.End()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	var pat models.PersonalAccessToken
+	if res := api.db.WithContext(ctx).
+		Scopes(api.PersonalAccessTokenIsOwnedByCurrentUser(c)).
+		First(&pat, "id = ?", id); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("personal access token"))
+		return
+	}
+	c.JSON(http.StatusOK, pat)
+}
+
+// DeletePersonalAccessToken revokes a personal access token
+// @Summary      Delete a Personal Access Token
+// @Description  Revokes a personal access token of the current user
+// @Id           DeletePersonalAccessToken
+// @Tags         PersonalAccessTokens
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string true "Personal Access Token ID"
+// @Success      200  {object}  models.PersonalAccessToken
+// @Failure      400  {object}  models.BaseError
+// @Failure      401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/personal_access_tokens/{id} [delete]
+func (api *API) DeletePersonalAccessToken(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeletePersonalAccessToken",
+		trace.WithAttributes(
+			attribute.String("id", c.Param("id")),
+		))
+	defer span.End()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	var pat models.PersonalAccessToken
+	if res := api.db.WithContext(ctx).
+		Scopes(api.PersonalAccessTokenIsOwnedByCurrentUser(c)).
+		First(&pat, "id = ?", id); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("personal access token"))
+		return
+	}
+	if res := api.db.WithContext(ctx).Delete(&pat); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, pat)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestPersonalAccessTokens() {
+	require := suite.Require()
+	require.NoError(suite.api.db.Exec("DELETE FROM personal_access_tokens").Error)
+
+	callerScopes := models.PersonalAccessTokenScopes
+	create := func(request models.AddPersonalAccessToken) (int, models.PersonalAccessToken) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/", "/",
+			func(c *gin.Context) {
+				c.Set(AuthUserScopes, callerScopes)
+				suite.api.CreatePersonalAccessToken(c)
+			}, bytes.NewBuffer(suite.jsonMarshal(request)),
+		)
+		require.NoError(err)
+		var pat models.PersonalAccessToken
+		if res.Code == http.StatusCreated {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &pat))
+		}
+		return res.Code, pat
+	}
+
+	// invalid requests.
+	code, _ := create(models.AddPersonalAccessToken{})
+	require.Equal(http.StatusBadRequest, code)
+	code, _ = create(models.AddPersonalAccessToken{Scopes: []string{"admin"}})
+	require.Equal(http.StatusBadRequest, code)
+	past := time.Now().Add(-time.Hour)
+	code, _ = create(models.AddPersonalAccessToken{Scopes: []string{"read:devices"}, Expiry: &past})
+	require.Equal(http.StatusBadRequest, code)
+	tooLate := time.Now().Add(2 * maxPersonalAccessTokenLifetime)
+	code, _ = create(models.AddPersonalAccessToken{Scopes: []string{"read:devices"}, Expiry: &tooLate})
+	require.Equal(http.StatusBadRequest, code)
+
+	// a read only caller can't mint a token with write scopes.
+	callerScopes = []string{"read:devices", "read:organizations"}
+	code, _ = create(models.AddPersonalAccessToken{Scopes: []string{"read:devices", "write:devices"}})
+	require.Equal(http.StatusForbidden, code)
+	code, _ = create(models.AddPersonalAccessToken{Scopes: []string{"read:devices"}})
+	require.Equal(http.StatusCreated, code)
+	require.NoError(suite.api.db.Exec("DELETE FROM personal_access_tokens").Error)
+	callerScopes = models.PersonalAccessTokenScopes
+
+	code, pat := create(models.AddPersonalAccessToken{
+		Description: "ci",
+		Scopes:      []string{"read:devices", "write:devices"},
+	})
+	require.Equal(http.StatusCreated, code)
+	require.True(strings.HasPrefix(pat.Token, models.PersonalAccessTokenPrefix))
+	require.Equal(TestUserID, pat.UserID)
+	require.WithinDuration(time.Now().Add(defaultPersonalAccessTokenLifetime), pat.Expiry, time.Minute)
+
+	// the token itself is not stored.
+	var stored models.PersonalAccessToken
+	require.NoError(suite.api.db.First(&stored, "id = ?", pat.ID).Error)
+	require.NotContains(stored.TokenHash, pat.Token)
+
+	// the token is only returned once.
+	_, res, err := suite.ServeRequest(http.MethodGet, "/:id", fmt.Sprintf("/%s", pat.ID), suite.api.GetPersonalAccessToken, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var actual models.PersonalAccessToken
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &actual))
+	require.Equal(pat.ID, actual.ID)
+	require.Empty(actual.Token)
+
+	_, res, err = suite.ServeRequest(http.MethodGet, "/", "/", suite.api.ListPersonalAccessTokens, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var tokens []models.PersonalAccessToken
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &tokens))
+	require.Len(tokens, 1)
+
+	// the token authenticates the user.
+	found, userName, err := suite.api.LookupPersonalAccessToken(context.Background(), pat.Token)
+	require.NoError(err)
+	require.Equal(pat.ID, found.ID)
+	var user models.User
+	require.NoError(suite.api.db.First(&user, "id = ?", TestUserID).Error)
+	require.Equal(user.UserName, userName)
+	require.ElementsMatch([]string{"read:devices", "write:devices"}, found.Scopes)
+	_, _, err = suite.api.LookupPersonalAccessToken(context.Background(), pat.Token+"x")
+	require.ErrorIs(err, ErrInvalidPersonalAccessToken)
+
+	// expired tokens are rejected.
+	require.NoError(suite.api.db.Model(&stored).Update("expiry", time.Now().Add(-time.Second)).Error)
+	_, _, err = suite.api.LookupPersonalAccessToken(context.Background(), pat.Token)
+	require.ErrorIs(err, ErrInvalidPersonalAccessToken)
+
+	// deleted tokens are rejected.
+	require.NoError(suite.api.db.Model(&stored).Update("expiry", time.Now().Add(time.Hour)).Error)
+	_, res, err = suite.ServeRequest(http.MethodDelete, "/:id", fmt.Sprintf("/%s", pat.ID), suite.api.DeletePersonalAccessToken, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	_, _, err = suite.api.LookupPersonalAccessToken(context.Background(), pat.Token)
+	require.ErrorIs(err, ErrInvalidPersonalAccessToken)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Quotas limits the resources that can be created, a zero limit is unlimited.
+type Quotas struct {
+	DevicesPerOrganization        int
+	OrganizationsPerUser          int
+	SecurityGroupsPerOrganization int
+	// RulesPerSecurityGroup limits the inbound and outbound rules of a security group together.
+	RulesPerSecurityGroup int
+}
+
+// errQuotaExceeded is returned when creating a resource would exceed one of the Quotas.
+type errQuotaExceeded struct {
+	quota string
+	limit int
+}
+
+func (e errQuotaExceeded) Error() string {
+	return fmt.Sprintf("%s quota of %d exceeded", e.quota, e.limit)
+}
+
+// SetQuotas sets the quotas that the create handlers enforce.
+func (api *API) SetQuotas(quotas Quotas) {
+	api.quotas = quotas
+}
+
+// checkQuota fails if the rows of the model matching the query already reach the limit. The row
+// of the owner the quota applies to is locked first, so that concurrent transactions creating
+// resources for the same owner can't both pass the check.
+func checkQuota(tx *gorm.DB, owner interface{}, ownerID interface{}, quota string, limit int, model interface{}, query string, args ...interface{}) error {
+	if limit <= 0 {
+		return nil
+	}
+	// sqlite does not support row locks, its write transactions are already serialized.
+	if tx.Dialector.Name() != "sqlite" {
+		var locked []string
+		if res := tx.Model(owner).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", ownerID).
+			Pluck("id", &locked); res.Error != nil {
+			return res.Error
+		}
+	}
+	var count int64
+	if res := tx.Model(model).Where(query, args...).Count(&count); res.Error != nil {
+		return res.Error
+	}
+	if count >= int64(limit) {
+		return errQuotaExceeded{quota: quota, limit: limit}
+	}
+	return nil
+}
+
+// checkRulesQuota fails if a security group would have more rules than the RulesPerSecurityGroup quota.
+func (api *API) checkRulesQuota(inbound []models.SecurityRule, outbound []models.SecurityRule) error {
+	limit := api.quotas.RulesPerSecurityGroup
+	if limit > 0 && len(inbound)+len(outbound) > limit {
+		return errQuotaExceeded{quota: "rules_per_security_group", limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestQuotas() {
+	require := suite.Require()
+	defer suite.api.SetQuotas(Quotas{})
+	suite.setFeatureFlag("security-groups", true)
+
+	requireQuotaExceeded := func(res *httptest.ResponseRecorder, quota string, limit int) {
+		require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+		var actual models.QuotaExceededError
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &actual))
+		require.Equal(models.NewQuotaExceededError(quota, limit), actual)
+	}
+
+	// devices
+	suite.api.SetQuotas(Quotas{DevicesPerOrganization: 1})
+	createDevice := func(publicKey string) *httptest.ResponseRecorder {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/", "/",
+			suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{
+				OrganizationID: suite.testOrganizationID,
+				PublicKey:      publicKey,
+			})),
+		)
+		require.NoError(err)
+		return res
+	}
+	res := createDevice("quotapubkeyA")
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	requireQuotaExceeded(createDevice("quotapubkeyB"), "devices_per_organization", 1)
+	// a device that already exists is still reported as a conflict.
+	require.Equal(http.StatusConflict, createDevice("quotapubkeyA").Code)
+
+	// organizations
+	var owned int64
+	require.NoError(suite.api.db.Model(&models.Organization{}).Where("owner_id = ?", TestUserID).Count(&owned).Error)
+	suite.api.SetQuotas(Quotas{OrganizationsPerUser: int(owned) + 1})
+	createOrganization := func(name string) *httptest.ResponseRecorder {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/", "/",
+			suite.api.CreateOrganization,
+			bytes.NewBuffer(suite.jsonMarshal(models.AddOrganization{Name: name})),
+		)
+		require.NoError(err)
+		return res
+	}
+	res = createOrganization("quota-organization-a")
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	requireQuotaExceeded(createOrganization("quota-organization-b"), "organizations_per_user", int(owned)+1)
+
+	// security groups
+	createSecurityGroup := func(name string, rules []models.SecurityRule) *httptest.ResponseRecorder {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost,
+			"/organizations/:organization/security_groups", fmt.Sprintf("/organizations/%s/security_groups", suite.testOrganizationID.String()),
+			suite.api.CreateSecurityGroup,
+			bytes.NewBuffer(suite.jsonMarshal(models.AddSecurityGroup{
+				GroupName:      name,
+				OrganizationId: suite.testOrganizationID,
+				InboundRules:   rules,
+			})),
+		)
+		require.NoError(err)
+		return res
+	}
+	rules := []models.SecurityRule{
+		{IpProtocol: "tcp", FromPort: 22, ToPort: 22},
+		{IpProtocol: "tcp", FromPort: 443, ToPort: 443},
+	}
+	suite.api.SetQuotas(Quotas{RulesPerSecurityGroup: 1})
+	requireQuotaExceeded(createSecurityGroup("quota-rules", rules), "rules_per_security_group", 1)
+	res = createSecurityGroup("quota-rules", rules[:1])
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+
+	var groups int64
+	require.NoError(suite.api.db.Model(&models.SecurityGroup{}).Where("organization_id = ?", suite.testOrganizationID).Count(&groups).Error)
+	suite.api.SetQuotas(Quotas{SecurityGroupsPerOrganization: int(groups)})
+	requireQuotaExceeded(createSecurityGroup("quota-groups", nil), "security_groups_per_organization", int(groups))
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+type errPrefixConflict struct {
+	prefix        string
+	conflictsWith string
+	deviceID      string
+}
+
+func (e errPrefixConflict) Error() string {
+	if e.conflictsWith == "" {
+		return fmt.Sprintf("prefix %s conflicts with another prefix", e.prefix)
+	}
+	return fmt.Sprintf("prefix %s conflicts with %s", e.prefix, e.conflictsWith)
+}
+
+// ipamPrefixError maps the ipam error of a child prefix assignment, a prefix that overlaps one of
+// another organization of the ipam namespace is a conflict, which is not disclosed.
+func ipamPrefixError(prefix string, err error) error {
+	if errors.Is(err, ipam.ErrPrefixOverlaps) {
+		return errPrefixConflict{prefix: normalizePrefix(prefix)}
+	}
+	return fmt.Errorf("failed to assign child prefix: %w", err)
+}
+
+// validateChildPrefixes checks that the child prefixes requested for a device are valid and do not
+// overlap each other, the cidrs of the organizations, or the child prefixes of the other devices that share
+// the ipam namespace of the organization, other than the same prefix advertised within the
+// organization. Default routes are not leased from ipam, so they are skipped.
+func (api *API) validateChildPrefixes(tx *gorm.DB, org models.Organization, deviceID uuid.UUID, prefixes []string) error {
+	requested := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		p, err := netip.ParsePrefix(prefix)
+		if err != nil || !util.IsValidPrefix(prefix) {
+			return errInvalidCidr{field: "child_prefix", reason: fmt.Sprintf("%s is not a valid cidr", prefix)}
+		}
+		if util.IsDefaultIPRoute(prefix) {
+			continue
+		}
+		p = p.Masked()
+		for _, other := range requested {
+			if other.Overlaps(p) {
+				return errPrefixConflict{prefix: p.String(), conflictsWith: other.String(), deviceID: deviceID.String()}
+			}
+		}
+		requested = append(requested, p)
+	}
+	if len(requested) == 0 {
+		return nil
+	}
+
+	// the child prefixes can't overlap the tunnel ranges of the organizations that share the ipam
+	// namespace.
+	orgs := []models.Organization{org}
+	if !org.PrivateCidr {
+		var others []models.Organization
+		if res := tx.Select("id", "ip_cidr", "ip_cidr_v6").
+			Where("id <> ? AND private_cidr = ?", org.ID, false).
+			Find(&others); res.Error != nil {
+			return res.Error
+		}
+		orgs = append(orgs, others...)
+	}
+	for _, o := range orgs {
+		for _, cidr := range []string{o.IpCidr, o.IpCidrV6} {
+			orgPrefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				continue
+			}
+			for _, p := range requested {
+				if orgPrefix.Overlaps(p) {
+					return errPrefixConflict{prefix: p.String(), conflictsWith: orgPrefix.Masked().String()}
+				}
+			}
+		}
+	}
+
+	// only the devices that advertise child prefixes in the ipam namespace of the organization are
+	// checked.
+	query := tx.Select("id", "organization_id", "child_prefix").
+		Where("id <> ? AND child_prefix IS NOT NULL AND child_prefix <> '{}'", deviceID)
+	if org.PrivateCidr {
+		query = query.Where("organization_id = ?", org.ID)
+	} else {
+		query = query.Where("organization_id IN (?)", tx.Model(&models.Organization{}).
+			Select("id").
+			Where("private_cidr = ?", false))
+	}
+	var devices []models.Device
+	if res := query.Find(&devices); res.Error != nil {
+		return res.Error
+	}
+	for _, device := range devices {
+		if device.ID == deviceID {
+			continue
+		}
+		for _, prefix := range device.ChildPrefix {
+			if util.IsDefaultIPRoute(prefix) {
+				continue
+			}
+			other, err := netip.ParsePrefix(prefix)
+			if err != nil {
+				continue
+			}
+			for _, p := range requested {
+				// devices of the same organization can advertise the same prefix for high availability.
+				if device.OrganizationID == org.ID && other.Masked() == p {
+					continue
+				}
+				if other.Overlaps(p) {
+					conflict := errPrefixConflict{prefix: p.String(), conflictsWith: other.Masked().String()}
+					// don't disclose the devices of other organizations.
+					if device.OrganizationID == org.ID {
+						conflict.deviceID = device.ID.String()
+					}
+					return conflict
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GetOrganizationRoutes returns the routing table of an Organization
+// @Summary      Get Organization Routes
+// @Description  Lists the prefixes advertised by the devices of an Organization: their tunnel addresses, child prefixes and the organization cidrs forwarded by relays
+// @Id           GetOrganizationRoutes
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param		 organization_id path   string true "Organization ID"
+// @Success      200  {object}  []models.Route
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/routes [get]
+func (api *API) GetOrganizationRoutes(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetOrganizationRoutes",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+
+	k, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	var org models.Organization
+	result := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", k.String())
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+		}
+		return
+	}
+
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).Where("organization_id = ?", org.ID).Find(&devices); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+
+	c.JSON(http.StatusOK, organizationRoutes(org, devices))
+}
+
+// organizationRoutes computes the routes advertised by the devices of an organization, sorted by
+// prefix with the active route first.
+func organizationRoutes(org models.Organization, devices []models.Device) []models.Route {
+	routes := make([]models.Route, 0)
+	add := func(device models.Device, prefix string, kind string, active bool) {
+		prefix = normalizePrefix(prefix)
+		route := models.Route{
+			Prefix:   prefix,
+			Kind:     kind,
+			DeviceID: device.ID,
+			Hostname: device.Hostname,
+			TunnelIP: device.TunnelIP,
+			Online:   device.Online,
+			Active:   active,
+		}
+		if kind == models.RouteKindChildPrefix {
+			route.Priority = device.ChildPrefixPriority
+		}
+		routes = append(routes, route)
+	}
+	for _, device := range devices {
+		if device.TunnelIP != "" {
+			add(device, device.TunnelIP+"/32", models.RouteKindTunnel, true)
+		}
+		if device.TunnelIpV6 != "" {
+			add(device, device.TunnelIpV6+"/128", models.RouteKindTunnel, true)
+		}
+		for _, prefix := range device.ChildPrefix {
+			add(device, prefix, models.RouteKindChildPrefix, routesChildPrefix(&device, normalizePrefix(prefix)))
+		}
+		if device.Relay {
+			for _, cidr := range []string{org.IpCidr, org.IpCidrV6} {
+				if cidr != "" {
+					add(device, cidr, models.RouteKindRelay, true)
+				}
+			}
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Prefix != routes[j].Prefix {
+			return routes[i].Prefix < routes[j].Prefix
+		}
+		if routes[i].Active != routes[j].Active {
+			return routes[i].Active
+		}
+		return routes[i].DeviceID.String() < routes[j].DeviceID.String()
+	})
+	return routes
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestChildPrefixConflictsAndRoutes() {
+	require := suite.Require()
+
+	createDevice := func(key string, childPrefix ...string) (int, []byte) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/", "/",
+			suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{
+				OrganizationID: suite.testOrganizationID,
+				PublicKey:      key,
+				ChildPrefix:    childPrefix,
+			})),
+		)
+		require.NoError(err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(err)
+		return res.Code, body
+	}
+	updateDevice := func(id string, childPrefix ...string) (int, []byte) {
+		_, res, err := suite.ServeRequest(
+			http.MethodPatch, "/:id", fmt.Sprintf("/%s", id),
+			suite.api.UpdateDevice, bytes.NewBuffer(suite.jsonMarshal(models.UpdateDevice{
+				ChildPrefix: childPrefix,
+			})),
+		)
+		require.NoError(err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(err)
+		return res.Code, body
+	}
+	conflictOf := func(body []byte) models.PrefixConflictError {
+		var conflict models.PrefixConflictError
+		require.NoError(json.Unmarshal(body, &conflict))
+		return conflict
+	}
+
+	code, body := createDevice("routes-pubkey-a", "172.16.10.0/24")
+	require.Equal(http.StatusCreated, code, "HTTP error: %s", string(body))
+	var deviceA models.Device
+	require.NoError(json.Unmarshal(body, &deviceA))
+
+	code, body = createDevice("routes-pubkey-b", "172.16.10.128/25")
+	require.Equal(http.StatusConflict, code, "HTTP error: %s", string(body))
+	require.Equal(models.NewPrefixConflictError("172.16.10.128/25", "172.16.10.0/24", deviceA.ID.String()), conflictOf(body))
+
+	code, body = createDevice("routes-pubkey-b", "100.64.1.0/24")
+	require.Equal(http.StatusConflict, code, "HTTP error: %s", string(body))
+	require.Equal(models.NewPrefixConflictError("100.64.1.0/24", "100.64.0.0/10", ""), conflictOf(body))
+
+	code, body = createDevice("routes-pubkey-b", "172.16.20.0/24", "172.16.20.0/25")
+	require.Equal(http.StatusConflict, code, "HTTP error: %s", string(body))
+
+	code, body = createDevice("routes-pubkey-b", "not-a-prefix")
+	require.Equal(http.StatusBadRequest, code, "HTTP error: %s", string(body))
+
+	// a prefix reserved in ipam by another organization of the namespace is a conflict too.
+	require.NoError(suite.api.ipam.AssignPrefix(context.Background(), defaultIPAMNamespace, "172.16.30.0/24"))
+	code, body = createDevice("routes-pubkey-b", "172.16.30.0/25")
+	require.Equal(http.StatusConflict, code, "HTTP error: %s", string(body))
+	require.Equal(models.NewPrefixConflictError("172.16.30.0/25", "", ""), conflictOf(body))
+
+	// the cidrs of the other organizations of the namespace are conflicts too.
+	other := models.Organization{Name: "routes-other", IpCidr: "10.210.0.0/16", IpCidrV6: "fd10:210::/64"}
+	require.NoError(suite.api.db.Create(&other).Error)
+	defer suite.api.db.Unscoped().Delete(&other)
+	code, body = createDevice("routes-pubkey-b", "10.210.4.0/24")
+	require.Equal(http.StatusConflict, code, "HTTP error: %s", string(body))
+	require.Equal(models.NewPrefixConflictError("10.210.4.0/24", "10.210.0.0/16", ""), conflictOf(body))
+
+	// unless the other organization has its own namespace.
+	require.NoError(suite.api.db.Model(&other).Update("private_cidr", true).Error)
+	code, body = createDevice("routes-pubkey-b", "10.210.4.0/24")
+	require.Equal(http.StatusCreated, code, "HTTP error: %s", string(body))
+	var deviceOther models.Device
+	require.NoError(json.Unmarshal(body, &deviceOther))
+	_, res, err := suite.ServeRequest(
+		http.MethodDelete, "/:id", fmt.Sprintf("/%s", deviceOther.ID),
+		suite.api.DeleteDevice, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+
+	// default routes don't conflict with anything.
+	code, body = createDevice("routes-pubkey-b", "172.16.11.0/24", "0.0.0.0/0")
+	require.Equal(http.StatusCreated, code, "HTTP error: %s", string(body))
+	var deviceB models.Device
+	require.NoError(json.Unmarshal(body, &deviceB))
+
+	code, body = updateDevice(deviceA.ID.String(), "172.16.10.0/24", "172.16.11.0/25")
+	require.Equal(http.StatusConflict, code, "HTTP error: %s", string(body))
+	require.Equal(models.NewPrefixConflictError("172.16.11.0/25", "172.16.11.0/24", deviceB.ID.String()), conflictOf(body))
+
+	// a device's own prefixes don't conflict with its update.
+	code, body = updateDevice(deviceA.ID.String(), "172.16.10.0/24", "172.16.12.0/24")
+	require.Equal(http.StatusOK, code, "HTTP error: %s", string(body))
+	code, body = updateDevice(deviceA.ID.String(), "172.16.12.0/24")
+	require.Equal(http.StatusOK, code, "HTTP error: %s", string(body))
+
+	// the released prefix can be used by another device.
+	code, body = updateDevice(deviceB.ID.String(), "172.16.11.0/24", "172.16.10.0/24", "0.0.0.0/0")
+	require.Equal(http.StatusOK, code, "HTTP error: %s", string(body))
+	require.NoError(json.Unmarshal(body, &deviceB))
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/:organization/routes", fmt.Sprintf("/%s/routes", suite.testOrganizationID),
+		suite.api.GetOrganizationRoutes, nil,
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	var routes []models.Route
+	require.NoError(json.Unmarshal(body, &routes))
+	advertised := map[string]string{}
+	for _, route := range routes {
+		advertised[route.Prefix] = route.Kind + " " + route.DeviceID.String()
+	}
+	require.Equal(map[string]string{
+		"0.0.0.0/0":                 "child_prefix " + deviceB.ID.String(),
+		"172.16.10.0/24":            "child_prefix " + deviceB.ID.String(),
+		"172.16.11.0/24":            "child_prefix " + deviceB.ID.String(),
+		"172.16.12.0/24":            "child_prefix " + deviceA.ID.String(),
+		deviceA.TunnelIP + "/32":    "tunnel " + deviceA.ID.String(),
+		deviceA.TunnelIpV6 + "/128": "tunnel " + deviceA.ID.String(),
+		deviceB.TunnelIP + "/32":    "tunnel " + deviceB.ID.String(),
+		deviceB.TunnelIpV6 + "/128": "tunnel " + deviceB.ID.String(),
+	}, advertised)
+}
@@ -105,20 +105,6 @@ func (api *API) GetSecurityGroup(c *gin.Context) {
 	c.JSON(http.StatusOK, securityGroup)
 }
 
-func (api *API) secGroupsEnabled(c *gin.Context) bool {
-	secGroupsEnabled, err := api.fflags.GetFlag("security-groups")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
-		return false
-	}
-	allowForTests := c.GetString("nexodus.secGroupsEnabled")
-	if (!secGroupsEnabled && allowForTests != "true") || allowForTests == "false" {
-		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError("security-groups support is disabled"))
-		return false
-	}
-	return true
-}
-
 // CreateSecurityGroup handles adding a new SecurityGroup
 // @Summary      Add SecurityGroup
 // @Id  		 CreateSecurityGroup
@@ -131,6 +117,7 @@ func (api *API) secGroupsEnabled(c *gin.Context) bool {
 // @Success      201  {object}  models.SecurityGroup
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.QuotaExceededError
 // @Failure      409  {object}  models.ConflictsError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
@@ -142,7 +129,7 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 	))
 	defer span.End()
 
-	if !api.secGroupsEnabled(c) {
+	if !api.flagCheck(ctx, c, "security-groups") {
 		return
 	}
 
@@ -172,6 +159,14 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 			return res.Error
 		}
 
+		if err := checkQuota(tx, &models.Organization{}, org.ID, "security_groups_per_organization", api.quotas.SecurityGroupsPerOrganization,
+			&models.SecurityGroup{}, "organization_id = ?", org.ID); err != nil {
+			return err
+		}
+		if err := api.checkRulesQuota(request.InboundRules, request.OutboundRules); err != nil {
+			return err
+		}
+
 		sg = models.SecurityGroup{
 			GroupName:        request.GroupName,
 			OrganizationId:   request.OrganizationId,
@@ -195,8 +190,11 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 	})
 
 	if err != nil {
+		var quota errQuotaExceeded
 		if errors.Is(err, errUserNotFound) {
 			c.JSON(http.StatusNotFound, models.NewApiInternalError(err))
+		} else if errors.As(err, &quota) {
+			c.JSON(http.StatusForbidden, models.NewQuotaExceededError(quota.quota, quota.limit))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
@@ -228,7 +226,7 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 
 	defer span.End()
 
-	if !api.secGroupsEnabled(c) {
+	if !api.flagCheck(ctx, c, "security-groups") {
 		return
 	}
 
@@ -306,6 +304,7 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 // @Success      200  {object}  models.SecurityGroup
 // @Failure		 401  {object}  models.BaseError
 // @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.QuotaExceededError
 // @Failure      404  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/security_groups/{security_group_id} [patch]
@@ -315,7 +314,7 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 	))
 	defer span.End()
 
-	if !api.secGroupsEnabled(c) {
+	if !api.flagCheck(ctx, c, "security-groups") {
 		return
 	}
 
@@ -353,6 +352,10 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 			return errSecurityGroupNotFound
 		}
 
+		if err := api.checkRulesQuota(request.InboundRules, request.OutboundRules); err != nil {
+			return err
+		}
+
 		securityGroup.GroupName = request.GroupName
 		securityGroup.GroupDescription = request.GroupDescription
 		securityGroup.InboundRules = request.InboundRules
@@ -366,8 +369,11 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 	})
 
 	if err != nil {
+		var quota errQuotaExceeded
 		if errors.Is(err, errSecurityGroupNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("security_group"))
+		} else if errors.As(err, &quota) {
+			c.JSON(http.StatusForbidden, models.NewQuotaExceededError(quota.quota, quota.limit))
 		} else if errors.Is(err, errOrgNotFound) {
 			c.JSON(http.StatusNotFound, err)
 		} else {
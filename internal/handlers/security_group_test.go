@@ -7,14 +7,13 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/gin-gonic/gin"
-
 	"github.com/nexodus-io/nexodus/internal/models"
 )
 
 func (suite *HandlerTestSuite) TestCreateGetSecurityGroups() {
 	require := suite.Require()
 	assert := suite.Assert()
+	suite.setFeatureFlag("security-groups", true)
 
 	groups := []models.AddSecurityGroup{
 		{
@@ -65,10 +64,7 @@ func (suite *HandlerTestSuite) TestCreateGetSecurityGroups() {
 		_, res, err := suite.ServeRequest(
 			http.MethodPost,
 			"/organizations/:organization/security_groups", fmt.Sprintf("/organizations/%s/security_groups", suite.testOrganizationID.String()),
-			func(c *gin.Context) {
-				c.Set("nexodus.secGroupsEnabled", "true")
-				suite.api.CreateSecurityGroup(c)
-			},
+			suite.api.CreateSecurityGroup,
 			bytes.NewBuffer(resBody),
 		)
 		require.NoError(err)
@@ -107,6 +103,7 @@ func (suite *HandlerTestSuite) TestCreateGetSecurityGroups() {
 
 func (suite *HandlerTestSuite) TestDeleteSecurityGroup() {
 	require := suite.Require()
+	suite.setFeatureFlag("security-groups", true)
 
 	// create a security group that we will delete later
 	newGroup := models.AddSecurityGroup{
@@ -123,10 +120,7 @@ func (suite *HandlerTestSuite) TestDeleteSecurityGroup() {
 	_, res, err := suite.ServeRequest(
 		http.MethodPost,
 		"/organizations/:organization/security_groups", fmt.Sprintf("/organizations/%s/security_groups", suite.testOrganizationID.String()),
-		func(c *gin.Context) {
-			c.Set("nexodus.secGroupsEnabled", "true")
-			suite.api.CreateSecurityGroup(c)
-		},
+		suite.api.CreateSecurityGroup,
 		bytes.NewBuffer(resBody),
 	)
 	require.NoError(err)
@@ -144,10 +138,7 @@ func (suite *HandlerTestSuite) TestDeleteSecurityGroup() {
 	_, res, err = suite.ServeRequest(
 		http.MethodDelete,
 		"/organizations/:organization/security_groups/:id", fmt.Sprintf("/organizations/%s/security_groups/%s", suite.testOrganizationID.String(), actual.ID),
-		func(c *gin.Context) {
-			c.Set("nexodus.secGroupsEnabled", "true")
-			suite.api.DeleteSecurityGroup(c)
-		},
+		suite.api.DeleteSecurityGroup,
 		nil,
 	)
 
@@ -167,6 +158,7 @@ func (suite *HandlerTestSuite) TestDeleteSecurityGroup() {
 func (suite *HandlerTestSuite) TestListSecurityGroups() {
 	require := suite.Require()
 	assert := suite.Assert()
+	suite.setFeatureFlag("security-groups", true)
 
 	// Create a couple of security groups for testing
 	groups := []models.AddSecurityGroup{
@@ -193,10 +185,7 @@ func (suite *HandlerTestSuite) TestListSecurityGroups() {
 		_, res, err := suite.ServeRequest(
 			http.MethodPost,
 			"/organizations/:organization/security_groups", fmt.Sprintf("/organizations/%s/security_groups", suite.testOrganizationID.String()),
-			func(c *gin.Context) {
-				c.Set("nexodus.secGroupsEnabled", "true")
-				suite.api.CreateSecurityGroup(c)
-			},
+			suite.api.CreateSecurityGroup,
 			bytes.NewBuffer(resBody),
 		)
 		require.NoError(err)
@@ -227,6 +216,7 @@ func (suite *HandlerTestSuite) TestListSecurityGroups() {
 func (suite *HandlerTestSuite) TestUpdateSecurityGroup() {
 	require := suite.Require()
 	assert := suite.Assert()
+	suite.setFeatureFlag("security-groups", true)
 
 	// Create a new security group
 	newGroup := models.AddSecurityGroup{
@@ -243,10 +233,7 @@ func (suite *HandlerTestSuite) TestUpdateSecurityGroup() {
 	_, res, err := suite.ServeRequest(
 		http.MethodPost,
 		"/organizations/:organization/security_groups", fmt.Sprintf("/organizations/%s/security_groups", suite.testOrganizationID.String()),
-		func(c *gin.Context) {
-			c.Set("nexodus.secGroupsEnabled", "true")
-			suite.api.CreateSecurityGroup(c)
-		},
+		suite.api.CreateSecurityGroup,
 		bytes.NewBuffer(resBody),
 	)
 	require.NoError(err)
@@ -273,10 +260,7 @@ func (suite *HandlerTestSuite) TestUpdateSecurityGroup() {
 	_, res, err = suite.ServeRequest(
 		http.MethodPatch,
 		"/organizations/:organization/security_groups/:id", fmt.Sprintf("/organizations/%s/security_groups/%s", suite.testOrganizationID.String(), actualGroup.ID),
-		func(c *gin.Context) {
-			c.Set("nexodus.secGroupsEnabled", "true")
-			suite.api.UpdateSecurityGroup(c)
-		},
+		suite.api.UpdateSecurityGroup,
 		bytes.NewBuffer(updateBody),
 	)
 	require.NoError(err)
@@ -1,12 +1,123 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/util"
+	"github.com/open-policy-agent/opa/storage"
 )
 
+// policyDataPath is where the PolicyData is stored, the policy reads it from data.nexodus.
+var policyDataPath = storage.MustParsePath("/nexodus")
+
+// PolicyData is the configuration of the authorization policy that is stored in the OPA store.
+type PolicyData struct {
+	// AllowedEmailDomains are the domains of the emails of the users that can log in through Google,
+	// no Google user can log in when empty.
+	AllowedEmailDomains []string `json:"allowed_email_domains"`
+	// AllowedIssuers are the issuers of the JWTs that are accepted, any issuer is accepted when empty.
+	AllowedIssuers []string `json:"allowed_issuers"`
+	// Audience is the audience the JWTs must be issued for.
+	Audience string `json:"audience"`
+}
+
+// DefaultPolicyData is the PolicyData used until SetPolicyData is called.
+var DefaultPolicyData = PolicyData{
+	AllowedEmailDomains: []string{"redhat.com"},
+	AllowedIssuers:      []string{},
+	Audience:            "account",
+}
+
 func (api *API) populateStore(parent context.Context) error {
-	_, span := tracer.Start(parent, "populateStore")
+	ctx, span := tracer.Start(parent, "populateStore")
 	defer span.End()
 
+	return api.SetPolicyData(ctx, DefaultPolicyData)
+}
+
+// SetPolicyData replaces the PolicyData in the OPA store, the policy uses it from the next request.
+func (api *API) SetPolicyData(ctx context.Context, data PolicyData) error {
+	if data.AllowedEmailDomains == nil {
+		data.AllowedEmailDomains = []string{}
+	}
+	if data.AllowedIssuers == nil {
+		data.AllowedIssuers = []string{}
+	}
+	// the store only holds json values.
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(content, &value); err != nil {
+		return err
+	}
+	return storage.WriteOne(ctx, api.store, storage.AddOp, policyDataPath, value)
+}
+
+// LoadPolicyDataFile reads a PolicyData from a json file, the fields missing from the file keep their
+// value in defaults.
+func LoadPolicyDataFile(file string, defaults PolicyData) (PolicyData, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return defaults, err
+	}
+	return parsePolicyData(content, defaults)
+}
+
+func parsePolicyData(content []byte, defaults PolicyData) (PolicyData, error) {
+	data := defaults
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&data); err != nil {
+		return defaults, fmt.Errorf("invalid policy data: %w", err)
+	}
+	if data.Audience == "" {
+		return defaults, fmt.Errorf("invalid policy data: the audience can't be empty")
+	}
+	return data, nil
+}
+
+// StartPolicyDataReloader loads the PolicyData from a json file, and starts a background worker that
+// checks the file for changes every interval. The file is compared by content rather than by
+// modification time so that the atomic symlink swaps of the kubernetes config maps are noticed. When
+// the file later becomes missing or invalid, the previous PolicyData is kept.
+func (api *API) StartPolicyDataReloader(ctx context.Context, wg *sync.WaitGroup, file string, defaults PolicyData, interval time.Duration) error {
+	var last []byte
+	reload := func() error {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if last != nil && bytes.Equal(content, last) {
+			return nil
+		}
+		data, err := parsePolicyData(content, defaults)
+		if err != nil {
+			return err
+		}
+		if err := api.SetPolicyData(ctx, data); err != nil {
+			return err
+		}
+		last = content
+		api.Logger(ctx).Infof("Loaded the policy data from %s", file)
+		return nil
+	}
+	if err := reload(); err != nil {
+		return fmt.Errorf("failed to load the policy data file %s: %w", file, err)
+	}
+	util.GoWithWaitGroup(wg, func() {
+		util.RunPeriodically(ctx, interval, func() {
+			if err := reload(); err != nil {
+				api.Logger(ctx).Warnf("failed to reload the policy data file %s: %v", file, err)
+			}
+		})
+	})
 	return nil
 }
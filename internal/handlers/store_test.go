@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/storage"
+)
+
+func (suite *HandlerTestSuite) TestPolicyDataReloader() {
+	require := suite.Require()
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer func() {
+		cancel()
+		wg.Wait()
+		require.NoError(suite.api.SetPolicyData(context.Background(), DefaultPolicyData))
+	}()
+
+	readPolicyData := func() interface{} {
+		value, err := storage.ReadOne(context.Background(), suite.api.store, policyDataPath)
+		require.NoError(err)
+		return value
+	}
+	// when nothing is configured, only the redhat.com Google users can log in.
+	require.Equal(map[string]interface{}{
+		"allowed_email_domains": []interface{}{"redhat.com"},
+		"allowed_issuers":       []interface{}{},
+		"audience":              "account",
+	}, readPolicyData())
+
+	file := filepath.Join(suite.T().TempDir(), "policy-data.json")
+	defaults := PolicyData{AllowedIssuers: []string{"https://auth.example.com"}, Audience: "account"}
+
+	// a file that can't be loaded fails the start.
+	require.Error(suite.api.StartPolicyDataReloader(ctx, wg, file, defaults, 10*time.Millisecond))
+	require.NoError(os.WriteFile(file, []byte(`{"allowed_email_domains": ["example.com"], "unknown": 1}`), 0600))
+	require.Error(suite.api.StartPolicyDataReloader(ctx, wg, file, defaults, 10*time.Millisecond))
+
+	// the fields missing from the file keep their default.
+	require.NoError(os.WriteFile(file, []byte(`{"allowed_email_domains": ["example.com"]}`), 0600))
+	require.NoError(suite.api.StartPolicyDataReloader(ctx, wg, file, defaults, 10*time.Millisecond))
+	require.Equal(map[string]interface{}{
+		"allowed_email_domains": []interface{}{"example.com"},
+		"allowed_issuers":       []interface{}{"https://auth.example.com"},
+		"audience":              "account",
+	}, readPolicyData())
+
+	// changes are reloaded.
+	require.NoError(os.WriteFile(file, []byte(`{"allowed_email_domains": ["example.org"], "audience": "nexodus"}`), 0600))
+	require.Eventually(func() bool {
+		value := readPolicyData().(map[string]interface{})
+		return value["audience"] == "nexodus"
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal([]interface{}{"example.org"}, readPolicyData().(map[string]interface{})["allowed_email_domains"])
+
+	// an invalid file keeps the previous policy data.
+	require.NoError(os.WriteFile(file, []byte(`{"audience": ""}`), 0600))
+	time.Sleep(50 * time.Millisecond)
+	require.Equal("nexodus", readPolicyData().(map[string]interface{})["audience"])
+}
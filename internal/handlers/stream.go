@@ -8,6 +8,8 @@ import (
 	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/nexodus-io/nexodus/internal/signalbus"
 	"net/http"
+	"reflect"
+	"strconv"
 	"time"
 )
 
@@ -31,16 +33,37 @@ func stream(c *gin.Context, nextEvent func() models.WatchEvent) {
 	}
 }
 
-// waitForCancelOrTimeoutOrNotification returns true if the context has been canceled or false after the timeout or sub signal
-func waitForCancelOrTimeoutOrNotification(ctx context.Context, timeout time.Duration, sub *signalbus.Subscription) bool {
+// waitForCancelOrTimeoutOrNotification returns true if the context has been canceled or false after the timeout or a signal of any of the subs,
+// signaled is the subscription that was signaled, if any.
+func waitForCancelOrTimeoutOrNotification(ctx context.Context, timeout time.Duration, subs ...*signalbus.Subscription) (canceled bool, signaled *signalbus.Subscription) {
 	tc, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	select {
-	case <-tc.Done():
-		return false
-	case <-sub.Signal():
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(tc.Done())},
+	}
+	for _, sub := range subs {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.Signal())})
+	}
+	chosen, _, _ := reflect.Select(cases)
+	if chosen >= 2 {
+		signaled = subs[chosen-2]
+	}
+	return chosen == 0, signaled
+}
+
+// revisionsSent returns true if the signals the subscription received since it was last checked
+// only carry revisions up to the given revision, the changes they signal have already been sent.
+func revisionsSent(sub *signalbus.Subscription, revision uint64) bool {
+	payloads, complete := sub.Payloads()
+	if !complete {
 		return false
-	case <-ctx.Done():
-		return true
 	}
+	for _, payload := range payloads {
+		r, err := strconv.ParseUint(payload, 10, 64)
+		if err != nil || r > revision {
+			return false
+		}
+	}
+	return true
 }
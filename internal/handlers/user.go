@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
 	"github.com/nexodus-io/nexodus/internal/models"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -18,6 +19,9 @@ import (
 // key for username in gin.Context
 const AuthUserName string = "_nexodus.UserName"
 
+// key for the OAUTH scopes of the access token in gin.Context
+const AuthUserScopes string = "_nexodus.Scopes"
+
 var noUUID = uuid.UUID{}
 
 func (api *API) CreateUserIfNotExists() gin.HandlerFunc {
@@ -149,15 +153,16 @@ func (api *API) createUserOrgIfNotExists(ctx context.Context, tx *gorm.DB, userI
 	}
 
 	ipamNamespace := defaultIPAMNamespace
+	ipamCtx := database.WithTx(ctx, tx)
 
 	// Create namespaces and prefixes
-	if err := api.ipam.CreateNamespace(ctx, ipamNamespace); err != nil {
+	if err := api.ipam.CreateNamespace(ipamCtx, ipamNamespace); err != nil {
 		return noUUID, fmt.Errorf("failed to create ipam namespace: %w", err)
 	}
-	if err := api.ipam.AssignPrefix(ctx, ipamNamespace, defaultIPAMv4Cidr); err != nil {
+	if err := api.ipam.AssignPrefix(ipamCtx, ipamNamespace, defaultIPAMv4Cidr); err != nil {
 		return noUUID, fmt.Errorf("can't assign default ipam v4 prefix: %w", err)
 	}
-	if err := api.ipam.AssignPrefix(ctx, ipamNamespace, defaultIPAMv6Cidr); err != nil {
+	if err := api.ipam.AssignPrefix(ipamCtx, ipamNamespace, defaultIPAMv6Cidr); err != nil {
 		return noUUID, fmt.Errorf("can't assign default ipam v6 prefix: %w", err)
 	}
 	// Create a default security group for the organization
@@ -267,13 +272,17 @@ func (api *API) DeleteUser(c *gin.Context) {
 
 	var user models.User
 	err := api.transaction(ctx, func(tx *gorm.DB) error {
-		if res := api.db.
+		if res := tx.
 			Scopes(api.UserIsCurrentUser(c)).
 			First(&user, "id = ?", userID); res.Error != nil {
 			return errUserNotFound
 		}
-		if res := api.db.Select(clause.Associations).Delete(&user); res.Error != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to delete user: %w", res.Error)))
+		if res := tx.Select(clause.Associations).Delete(&user); res.Error != nil {
+			return fmt.Errorf("failed to delete user: %w", res.Error)
+		}
+		// the personal access tokens must not work again if the user logs in again.
+		if res := tx.Where("user_id = ?", user.ID).Delete(&models.PersonalAccessToken{}); res.Error != nil {
+			return res.Error
 		}
 
 		return nil
@@ -0,0 +1,309 @@
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ IPAM = &EmbeddedIPAM{}   // type check the interface is implemented.
+var _ Lister = &EmbeddedIPAM{} // type check the interface is implemented.
+
+// ipamPrefix is a prefix that addresses are leased from.
+type ipamPrefix struct {
+	Namespace uuid.UUID `gorm:"type:uuid;primary_key"`
+	Cidr      string    `gorm:"primary_key"`
+	CreatedAt time.Time
+}
+
+func (ipamPrefix) TableName() string {
+	return "ipam_prefixes"
+}
+
+// ipamAddress is an address leased from a prefix.
+type ipamAddress struct {
+	Namespace uuid.UUID `gorm:"type:uuid;primary_key"`
+	Address   string    `gorm:"primary_key"`
+	Cidr      string    `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (ipamAddress) TableName() string {
+	return "ipam_addresses"
+}
+
+// EmbeddedIPAM implements IPAM with the leases stored in the apiserver database. When the
+// context passed to it carries a transaction (see database.WithTx), the leases are made as
+// part of that transaction, so they are rolled back with it.
+type EmbeddedIPAM struct {
+	logger *zap.SugaredLogger
+	db     *gorm.DB
+}
+
+// NewEmbeddedIPAM creates an EmbeddedIPAM that stores the leases in db.
+func NewEmbeddedIPAM(logger *zap.SugaredLogger, db *gorm.DB) *EmbeddedIPAM {
+	return &EmbeddedIPAM{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// CreateNamespace is a no-op, namespaces are created with their first prefix.
+func (i *EmbeddedIPAM) CreateNamespace(parent context.Context, namespace uuid.UUID) error {
+	return nil
+}
+
+func (i *EmbeddedIPAM) DeleteNamespace(parent context.Context, namespace uuid.UUID) error {
+	ctx, span := tracer.Start(parent, "DeleteNamespace")
+	defer span.End()
+	db := database.TxFromContext(ctx, i.db)
+	if res := db.Delete(&ipamAddress{}, "namespace = ?", namespace); res.Error != nil {
+		return res.Error
+	}
+	return db.Delete(&ipamPrefix{}, "namespace = ?", namespace).Error
+}
+
+func (i *EmbeddedIPAM) AssignSpecificTunnelIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) (string, error) {
+	ctx, span := tracer.Start(parent, "AssignSpecificTunnelIP")
+	defer span.End()
+	addr, err := netip.ParseAddr(TunnelIP)
+	if err != nil {
+		return "", fmt.Errorf("Address %s is not valid", TunnelIP)
+	}
+	ip, err := i.acquire(ctx, namespace, ipamPrefix, &addr)
+	if err != nil {
+		allocationFailures.WithLabelValues("address").Inc()
+		return "", fmt.Errorf("failed to acquire the IPAM address %s: %w", TunnelIP, err)
+	}
+	return ip, nil
+}
+
+func (i *EmbeddedIPAM) AssignFromPool(parent context.Context, namespace uuid.UUID, ipamPrefix string) (string, error) {
+	ctx, span := tracer.Start(parent, "AssignFromPool")
+	defer span.End()
+	ip, err := i.acquire(ctx, namespace, ipamPrefix, nil)
+	if err != nil {
+		allocationFailures.WithLabelValues("address").Inc()
+		return "", fmt.Errorf("failed to acquire an IPAM assigned address %w", err)
+	}
+	return ip, nil
+}
+
+// acquire leases the address requested, or the first free address of the prefix if requested is nil.
+func (i *EmbeddedIPAM) acquire(ctx context.Context, namespace uuid.UUID, cidr string, requested *netip.Addr) (string, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", fmt.Errorf("invalid prefix %s: %w", cidr, err)
+	}
+	prefix = prefix.Masked()
+
+	db := database.TxFromContext(ctx, i.db)
+	// lock the prefix so that concurrent transactions don't pick the same free address.
+	query := db
+	if db.Dialector.Name() != "sqlite" {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var p ipamPrefix
+	if res := query.First(&p, "namespace = ? AND cidr = ?", namespace, prefix.String()); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("prefix %s not found", prefix)
+		}
+		return "", res.Error
+	}
+
+	var leased []string
+	if res := db.Model(&ipamAddress{}).
+		Where("namespace = ? AND cidr = ?", namespace, p.Cidr).
+		Pluck("address", &leased); res.Error != nil {
+		return "", res.Error
+	}
+	used := make(map[netip.Addr]struct{}, len(leased)+2)
+	for _, address := range leased {
+		if addr, err := netip.ParseAddr(address); err == nil {
+			used[addr] = struct{}{}
+		}
+	}
+	// like go-ipam, the first address and the ipv4 broadcast address are never leased.
+	used[prefix.Addr()] = struct{}{}
+	if prefix.Addr().Is4() {
+		used[lastAddr(prefix)] = struct{}{}
+	}
+
+	var found netip.Addr
+	if requested != nil {
+		if !prefix.Contains(*requested) {
+			return "", fmt.Errorf("%s is not in %s", requested, prefix)
+		}
+		if _, ok := used[*requested]; ok {
+			return "", fmt.Errorf("%s is already allocated", requested)
+		}
+		found = *requested
+	} else {
+		for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+			if _, ok := used[addr]; !ok {
+				found = addr
+				break
+			}
+		}
+		if !found.IsValid() {
+			return "", fmt.Errorf("no more addresses available in prefix %s", prefix)
+		}
+	}
+
+	if res := db.Create(&ipamAddress{
+		Namespace: namespace,
+		Address:   found.String(),
+		Cidr:      p.Cidr,
+	}); res.Error != nil {
+		return "", res.Error
+	}
+	return found.String(), nil
+}
+
+// lastAddr returns the last address of a masked prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(b)*8; bit++ {
+		b[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+func (i *EmbeddedIPAM) AssignPrefix(parent context.Context, namespace uuid.UUID, cidr string) error {
+	ctx, span := tracer.Start(parent, "AssignPrefix")
+	defer span.End()
+	err := i.assignPrefix(ctx, namespace, cidr)
+	if err != nil {
+		allocationFailures.WithLabelValues("prefix").Inc()
+	}
+	return err
+}
+
+func (i *EmbeddedIPAM) assignPrefix(ctx context.Context, namespace uuid.UUID, cidr string) error {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid prefix requested: %w", err)
+	}
+	prefix = prefix.Masked()
+
+	// the primary key does not keep overlapping prefixes out of a namespace, so the prefixes of the
+	// namespace are checked and created under a lock of the namespace.
+	return database.TxFromContext(ctx, i.db).Transaction(func(tx *gorm.DB) error {
+		if err := database.LockInTx(tx, "ipam-prefixes/"+namespace.String()); err != nil {
+			return err
+		}
+		var existing []string
+		if res := tx.Model(&ipamPrefix{}).
+			Where("namespace = ?", namespace).
+			Pluck("cidr", &existing); res.Error != nil {
+			return res.Error
+		}
+		for _, e := range existing {
+			other, err := netip.ParsePrefix(e)
+			if err != nil {
+				continue
+			}
+			if other == prefix {
+				// it was already created.
+				return nil
+			}
+			if other.Overlaps(prefix) {
+				return fmt.Errorf("%w: %s overlaps %s", ErrPrefixOverlaps, prefix, other)
+			}
+		}
+		return tx.Create(&ipamPrefix{
+			Namespace: namespace,
+			Cidr:      prefix.String(),
+		}).Error
+	})
+}
+
+// ReleaseToPool release the ipam address back to the specified prefix
+func (i *EmbeddedIPAM) ReleaseToPool(parent context.Context, namespace uuid.UUID, address, cidr string) error {
+	ctx, span := tracer.Start(parent, "ReleaseToPool")
+	defer span.End()
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return fmt.Errorf("failed to release IPAM address: invalid prefix %s: %w", cidr, err)
+	}
+	res := database.TxFromContext(ctx, i.db).
+		Delete(&ipamAddress{}, "namespace = ? AND address = ? AND cidr = ?", namespace, address, prefix.Masked().String())
+	if res.Error != nil {
+		return fmt.Errorf("failed to release IPAM address %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("failed to release IPAM address: %s is not allocated in prefix %s", address, cidr)
+	}
+	return nil
+}
+
+// ReleasePrefix release the ipam address back to the specified prefix
+func (i *EmbeddedIPAM) ReleasePrefix(parent context.Context, namespace uuid.UUID, cidr string) error {
+	ctx, span := tracer.Start(parent, "ReleasePrefix")
+	defer span.End()
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return fmt.Errorf("failed to release IPAM prefix: invalid prefix %s: %w", cidr, err)
+	}
+	cidr = prefix.Masked().String()
+
+	db := database.TxFromContext(ctx, i.db)
+	var leased int64
+	if res := db.Model(&ipamAddress{}).
+		Where("namespace = ? AND cidr = ?", namespace, cidr).
+		Count(&leased); res.Error != nil {
+		return fmt.Errorf("failed to release IPAM prefix %w", res.Error)
+	}
+	if leased > 0 {
+		return fmt.Errorf("failed to release IPAM prefix: prefix %s has ips", cidr)
+	}
+	res := db.Delete(&ipamPrefix{}, "namespace = ? AND cidr = ?", namespace, cidr)
+	if res.Error != nil {
+		return fmt.Errorf("failed to release IPAM prefix %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("failed to release IPAM prefix: prefix %s not found", cidr)
+	}
+	return nil
+}
+
+func (i *EmbeddedIPAM) ListNamespaces(parent context.Context) ([]uuid.UUID, error) {
+	ctx, span := tracer.Start(parent, "ListNamespaces")
+	defer span.End()
+	var namespaces []uuid.UUID
+	res := database.TxFromContext(ctx, i.db).Model(&ipamPrefix{}).
+		Distinct("namespace").
+		Pluck("namespace", &namespaces)
+	return namespaces, res.Error
+}
+
+func (i *EmbeddedIPAM) ListPrefixes(parent context.Context, namespace uuid.UUID) ([]string, error) {
+	ctx, span := tracer.Start(parent, "ListPrefixes")
+	defer span.End()
+	var prefixes []string
+	res := database.TxFromContext(ctx, i.db).Model(&ipamPrefix{}).
+		Where("namespace = ?", namespace).
+		Order("cidr").
+		Pluck("cidr", &prefixes)
+	return prefixes, res.Error
+}
+
+func (i *EmbeddedIPAM) ListAddresses(parent context.Context, namespace uuid.UUID, cidr string) ([]string, error) {
+	ctx, span := tracer.Start(parent, "ListAddresses")
+	defer span.End()
+	var addresses []string
+	res := database.TxFromContext(ctx, i.db).Model(&ipamAddress{}).
+		Where("namespace = ? AND cidr = ?", namespace, cidr).
+		Order("address").
+		Pluck("address", &addresses)
+	return addresses, res.Error
+}
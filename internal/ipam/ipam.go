@@ -2,18 +2,16 @@ package ipam
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
-	"net/http"
 	"strings"
 
-	"github.com/bufbuild/connect-go"
 	"github.com/google/uuid"
-	apiv1 "github.com/metal-stack/go-ipam/api/v1"
-	"github.com/metal-stack/go-ipam/api/v1/apiv1connect"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
-	"go.uber.org/zap"
 )
 
 var tracer trace.Tracer
@@ -22,126 +20,56 @@ func init() {
 	tracer = otel.Tracer("github.com/nexodus-io/nexodus/internal/ipam")
 }
 
+var allocationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "apiserver",
+	Name:      "ipam_allocation_failures_total",
+	Help:      "Number of failed IPAM address and prefix allocations.",
+}, []string{"type"})
+
 func uuidToNamespace(id uuid.UUID) string {
 	return strings.ReplaceAll(id.String(), "-", "_")
 }
 
-type IPAM struct {
-	logger *zap.SugaredLogger
-	client apiv1connect.IpamServiceClient
-}
-
-func NewIPAM(logger *zap.SugaredLogger, ipamAddress string) IPAM {
-	return IPAM{
-		logger: logger,
-		client: apiv1connect.NewIpamServiceClient(
-			http.DefaultClient,
-			ipamAddress,
-			connect.WithGRPC(),
-		)}
+func namespaceToUUID(namespace string) (uuid.UUID, error) {
+	return uuid.Parse(strings.ReplaceAll(namespace, "_", "-"))
 }
 
-func (i *IPAM) CreateNamespace(parent context.Context, namespace uuid.UUID) error {
-	ctx, span := tracer.Start(parent, "CreateNamespace")
-	defer span.End()
-	_, err := i.client.CreateNamespace(ctx, connect.NewRequest(&apiv1.CreateNamespaceRequest{
-		Namespace: uuidToNamespace(namespace),
-	}))
-	return err
+// IPAM leases tunnel addresses and prefixes to organizations and devices. Namespaces
+// keep the addresses of organizations with a private CIDR apart from each other.
+type IPAM interface {
+	CreateNamespace(ctx context.Context, namespace uuid.UUID) error
+	DeleteNamespace(ctx context.Context, namespace uuid.UUID) error
+	// AssignSpecificTunnelIP leases tunnelIP, it fails if tunnelIP is not available in ipamPrefix.
+	AssignSpecificTunnelIP(ctx context.Context, namespace uuid.UUID, ipamPrefix string, tunnelIP string) (string, error)
+	// AssignFromPool leases the next free address in ipamPrefix.
+	AssignFromPool(ctx context.Context, namespace uuid.UUID, ipamPrefix string) (string, error)
+	// AssignPrefix reserves cidr, it fails if cidr overlaps a different prefix in the namespace.
+	AssignPrefix(ctx context.Context, namespace uuid.UUID, cidr string) error
+	// ReleaseToPool releases an address leased from cidr.
+	ReleaseToPool(ctx context.Context, namespace uuid.UUID, address, cidr string) error
+	// ReleasePrefix releases cidr, it fails if addresses are still leased from it.
+	ReleasePrefix(ctx context.Context, namespace uuid.UUID, cidr string) error
 }
 
-func (i *IPAM) DeleteNamespace(parent context.Context, namespace uuid.UUID) error {
-	ctx, span := tracer.Start(parent, "DeleteNamespace")
-	defer span.End()
-	_, err := i.client.DeleteNamespace(ctx, connect.NewRequest(&apiv1.DeleteNamespaceRequest{
-		Namespace: uuidToNamespace(namespace),
-	}))
-	return err
-}
+// ErrPrefixOverlaps is returned by AssignPrefix when the prefix overlaps a different prefix of the namespace.
+var ErrPrefixOverlaps = errors.New("prefix overlaps another prefix of the namespace")
 
-func (i *IPAM) AssignSpecificTunnelIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) (string, error) {
-	ctx, span := tracer.Start(parent, "AssignSpecificTunnelIP")
-	defer span.End()
-	if err := validateIP(TunnelIP); err != nil {
-		return "", fmt.Errorf("Address %s is not valid", TunnelIP)
-	}
-	ns := uuidToNamespace(namespace)
-	res, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
-		PrefixCidr: ipamPrefix,
-		Ip:         &TunnelIP,
-		Namespace:  &ns,
-	}))
-	if err != nil {
-		i.logger.Errorf("failed to assign the requested address %s, assigning an address from the pool: %v\n", TunnelIP, err)
-		return i.AssignFromPool(ctx, namespace, ipamPrefix)
-	}
-	return res.Msg.Ip.Ip, nil
-}
+// ErrListingNotSupported is returned by the Lister methods a backend can not implement.
+var ErrListingNotSupported = errors.New("listing is not supported by this ipam backend")
 
-func (i *IPAM) AssignFromPool(parent context.Context, namespace uuid.UUID, ipamPrefix string) (string, error) {
-	ctx, span := tracer.Start(parent, "AssignFromPool")
-	defer span.End()
-	ns := uuidToNamespace(namespace)
-	res, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
-		PrefixCidr: ipamPrefix,
-		Namespace:  &ns,
-	}))
-	if err != nil {
-		return "", fmt.Errorf("failed to acquire an IPAM assigned address %w\n", err)
-	}
-	return res.Msg.Ip.Ip, nil
+// Lister lists the leases an IPAM has on record, it is used to find and release leases
+// that no longer belong to a device or organization.
+type Lister interface {
+	ListNamespaces(ctx context.Context) ([]uuid.UUID, error)
+	ListPrefixes(ctx context.Context, namespace uuid.UUID) ([]string, error)
+	ListAddresses(ctx context.Context, namespace uuid.UUID, cidr string) ([]string, error)
 }
 
-func (i *IPAM) AssignPrefix(parent context.Context, namespace uuid.UUID, cidr string) error {
-	println()
-	ctx, span := tracer.Start(parent, "AssignPrefix")
-	defer span.End()
-	cidr, err := cleanCidr(cidr)
-	if err != nil {
-		return fmt.Errorf("invalid prefix requested: %w", err)
-	}
-	ns := uuidToNamespace(namespace)
-	_, originalErr := i.client.CreatePrefix(ctx, connect.NewRequest(&apiv1.CreatePrefixRequest{Cidr: cidr, Namespace: &ns}))
-	if originalErr != nil {
-		// check to see if the prefix had been already created....
-		resp, err := i.client.GetPrefix(ctx, connect.NewRequest(&apiv1.GetPrefixRequest{Cidr: cidr, Namespace: &ns}))
-		if err == nil {
-			// it did exist... so ignore that create error since the prefix was created.
-			if resp.Msg.Prefix.Cidr == cidr && resp.Msg.Prefix.ParentCidr == "" {
-				originalErr = nil
-			}
-		}
-	}
-	return originalErr
-}
-
-// ReleaseToPool release the ipam address back to the specified prefix
-func (i *IPAM) ReleaseToPool(ctx context.Context, namespace uuid.UUID, address, cidr string) error {
-	ns := uuidToNamespace(namespace)
-	_, err := i.client.ReleaseIP(ctx, connect.NewRequest(&apiv1.ReleaseIPRequest{
-		Ip:         address,
-		PrefixCidr: cidr,
-		Namespace:  &ns,
-	}))
-
-	if err != nil {
-		return fmt.Errorf("failed to release IPAM address %w", err)
-	}
-	return nil
-}
-
-// ReleasePrefix release the ipam address back to the specified prefix
-func (i *IPAM) ReleasePrefix(ctx context.Context, namespace uuid.UUID, cidr string) error {
-	ns := uuidToNamespace(namespace)
-	_, err := i.client.DeletePrefix(ctx, connect.NewRequest(&apiv1.DeletePrefixRequest{
-		Cidr:      cidr,
-		Namespace: &ns,
-	}))
-
-	if err != nil {
-		return fmt.Errorf("failed to release IPAM prefix %w", err)
-	}
-	return nil
+// Transactional returns true if the leases of i are made as part of the database transaction
+// carried by the context, so that they are rolled back with it.
+func Transactional(i IPAM) bool {
+	_, ok := i.(*EmbeddedIPAM)
+	return ok
 }
 
 // cleanCidr ensures a valid IP4/IP6 address is provided and return a proper
@@ -10,10 +10,12 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"gorm.io/gorm"
 )
 
 type IpamTestSuite struct {
@@ -22,12 +24,23 @@ type IpamTestSuite struct {
 	ipam   IPAM
 	server *http.Server
 	wg     sync.WaitGroup
+	// when set the suite runs against the EmbeddedIPAM instead of a go-ipam server
+	embedded bool
+	db       *gorm.DB
 }
 
 func (suite *IpamTestSuite) SetupSuite() {
-	suite.server = NewTestIPAMServer()
 	suite.logger = zaptest.NewLogger(suite.T()).Sugar()
-	suite.ipam = NewIPAM(suite.logger, TestIPAMClientAddr)
+	if suite.embedded {
+		var err error
+		suite.db, err = database.NewTestDatabase()
+		suite.Require().NoError(err)
+		suite.ipam = NewEmbeddedIPAM(suite.logger, suite.db)
+		return
+	}
+
+	suite.server = NewTestIPAMServer()
+	suite.ipam = NewRemoteIPAM(suite.logger, TestIPAMClientAddr)
 	suite.wg = sync.WaitGroup{}
 	suite.wg.Add(1)
 	listener, err := net.Listen("tcp", "[::1]:9091")
@@ -42,8 +55,10 @@ func (suite *IpamTestSuite) SetupSuite() {
 }
 
 func (suite *IpamTestSuite) TearDownSuite() {
-	suite.server.Close()
-	suite.wg.Wait()
+	if suite.server != nil {
+		suite.server.Close()
+		suite.wg.Wait()
+	}
 }
 
 func (suite *IpamTestSuite) TestAllocateTunnelIP() {
@@ -74,23 +89,112 @@ func (suite *IpamTestSuite) TestAllocateTunnelIP() {
 	}
 	assert.Equal(suite.T(), TunnelIP, ip)
 
-	// 3. Test conflicting TunnelIP assigned from pool
+	// 3. Does not assign a conflicting TunnelIP
 	TunnelIP = "10.20.30.1"
-	ip, err = suite.ipam.AssignSpecificTunnelIP(ctx, namespace, prefix, TunnelIP)
-	if err != nil {
-		suite.T().Fatal(err)
+	_, err = suite.ipam.AssignSpecificTunnelIP(ctx, namespace, prefix, TunnelIP)
+	if err == nil {
+		suite.T().Fatal("should return an error if ip is already assigned")
 	}
-	assert.Equal(suite.T(), "10.20.30.2", ip)
 
-	// 3. Test mismatched TunnelIP assigned from pool
+	// 4. Does not assign a TunnelIP outside of the prefix
 	TunnelIP = "10.20.40.1"
-	ip, err = suite.ipam.AssignSpecificTunnelIP(ctx, namespace, prefix, TunnelIP)
+	_, err = suite.ipam.AssignSpecificTunnelIP(ctx, namespace, prefix, TunnelIP)
+	if err == nil {
+		suite.T().Fatal("should return an error if ip is not in the prefix")
+	}
+
+	// 5. The failed requests did not lease any address
+	ip, err = suite.ipam.AssignFromPool(ctx, namespace, prefix)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
-	assert.Equal(suite.T(), "10.20.30.3", ip)
+	assert.Equal(suite.T(), "10.20.30.2", ip)
+}
+
+func (suite *IpamTestSuite) TestPrefixes() {
+	require := suite.Require()
+	ctx := context.Background()
+	namespace := uuid.New()
+
+	require.NoError(suite.ipam.CreateNamespace(ctx, namespace))
+	require.NoError(suite.ipam.AssignPrefix(ctx, namespace, "10.30.0.0/16"))
+	// assigning the same prefix again is not an error
+	require.NoError(suite.ipam.AssignPrefix(ctx, namespace, "10.30.0.0/16"))
+	// but overlapping prefixes are
+	require.ErrorIs(suite.ipam.AssignPrefix(ctx, namespace, "10.30.1.0/24"), ErrPrefixOverlaps)
+	// unless they are in different namespaces
+	other := uuid.New()
+	require.NoError(suite.ipam.CreateNamespace(ctx, other))
+	require.NoError(suite.ipam.AssignPrefix(ctx, other, "10.30.1.0/24"))
+
+	ip, err := suite.ipam.AssignFromPool(ctx, namespace, "10.30.0.0/16")
+	require.NoError(err)
+	require.Equal("10.30.0.1", ip)
+
+	// a prefix with leased addresses can't be released
+	require.Error(suite.ipam.ReleasePrefix(ctx, namespace, "10.30.0.0/16"))
+	require.NoError(suite.ipam.ReleaseToPool(ctx, namespace, ip, "10.30.0.0/16"))
+	require.Error(suite.ipam.ReleaseToPool(ctx, namespace, ip, "10.30.0.0/16"))
+	require.NoError(suite.ipam.ReleasePrefix(ctx, namespace, "10.30.0.0/16"))
+	require.NoError(suite.ipam.AssignPrefix(ctx, namespace, "10.30.1.0/24"))
+
+	require.NoError(suite.ipam.AssignPrefix(ctx, namespace, "200:30::/64"))
+	ip, err = suite.ipam.AssignFromPool(ctx, namespace, "200:30::/64")
+	require.NoError(err)
+	require.Equal("200:30::1", ip)
+}
+
+func (suite *IpamTestSuite) TestTransactionRollback() {
+	if !suite.embedded {
+		suite.T().Skip("only the embedded ipam takes part in database transactions")
+	}
+	require := suite.Require()
+	ctx := context.Background()
+	namespace := uuid.New()
+	prefix := "10.40.0.0/24"
+	require.NoError(suite.ipam.AssignPrefix(ctx, namespace, prefix))
+
+	errRollback := errors.New("rollback")
+	err := suite.db.Transaction(func(tx *gorm.DB) error {
+		ip, err := suite.ipam.AssignFromPool(database.WithTx(ctx, tx), namespace, prefix)
+		require.NoError(err)
+		require.Equal("10.40.0.1", ip)
+		return errRollback
+	})
+	require.ErrorIs(err, errRollback)
+
+	// the address leased in the rolled back transaction is available again
+	ip, err := suite.ipam.AssignFromPool(ctx, namespace, prefix)
+	require.NoError(err)
+	require.Equal("10.40.0.1", ip)
+}
+
+func (suite *IpamTestSuite) TestPrefixOverlapInTransaction() {
+	if !suite.embedded {
+		suite.T().Skip("only the embedded ipam takes part in database transactions")
+	}
+	require := suite.Require()
+	ctx := context.Background()
+	namespace := uuid.New()
+
+	// an overlapping prefix fails without failing the transaction it is assigned in.
+	err := suite.db.Transaction(func(tx *gorm.DB) error {
+		txCtx := database.WithTx(ctx, tx)
+		require.NoError(suite.ipam.AssignPrefix(txCtx, namespace, "10.50.0.0/16"))
+		require.ErrorIs(suite.ipam.AssignPrefix(txCtx, namespace, "10.50.1.0/24"), ErrPrefixOverlaps)
+		return suite.ipam.AssignPrefix(txCtx, namespace, "10.51.0.0/16")
+	})
+	require.NoError(err)
+
+	prefixes, err := suite.ipam.(Lister).ListPrefixes(ctx, namespace)
+	require.NoError(err)
+	require.ElementsMatch([]string{"10.50.0.0/16", "10.51.0.0/16"}, prefixes)
 }
 
 func TestIpamTestSuite(t *testing.T) {
 	suite.Run(t, new(IpamTestSuite))
 }
+
+func TestEmbeddedIpamTestSuite(t *testing.T) {
+	suite.Run(t, &IpamTestSuite{embedded: true})
+}
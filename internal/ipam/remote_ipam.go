@@ -0,0 +1,181 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/google/uuid"
+	apiv1 "github.com/metal-stack/go-ipam/api/v1"
+	"github.com/metal-stack/go-ipam/api/v1/apiv1connect"
+	"go.uber.org/zap"
+)
+
+var _ IPAM = &RemoteIPAM{}   // type check the interface is implemented.
+var _ Lister = &RemoteIPAM{} // type check the interface is implemented.
+
+// RemoteIPAM implements IPAM using a go-ipam grpc service.
+type RemoteIPAM struct {
+	logger *zap.SugaredLogger
+	client apiv1connect.IpamServiceClient
+}
+
+// NewRemoteIPAM creates a RemoteIPAM that connects to the go-ipam service at ipamAddress.
+func NewRemoteIPAM(logger *zap.SugaredLogger, ipamAddress string) *RemoteIPAM {
+	return &RemoteIPAM{
+		logger: logger,
+		client: apiv1connect.NewIpamServiceClient(
+			http.DefaultClient,
+			ipamAddress,
+			connect.WithGRPC(),
+		)}
+}
+
+func (i *RemoteIPAM) CreateNamespace(parent context.Context, namespace uuid.UUID) error {
+	ctx, span := tracer.Start(parent, "CreateNamespace")
+	defer span.End()
+	_, err := i.client.CreateNamespace(ctx, connect.NewRequest(&apiv1.CreateNamespaceRequest{
+		Namespace: uuidToNamespace(namespace),
+	}))
+	return err
+}
+
+func (i *RemoteIPAM) DeleteNamespace(parent context.Context, namespace uuid.UUID) error {
+	ctx, span := tracer.Start(parent, "DeleteNamespace")
+	defer span.End()
+	_, err := i.client.DeleteNamespace(ctx, connect.NewRequest(&apiv1.DeleteNamespaceRequest{
+		Namespace: uuidToNamespace(namespace),
+	}))
+	return err
+}
+
+func (i *RemoteIPAM) AssignSpecificTunnelIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) (string, error) {
+	ctx, span := tracer.Start(parent, "AssignSpecificTunnelIP")
+	defer span.End()
+	if err := validateIP(TunnelIP); err != nil {
+		return "", fmt.Errorf("Address %s is not valid", TunnelIP)
+	}
+	ns := uuidToNamespace(namespace)
+	res, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
+		PrefixCidr: ipamPrefix,
+		Ip:         &TunnelIP,
+		Namespace:  &ns,
+	}))
+	if err != nil {
+		allocationFailures.WithLabelValues("address").Inc()
+		return "", fmt.Errorf("failed to acquire the IPAM address %s: %w", TunnelIP, err)
+	}
+	return res.Msg.Ip.Ip, nil
+}
+
+func (i *RemoteIPAM) AssignFromPool(parent context.Context, namespace uuid.UUID, ipamPrefix string) (string, error) {
+	ctx, span := tracer.Start(parent, "AssignFromPool")
+	defer span.End()
+	ns := uuidToNamespace(namespace)
+	res, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
+		PrefixCidr: ipamPrefix,
+		Namespace:  &ns,
+	}))
+	if err != nil {
+		allocationFailures.WithLabelValues("address").Inc()
+		return "", fmt.Errorf("failed to acquire an IPAM assigned address %w\n", err)
+	}
+	return res.Msg.Ip.Ip, nil
+}
+
+func (i *RemoteIPAM) AssignPrefix(parent context.Context, namespace uuid.UUID, cidr string) error {
+	ctx, span := tracer.Start(parent, "AssignPrefix")
+	defer span.End()
+	cidr, err := cleanCidr(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid prefix requested: %w", err)
+	}
+	ns := uuidToNamespace(namespace)
+	_, originalErr := i.client.CreatePrefix(ctx, connect.NewRequest(&apiv1.CreatePrefixRequest{Cidr: cidr, Namespace: &ns}))
+	if originalErr != nil {
+		// check to see if the prefix had been already created....
+		resp, err := i.client.GetPrefix(ctx, connect.NewRequest(&apiv1.GetPrefixRequest{Cidr: cidr, Namespace: &ns}))
+		if err == nil {
+			// it did exist... so ignore that create error since the prefix was created.
+			if resp.Msg.Prefix.Cidr == cidr && resp.Msg.Prefix.ParentCidr == "" {
+				originalErr = nil
+			}
+		}
+	}
+	if originalErr != nil {
+		allocationFailures.WithLabelValues("prefix").Inc()
+		if connect.CodeOf(originalErr) == connect.CodeInvalidArgument && strings.Contains(originalErr.Error(), "overlaps") {
+			return fmt.Errorf("%w: %s", ErrPrefixOverlaps, originalErr)
+		}
+	}
+	return originalErr
+}
+
+// ReleaseToPool release the ipam address back to the specified prefix
+func (i *RemoteIPAM) ReleaseToPool(ctx context.Context, namespace uuid.UUID, address, cidr string) error {
+	ns := uuidToNamespace(namespace)
+	_, err := i.client.ReleaseIP(ctx, connect.NewRequest(&apiv1.ReleaseIPRequest{
+		Ip:         address,
+		PrefixCidr: cidr,
+		Namespace:  &ns,
+	}))
+
+	if err != nil {
+		return fmt.Errorf("failed to release IPAM address %w", err)
+	}
+	return nil
+}
+
+// ReleasePrefix release the ipam address back to the specified prefix
+func (i *RemoteIPAM) ReleasePrefix(ctx context.Context, namespace uuid.UUID, cidr string) error {
+	ns := uuidToNamespace(namespace)
+	_, err := i.client.DeletePrefix(ctx, connect.NewRequest(&apiv1.DeletePrefixRequest{
+		Cidr:      cidr,
+		Namespace: &ns,
+	}))
+
+	if err != nil {
+		return fmt.Errorf("failed to release IPAM prefix %w", err)
+	}
+	return nil
+}
+
+// ListNamespaces lists the namespaces of the go-ipam service that were created by nexodus.
+func (i *RemoteIPAM) ListNamespaces(parent context.Context) ([]uuid.UUID, error) {
+	ctx, span := tracer.Start(parent, "ListNamespaces")
+	defer span.End()
+	res, err := i.client.ListNamespaces(ctx, connect.NewRequest(&apiv1.ListNamespacesRequest{}))
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []uuid.UUID
+	for _, ns := range res.Msg.Namespace {
+		// skip the go-ipam builtin namespaces
+		if id, err := namespaceToUUID(ns); err == nil {
+			namespaces = append(namespaces, id)
+		}
+	}
+	return namespaces, nil
+}
+
+func (i *RemoteIPAM) ListPrefixes(parent context.Context, namespace uuid.UUID) ([]string, error) {
+	ctx, span := tracer.Start(parent, "ListPrefixes")
+	defer span.End()
+	ns := uuidToNamespace(namespace)
+	res, err := i.client.ListPrefixes(ctx, connect.NewRequest(&apiv1.ListPrefixesRequest{Namespace: &ns}))
+	if err != nil {
+		return nil, err
+	}
+	var prefixes []string
+	for _, prefix := range res.Msg.Prefixes {
+		prefixes = append(prefixes, prefix.Cidr)
+	}
+	return prefixes, nil
+}
+
+// ListAddresses is not supported, the go-ipam service only dumps the addresses of its default namespace.
+func (i *RemoteIPAM) ListAddresses(ctx context.Context, namespace uuid.UUID, cidr string) ([]string, error) {
+	return nil, ErrListingNotSupported
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent records a use of the admin API.
+type AuditEvent struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	// UserID is the id of the admin that made the request.
+	UserID   string `json:"user_id" gorm:"index"`
+	UserName string `json:"user_name"`
+	Method   string `json:"method" example:"DELETE"`
+	// Path is the request path, including the query.
+	Path string `json:"path" example:"/api/admin/devices/aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	// Route is the route the request matched.
+	Route  string `json:"route" example:"/api/admin/devices/:id"`
+	Status int    `json:"status" example:"200"`
+}
@@ -1,12 +1,19 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
 // Device is a unique, end-user device.
-// Devices belong to one User and may be onboarded into an organization
+// Devices belong to one User and may be onboarded into an organization.
+// When several devices advertise the same ChildPrefix, the one with the highest
+// ChildPrefixPriority that is online and healthy is elected to route it, and the
+// prefixes a device was elected for are listed in its ActiveChildPrefix.
+// Hub devices are relays of a hub organization, the other devices of the
+// organization only peer with its hubs.
 type Device struct {
 	Base
 	UserID                   string         `json:"user_id"`
@@ -16,7 +23,10 @@ type Device struct {
 	TunnelIP                 string         `json:"tunnel_ip"`
 	TunnelIpV6               string         `json:"tunnel_ip_v6"`
 	ChildPrefix              pq.StringArray `json:"child_prefix" gorm:"type:text[]" swaggertype:"array,string"`
+	ChildPrefixPriority      int32          `json:"child_prefix_priority"`
+	ActiveChildPrefix        pq.StringArray `json:"active_child_prefix" gorm:"type:text[]" swaggertype:"array,string"`
 	Relay                    bool           `json:"relay"`
+	Hub                      bool           `json:"hub"`
 	Discovery                bool           `json:"discovery"`
 	OrganizationPrefix       string         `json:"organization_prefix"`
 	OrganizationPrefixV6     string         `json:"organization_prefix_v6"`
@@ -27,6 +37,8 @@ type Device struct {
 	Endpoints                []Endpoint     `json:"endpoints" gorm:"type:JSONB; serializer:json"`
 	Revision                 uint64         `json:"revision" gorm:"type:bigserial;index:"`
 	SecurityGroupId          uuid.UUID      `json:"security_group_id"`
+	LastSeen                 *time.Time     `json:"last_seen"`
+	Online                   bool           `json:"online"`
 }
 
 // AddDevice is the information needed to add a new Device.
@@ -37,7 +49,9 @@ type AddDevice struct {
 	TunnelIP                 string     `json:"tunnel_ip" example:"1.2.3.4"`
 	TunnelIpV6               string     `json:"tunnel_ip_v6" example:"200::1"`
 	ChildPrefix              []string   `json:"child_prefix" example:"172.16.42.0/24"`
+	ChildPrefixPriority      int32      `json:"child_prefix_priority" example:"100"`
 	Relay                    bool       `json:"relay"`
+	Hub                      bool       `json:"hub"`
 	Discovery                bool       `json:"discovery"`
 	EndpointLocalAddressIPv4 string     `json:"endpoint_local_address_ip4" example:"1.2.3.4"`
 	SymmetricNat             bool       `json:"symmetric_nat"`
@@ -49,11 +63,21 @@ type AddDevice struct {
 
 // UpdateDevice is the information needed to update a Device.
 type UpdateDevice struct {
-	OrganizationID           uuid.UUID  `json:"organization_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
-	ChildPrefix              []string   `json:"child_prefix" example:"172.16.42.0/24"`
+	OrganizationID uuid.UUID `json:"organization_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	ChildPrefix    []string  `json:"child_prefix" example:"172.16.42.0/24"`
+	// ChildPrefixPriority is left unchanged when it is not set, it can be set to 0.
+	ChildPrefixPriority      *int32     `json:"child_prefix_priority,omitempty" example:"100" extensions:"x-nullable"`
 	EndpointLocalAddressIPv4 string     `json:"endpoint_local_address_ip4" example:"1.2.3.4"`
 	SymmetricNat             bool       `json:"symmetric_nat"`
 	Hostname                 string     `json:"hostname" example:"myhost"`
 	Endpoints                []Endpoint `json:"endpoints" gorm:"type:JSONB; serializer:json"`
 	Revision                 *uint64    `json:"revision"`
 }
+
+// DeviceCompaction records the highest revision of the deleted devices of an organization that
+// have been removed from the database. The device watches can't resume from an older revision.
+type DeviceCompaction struct {
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;primary_key"`
+	Revision       uint64    `json:"revision"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -106,3 +106,60 @@ func NewNotAllowedError(reason string) NotAllowedError {
 		},
 	}
 }
+
+// PrefixConflictError is returned in the body of an HTTP 409 when a prefix overlaps one that is already in use
+type PrefixConflictError struct {
+	BaseError
+	Prefix string `json:"prefix" example:"172.16.42.0/24"`
+	// ConflictsWith is empty when the prefix conflicts with a prefix of a peer organization,
+	// which is not disclosed.
+	ConflictsWith string `json:"conflicts_with,omitempty" example:"172.16.0.0/16"`
+	// DeviceID is the device that the conflicting prefix is routed to, it is empty when the
+	// prefix conflicts with the organization cidr.
+	DeviceID string `json:"device_id,omitempty" example:"a1fae5de-dd96-4b20-8362-95f6a574c4b1"`
+}
+
+func NewPrefixConflictError(prefix string, conflictsWith string, deviceID string) PrefixConflictError {
+	return PrefixConflictError{
+		Prefix:        prefix,
+		ConflictsWith: conflictsWith,
+		DeviceID:      deviceID,
+		BaseError: BaseError{
+			Error: "prefix conflicts with a prefix already in use",
+		},
+	}
+}
+
+// ExpiredError is returned in the body of an HTTP 410 when the requested revision is older than the
+// revisions the server can resume from, the client has to list the resources again.
+type ExpiredError struct {
+	BaseError
+	// CompactedRevision is the highest revision that was removed, the client can resume from it or later revisions.
+	CompactedRevision uint64 `json:"compacted_revision"`
+}
+
+func NewExpiredError(compactedRevision uint64) ExpiredError {
+	return ExpiredError{
+		CompactedRevision: compactedRevision,
+		BaseError: BaseError{
+			Error: "revision expired",
+		},
+	}
+}
+
+// QuotaExceededError is returned in the body of an HTTP 403 when creating a resource would exceed a quota
+type QuotaExceededError struct {
+	BaseError
+	Quota string `json:"quota" example:"devices_per_organization"`
+	Limit int    `json:"limit" example:"100"`
+}
+
+func NewQuotaExceededError(quota string, limit int) QuotaExceededError {
+	return QuotaExceededError{
+		Quota: quota,
+		Limit: limit,
+		BaseError: BaseError{
+			Error: "quota exceeded",
+		},
+	}
+}
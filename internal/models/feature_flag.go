@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// FeatureFlag configures who a feature flag is enabled for, the flags that are not configured use
+// their default. A flag is enabled for a user when it is enabled for everyone, when the user or one
+// of their organizations is targeted, or when the user falls in the rollout percentage.
+type FeatureFlag struct {
+	Name          string         `json:"name" gorm:"primary_key"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	Enabled       bool           `json:"enabled"`
+	Organizations pq.StringArray `json:"organizations" gorm:"type:text[]" swaggertype:"array,string"`
+	Users         pq.StringArray `json:"users" gorm:"type:text[]" swaggertype:"array,string"`
+	Percentage    int            `json:"percentage"`
+}
+
+// UpdateFeatureFlag is the information needed to configure a FeatureFlag.
+type UpdateFeatureFlag struct {
+	Enabled       bool        `json:"enabled"`
+	Organizations []uuid.UUID `json:"organizations" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	Users         []string    `json:"users" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	// Percentage of the users the flag is rolled out to, from 0 to 100.
+	Percentage int `json:"percentage" example:"10"`
+}
@@ -21,32 +21,41 @@ type Organization struct {
 	HubZone         bool      `json:"hub_zone"`
 	Invitations     []*Invitation
 	SecurityGroupId uuid.UUID `json:"security_group_id"`
+	// DeviceExpirySeconds is how long a device can go unseen before it is removed, 0 disables expiry.
+	DeviceExpirySeconds int64 `json:"device_expiry_seconds"`
+	// DnsSuffix is the domain the device hostnames are resolved in by the mesh dns, empty uses
+	// <name>.nexodus.local.
+	DnsSuffix string `json:"dns_suffix"`
 }
 
 // Organization contains Users and their Devices
 type OrganizationJSON struct {
-	ID              uuid.UUID `json:"id"`
-	OwnerID         string    `json:"owner_id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
-	Name            string    `json:"name" example:"zone-red"`
-	Description     string    `json:"description" example:"The Red Zone"`
-	PrivateCidr     bool      `json:"private_cidr"`
-	IpCidr          string    `json:"cidr" example:"172.16.42.0/24"`
-	IpCidrV6        string    `json:"cidr_v6" example:"200::/8"`
-	HubZone         bool      `json:"hub_zone"`
-	SecurityGroupId uuid.UUID `json:"security_group_id"`
+	ID                  uuid.UUID `json:"id"`
+	OwnerID             string    `json:"owner_id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	Name                string    `json:"name" example:"zone-red"`
+	Description         string    `json:"description" example:"The Red Zone"`
+	PrivateCidr         bool      `json:"private_cidr"`
+	IpCidr              string    `json:"cidr" example:"172.16.42.0/24"`
+	IpCidrV6            string    `json:"cidr_v6" example:"200::/8"`
+	HubZone             bool      `json:"hub_zone"`
+	SecurityGroupId     uuid.UUID `json:"security_group_id"`
+	DeviceExpirySeconds int64     `json:"device_expiry_seconds" example:"86400"`
+	DnsSuffix           string    `json:"dns_suffix" example:"red.example.com"`
 }
 
 func (o Organization) MarshalJSON() ([]byte, error) {
 	org := OrganizationJSON{
-		ID:              o.ID,
-		OwnerID:         o.OwnerID,
-		Name:            o.Name,
-		PrivateCidr:     o.PrivateCidr,
-		Description:     o.Description,
-		IpCidr:          o.IpCidr,
-		IpCidrV6:        o.IpCidrV6,
-		HubZone:         o.HubZone,
-		SecurityGroupId: o.SecurityGroupId,
+		ID:                  o.ID,
+		OwnerID:             o.OwnerID,
+		Name:                o.Name,
+		PrivateCidr:         o.PrivateCidr,
+		Description:         o.Description,
+		IpCidr:              o.IpCidr,
+		IpCidrV6:            o.IpCidrV6,
+		HubZone:             o.HubZone,
+		SecurityGroupId:     o.SecurityGroupId,
+		DeviceExpirySeconds: o.DeviceExpirySeconds,
+		DnsSuffix:           o.DnsSuffix,
 	}
 	return json.Marshal(org)
 }
@@ -62,11 +71,31 @@ func (z *Organization) BeforeCreate(tx *gorm.DB) error {
 }
 
 type AddOrganization struct {
-	Name            string    `json:"name" example:"zone-red"`
-	Description     string    `json:"description" example:"The Red Zone"`
-	PrivateCidr     bool      `json:"private_cidr"`
-	IpCidr          string    `json:"cidr" example:"172.16.42.0/24"`
-	IpCidrV6        string    `json:"cidr_v6" example:"0200::/8"`
-	HubZone         bool      `json:"hub_zone"`
-	SecurityGroupId uuid.UUID `json:"security_group_id"`
+	Name                string    `json:"name" example:"zone-red"`
+	Description         string    `json:"description" example:"The Red Zone"`
+	PrivateCidr         bool      `json:"private_cidr"`
+	IpCidr              string    `json:"cidr" example:"172.16.42.0/24"`
+	IpCidrV6            string    `json:"cidr_v6" example:"0200::/8"`
+	HubZone             bool      `json:"hub_zone"`
+	SecurityGroupId     uuid.UUID `json:"security_group_id"`
+	DeviceExpirySeconds int64     `json:"device_expiry_seconds" example:"86400"`
+	DnsSuffix           string    `json:"dns_suffix" example:"red.example.com"`
+}
+
+// UpdateOrganization is the information needed to update an Organization.
+type UpdateOrganization struct {
+	Description string `json:"description" example:"The Red Zone"`
+	// IpCidr and IpCidrV6 can only grow the organization prefixes, they must contain the current ones.
+	IpCidr   string `json:"cidr" example:"172.16.0.0/16"`
+	IpCidrV6 string `json:"cidr_v6" example:"0200::/8"`
+	// DnsSuffix replaces the mesh dns suffix, "." resets it to the default.
+	DnsSuffix string `json:"dns_suffix" example:"red.example.com"`
+	// DeviceExpirySeconds replaces how long a device can go unseen before it is removed, 0 disables expiry.
+	DeviceExpirySeconds *int64 `json:"device_expiry_seconds,omitempty" example:"86400" extensions:"x-nullable"`
+}
+
+// RenumberOrganization is the information needed to move an Organization to new prefixes.
+type RenumberOrganization struct {
+	IpCidr   string `json:"cidr" example:"172.17.0.0/24"`
+	IpCidrV6 string `json:"cidr_v6" example:"0300::/8"`
 }
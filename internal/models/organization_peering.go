@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// OrganizationPeering connects the meshes of two organizations. Each side exports some of its
+// devices, by id or by the prefixes their addresses fall in, and once the owners of both
+// organizations have approved the peering the exported devices are listed as peers of the devices
+// of the other organization.
+type OrganizationPeering struct {
+	Base
+	// OrganizationID is the organization that requested the peering, it approves it by creating it.
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	// PeerOrganizationID is the organization that has to approve the peering.
+	PeerOrganizationID   uuid.UUID      `json:"peer_organization_id" gorm:"type:uuid;index"`
+	ExportedDevices      pq.StringArray `json:"exported_devices" gorm:"type:text[]" swaggertype:"array,string"`
+	ExportedPrefixes     pq.StringArray `json:"exported_prefixes" gorm:"type:text[]" swaggertype:"array,string"`
+	PeerExportedDevices  pq.StringArray `json:"peer_exported_devices" gorm:"type:text[]" swaggertype:"array,string"`
+	PeerExportedPrefixes pq.StringArray `json:"peer_exported_prefixes" gorm:"type:text[]" swaggertype:"array,string"`
+	PeerApproved         bool           `json:"peer_approved"`
+}
+
+// AddOrganizationPeering is the information needed to request a peering with another organization.
+type AddOrganizationPeering struct {
+	PeerOrganizationID uuid.UUID `json:"peer_organization_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	// ExportedDevices are the ids of the devices exported to the peer organization.
+	ExportedDevices []string `json:"exported_devices" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	// ExportedPrefixes export the devices whose tunnel address or child prefixes are in one of them.
+	ExportedPrefixes []string `json:"exported_prefixes" example:"100.100.0.0/24"`
+}
+
+// UpdateOrganizationPeering is the information needed to update the side of a peering owned by an
+// organization. The exports are left unchanged when they are not set.
+type UpdateOrganizationPeering struct {
+	ExportedDevices  []string `json:"exported_devices,omitempty" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	ExportedPrefixes []string `json:"exported_prefixes,omitempty" example:"100.100.0.0/24"`
+	// Approved approves the peering, only the peer organization can set it.
+	Approved bool `json:"approved"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ConnectivityDirect means the devices have a healthy direct wireguard session
+	ConnectivityDirect = "direct"
+	// ConnectivityRelayed means the devices have a healthy session through the organization's relay node
+	ConnectivityRelayed = "relayed"
+	// ConnectivityBroken means the devices do not have a healthy session
+	ConnectivityBroken = "broken"
+)
+
+// PeerStatus is the state of a device's connection to one of its peers, as seen by the device.
+type PeerStatus struct {
+	PublicKey       string `json:"public_key"`
+	Endpoint        string `json:"endpoint" example:"1.2.3.4:51820"`
+	Relayed         bool   `json:"relayed"`
+	Healthy         bool   `json:"healthy"`
+	LatestHandshake string `json:"latest_handshake"`
+	TxBytes         int64  `json:"tx_bytes" format:"int64"`
+	RxBytes         int64  `json:"rx_bytes" format:"int64"`
+}
+
+// DevicePeerStatus is the most recent peer status summary reported by a device.
+type DevicePeerStatus struct {
+	DeviceID       uuid.UUID    `json:"device_id" gorm:"type:uuid;primary_key"`
+	OrganizationID uuid.UUID    `json:"organization_id" gorm:"type:uuid;index"`
+	Peers          []PeerStatus `json:"peers" gorm:"type:JSONB; serializer:json"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// ReportPeerStatus is the peer status summary uploaded by a device.
+type ReportPeerStatus struct {
+	Peers []PeerStatus `json:"peers"`
+}
+
+// Connectivity is the state of the connection from one device to another.
+type Connectivity struct {
+	SourceDeviceID      uuid.UUID `json:"source_device_id"`
+	DestinationDeviceID uuid.UUID `json:"destination_device_id"`
+	Status              string    `json:"status" example:"direct"`
+	Endpoint            string    `json:"endpoint" example:"1.2.3.4:51820"`
+	LatestHandshake     string    `json:"latest_handshake"`
+	ReportedAt          time.Time `json:"reported_at"`
+}
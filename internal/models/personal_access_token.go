@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, it tells them apart from the JWTs.
+const PersonalAccessTokenPrefix = "nexpat_"
+
+// PersonalAccessTokenScopes are the scopes a personal access token can be granted, the same as the
+// OAUTH scopes of the JWTs.
+var PersonalAccessTokenScopes = []string{
+	"read:organizations",
+	"write:organizations",
+	"read:users",
+	"write:users",
+	"read:devices",
+	"write:devices",
+}
+
+// PersonalAccessToken authenticates the API requests of scripts and automation on behalf of a user.
+// Only a hash of the token is stored, the token itself is returned once when it is created.
+type PersonalAccessToken struct {
+	Base
+	UserID      string         `json:"user_id" gorm:"index"`
+	Description string         `json:"description"`
+	Scopes      pq.StringArray `json:"scopes" gorm:"type:text[]" swaggertype:"array,string"`
+	Expiry      time.Time      `json:"expiry"`
+	TokenHash   string         `json:"-" gorm:"uniqueIndex"`
+	// Token is only returned by the create request.
+	Token string `json:"token,omitempty" gorm:"-"`
+}
+
+// AddPersonalAccessToken is the information needed to create a PersonalAccessToken.
+type AddPersonalAccessToken struct {
+	Description string   `json:"description" example:"ci pipeline"`
+	Scopes      []string `json:"scopes" example:"read:devices"`
+	// Expiry defaults to 90 days from now, and can be at most a year from now.
+	Expiry *time.Time `json:"expiry,omitempty"`
+}
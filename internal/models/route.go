@@ -0,0 +1,25 @@
+package models
+
+import "github.com/google/uuid"
+
+const (
+	// RouteKindTunnel is the tunnel address of a device.
+	RouteKindTunnel = "tunnel"
+	// RouteKindChildPrefix is a prefix a device routes to the network behind it.
+	RouteKindChildPrefix = "child_prefix"
+	// RouteKindRelay is an organization cidr that a relay forwards for the devices that can't peer directly.
+	RouteKindRelay = "relay"
+)
+
+// Route is a prefix that a device of an organization advertises to its peers. When several devices
+// advertise the same child prefix, only the Active one is routed to.
+type Route struct {
+	Prefix   string    `json:"prefix" example:"172.16.42.0/24"`
+	Kind     string    `json:"kind" example:"child_prefix"`
+	DeviceID uuid.UUID `json:"device_id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	Hostname string    `json:"hostname" example:"myhost"`
+	TunnelIP string    `json:"tunnel_ip" example:"100.64.0.1"`
+	Online   bool      `json:"online"`
+	Active   bool      `json:"active"`
+	Priority int32     `json:"priority" example:"100"`
+}
@@ -47,3 +47,12 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// AdminUser is a user as seen by the admin API, with the organizations it is a member of and its devices.
+type AdminUser struct {
+	ID            string      `json:"id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	UserName      string      `json:"username" example:"admin"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Organizations []uuid.UUID `json:"organizations"`
+	Devices       []uuid.UUID `json:"devices"`
+}
@@ -5,3 +5,8 @@ type WatchEvent struct {
 	Type  string      `json:"type"`
 	Value interface{} `json:"value,omitempty"`
 }
+
+// WatchBookmark is the value of the bookmark events, the watch has sent all the changes up to the revision.
+type WatchBookmark struct {
+	Revision uint64 `json:"revision"`
+}
@@ -0,0 +1,43 @@
+package nexodus
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JoinedOrganization describes an organization nexd has joined and the tunnel interface serving it.
+type JoinedOrganization struct {
+	Id         string
+	Name       string
+	Interface  string
+	TunnelIP   string
+	TunnelIpV6 string
+}
+
+func (ac *NexdCtl) ListOrganizations(_ string, result *string) error {
+	var orgs []JoinedOrganization
+	for _, ax := range append([]*Nexodus{ac.ax}, ac.ax.members...) {
+		if ax.org == nil {
+			continue
+		}
+		ax.deviceCacheLock.RLock()
+		name := ax.org.Name
+		ax.deviceCacheLock.RUnlock()
+		orgs = append(orgs, JoinedOrganization{
+			Id:         ax.org.Id,
+			Name:       name,
+			Interface:  ax.tunnelIface,
+			TunnelIP:   ax.TunnelIP,
+			TunnelIpV6: ax.TunnelIpV6,
+		})
+	}
+
+	orgsJSON, err := json.Marshal(orgs)
+	if err != nil {
+		return fmt.Errorf("error marshalling list of organizations: %w", err)
+	}
+
+	*result = string(orgsJSON)
+
+	return nil
+}
@@ -14,7 +14,8 @@ type NexdCtl struct {
 
 func (ac *NexdCtl) Status(_ string, result *string) error {
 	var statusStr string
-	switch ac.ax.status {
+	status, statusMsg := ac.ax.getStatus()
+	switch status {
 	case NexdStatusStarting:
 		statusStr = "Starting"
 	case NexdStatusAuth:
@@ -25,8 +26,8 @@ func (ac *NexdCtl) Status(_ string, result *string) error {
 		statusStr = "Unknown"
 	}
 	res := fmt.Sprintf("Status: %s\n", statusStr)
-	if len(ac.ax.statusMsg) > 0 {
-		res += ac.ax.statusMsg
+	if len(statusMsg) > 0 {
+		res += statusMsg
 	}
 	*result = res
 	return nil
@@ -0,0 +1,230 @@
+package nexodus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	meshDnsDefaultDomain = "nexodus.local"
+	meshDnsPort          = 53
+	meshDnsTTL           = 60
+	// large enough for a query, a device name and a handful of addresses
+	meshDnsMaxMessage = 1232
+)
+
+type meshDns struct {
+	// the address the responder is listening on
+	addr string
+	// the domain the responder is resolving the device hostnames in
+	suffix string
+	conn   net.PacketConn
+}
+
+// meshDnsSuffix returns the domain the device hostnames of the organization are resolved in.
+// assumes deviceCacheLock is held, the organization is refreshed under it.
+func (ax *Nexodus) meshDnsSuffix() string {
+	if ax.org.DnsSuffix != "" {
+		return strings.ToLower(strings.TrimSuffix(ax.org.DnsSuffix, "."))
+	}
+	return dnsLabel(ax.org.Name) + "." + meshDnsDefaultDomain
+}
+
+// dnsLabel converts a name to a dns label, in lower case with the characters other than letters,
+// digits and hyphens replaced by hyphens.
+func dnsLabel(name string) string {
+	label := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+	label = strings.Trim(label, "-")
+	if len(label) > 63 {
+		label = strings.TrimRight(label[:63], "-")
+	}
+	return label
+}
+
+// reconcileMeshDns starts the dns responder on the tunnel address, and restarts it when the address
+// or the dns suffix of the organization changes. assumes deviceCacheLock is held.
+func (ax *Nexodus) reconcileMeshDns() {
+	if !ax.meshDnsEnabled || ax.TunnelIP == "" {
+		return
+	}
+	addr := net.JoinHostPort(ax.TunnelIP, fmt.Sprint(meshDnsPort))
+	suffix := ax.meshDnsSuffix()
+	if ax.meshDns.addr == addr && ax.meshDns.suffix == suffix {
+		return
+	}
+	ax.stopMeshDns()
+
+	var conn net.PacketConn
+	var err error
+	if ax.userspaceMode {
+		conn, err = ax.userspaceNet.ListenUDPAddrPort(netip.MustParseAddrPort(addr))
+	} else {
+		conn, err = net.ListenPacket("udp", addr)
+	}
+	if err != nil {
+		ax.logger.Errorf("Failed to start the mesh dns on %s: %v", addr, err)
+		return
+	}
+	ax.meshDns = meshDns{addr: addr, suffix: suffix, conn: conn}
+
+	ax.logger.Infof("Mesh dns is resolving the device hostnames in %s on %s", suffix, addr)
+	if !ax.userspaceMode {
+		if err := ax.configureMeshDnsOS(suffix, ax.TunnelIP); err != nil {
+			ax.logger.Warnf("Failed to configure the host to use the mesh dns for %s: %v", suffix, err)
+		}
+	}
+
+	util.GoWithWaitGroup(ax.nexWg, func() {
+		ax.serveMeshDns(conn, suffix)
+	})
+}
+
+// reconcileOrganization refreshes the name and dns suffix of the organization, which can be
+// updated after nexd joined it, and restarts the mesh dns when its suffix changes.
+func (ax *Nexodus) reconcileOrganization(ctx context.Context) {
+	if !ax.meshDnsEnabled {
+		return
+	}
+	org, _, err := ax.client.OrganizationsApi.GetOrganizations(ctx, ax.org.Id).Execute()
+	if err != nil {
+		ax.logger.Debugf("Failed to refresh the organization: %v", err)
+		return
+	}
+	ax.deviceCacheLock.Lock()
+	defer ax.deviceCacheLock.Unlock()
+	ax.org.Name = org.Name
+	ax.org.DnsSuffix = org.DnsSuffix
+	ax.reconcileMeshDns()
+}
+
+func (ax *Nexodus) stopMeshDns() {
+	if ax.meshDns.conn != nil {
+		_ = ax.meshDns.conn.Close()
+	}
+	ax.meshDns = meshDns{}
+}
+
+// serveMeshDns answers the queries received on conn until it is closed.
+func (ax *Nexodus) serveMeshDns(conn net.PacketConn, suffix string) {
+	buf := make([]byte, meshDnsMaxMessage)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		response, err := ax.meshDnsAnswer(buf[:n], suffix)
+		if err != nil {
+			ax.logger.Debugf("invalid dns query from %s: %v", addr, err)
+			continue
+		}
+		if _, err := conn.WriteTo(response, addr); err != nil {
+			ax.logger.Debugf("failed to send the dns response to %s: %v", addr, err)
+		}
+	}
+}
+
+// meshDnsAnswer builds the response to a dns query for <hostname>.<suffix> from the device cache.
+// The suffix itself exists without records, names outside of it are refused, the responder does
+// not recurse.
+func (ax *Nexodus) meshDnsAnswer(query []byte, suffix string) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	response := dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		Authoritative:      true,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: false,
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+	host, inZone := strings.CutSuffix(name, "."+suffix)
+	var addrs []netip.Addr
+	switch {
+	case name == suffix:
+		// the apex has no records of its own, answer with no data rather than a refusal.
+	case !inZone:
+		response.RCode = dnsmessage.RCodeRefused
+	case strings.Contains(host, "."):
+		response.RCode = dnsmessage.RCodeNameError
+	default:
+		found := false
+		ax.deviceCacheIterRead(func(d deviceCacheEntry) {
+			// the devices imported from peered organizations are resolved by their own organization.
+			if d.device.OrganizationId != ax.org.Id || dnsLabel(d.device.Hostname) != host {
+				return
+			}
+			found = true
+			addrs = append(addrs, meshDnsAddrs(d.device, question.Type)...)
+		})
+		if !found {
+			response.RCode = dnsmessage.RCodeNameError
+		}
+	}
+
+	builder := dnsmessage.NewBuilder(make([]byte, 0, meshDnsMaxMessage), response)
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		rh := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: meshDnsTTL}
+		if addr.Is4() {
+			err = builder.AResource(rh, dnsmessage.AResource{A: addr.As4()})
+		} else {
+			err = builder.AAAAResource(rh, dnsmessage.AAAAResource{AAAA: addr.As16()})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
+
+// meshDnsAddrs returns the tunnel addresses of a device that answer a query of the given type.
+func meshDnsAddrs(device public.ModelsDevice, qtype dnsmessage.Type) []netip.Addr {
+	var ip string
+	switch qtype {
+	case dnsmessage.TypeA:
+		ip = device.TunnelIp
+	case dnsmessage.TypeAAAA:
+		ip = device.TunnelIpV6
+	default:
+		return nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	return []netip.Addr{addr}
+}
@@ -0,0 +1,10 @@
+//go:build darwin
+
+package nexodus
+
+import "fmt"
+
+// configureMeshDnsOS for darwin build purposes, configuring the host resolver is currently only supported on linux
+func (ax *Nexodus) configureMeshDnsOS(suffix string, server string) error {
+	return fmt.Errorf("resolve %s with the dns server %s to use the mesh dns", suffix, server)
+}
@@ -0,0 +1,20 @@
+//go:build linux
+
+package nexodus
+
+import "fmt"
+
+// configureMeshDnsOS sends the queries for the mesh dns suffix to the responder on the tunnel
+// interface with systemd-resolved split dns, the other queries keep using the host resolvers.
+func (ax *Nexodus) configureMeshDnsOS(suffix string, server string) error {
+	if !IsCommandAvailable("resolvectl") {
+		return fmt.Errorf("resolvectl not found, resolve %s with the dns server %s to use the mesh dns", suffix, server)
+	}
+	if _, err := RunCommand("resolvectl", "dns", ax.tunnelIface, server); err != nil {
+		return err
+	}
+	if _, err := RunCommand("resolvectl", "domain", ax.tunnelIface, "~"+suffix); err != nil {
+		return err
+	}
+	return nil
+}
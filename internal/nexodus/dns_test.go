@@ -0,0 +1,63 @@
+package nexodus
+
+import (
+	"testing"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestMeshDnsAnswer(t *testing.T) {
+	require := require.New(t)
+
+	ax := &Nexodus{
+		org:    &public.ModelsOrganization{Id: "org-a", Name: "Org A"},
+		logger: zap.NewNop().Sugar(),
+		deviceCache: map[string]deviceCacheEntry{
+			"key-1": {device: public.ModelsDevice{OrganizationId: "org-a", Hostname: "Web_1", TunnelIp: "100.64.0.1", TunnelIpV6: "200::1"}},
+			"key-2": {device: public.ModelsDevice{OrganizationId: "org-b", Hostname: "db", TunnelIp: "100.64.0.2"}},
+		},
+	}
+	suffix := ax.meshDnsSuffix()
+	require.Equal("org-a.nexodus.local", suffix)
+
+	ask := func(name string, qtype dnsmessage.Type) dnsmessage.Message {
+		query, err := (&dnsmessage.Message{
+			Header: dnsmessage.Header{ID: 42, RecursionDesired: true},
+			Questions: []dnsmessage.Question{{
+				Name:  dnsmessage.MustNewName(name),
+				Type:  qtype,
+				Class: dnsmessage.ClassINET,
+			}},
+		}).Pack()
+		require.NoError(err)
+		response, err := ax.meshDnsAnswer(query, suffix)
+		require.NoError(err)
+		var msg dnsmessage.Message
+		require.NoError(msg.Unpack(response))
+		require.Equal(uint16(42), msg.ID)
+		return msg
+	}
+
+	msg := ask("web-1.org-a.nexodus.local.", dnsmessage.TypeA)
+	require.Equal(dnsmessage.RCodeSuccess, msg.RCode)
+	require.Len(msg.Answers, 1)
+	require.Equal([4]byte{100, 64, 0, 1}, msg.Answers[0].Body.(*dnsmessage.AResource).A)
+
+	msg = ask("WEB-1.org-a.nexodus.local.", dnsmessage.TypeAAAA)
+	require.Equal(dnsmessage.RCodeSuccess, msg.RCode)
+	require.Len(msg.Answers, 1)
+
+	// the devices of other organizations are not resolved in this one.
+	require.Equal(dnsmessage.RCodeNameError, ask("db.org-a.nexodus.local.", dnsmessage.TypeA).RCode)
+	require.Equal(dnsmessage.RCodeNameError, ask("a.web-1.org-a.nexodus.local.", dnsmessage.TypeA).RCode)
+
+	// the apex exists without records.
+	msg = ask("org-a.nexodus.local.", dnsmessage.TypeSOA)
+	require.Equal(dnsmessage.RCodeSuccess, msg.RCode)
+	require.Empty(msg.Answers)
+
+	require.Equal(dnsmessage.RCodeRefused, ask("example.com.", dnsmessage.TypeA).RCode)
+}
@@ -0,0 +1,113 @@
+package nexodus
+
+import (
+	"net"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/stun"
+	"github.com/nexodus-io/nexodus/internal/util"
+)
+
+// exitNodePrefixes are the default routes advertised by an exit node.
+var exitNodePrefixes = []string{"0.0.0.0/0", "::/0"}
+
+// isSelectedExitNode returns true if the device is the exit node chosen with --use-exit-node, which
+// can name the device by its id, hostname or tunnel address.
+func (ax *Nexodus) isSelectedExitNode(device public.ModelsDevice) bool {
+	if ax.useExitNode == "" {
+		return false
+	}
+	return ax.useExitNode == device.Id ||
+		ax.useExitNode == device.Hostname ||
+		ax.useExitNode == device.TunnelIp ||
+		ax.useExitNode == device.TunnelIpV6
+}
+
+// peerChildPrefixes returns the child prefixes routed through a peer. The default routes of exit
+// nodes are only routed through the exit node this device uses.
+func (ax *Nexodus) peerChildPrefixes(device public.ModelsDevice) []string {
+	var prefixes []string
+	for _, prefix := range device.ActiveChildPrefix {
+		if util.IsDefaultIPRoute(prefix) && !ax.isSelectedExitNode(device) {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// exitNodePeer returns the peer configuration of the exit node this device uses, if it is a peer.
+// assumes deviceCacheLock is held.
+func (ax *Nexodus) exitNodePeer() (wgPeerConfig, bool) {
+	for _, peer := range ax.wgConfig.Peers {
+		for _, allowedIP := range peer.AllowedIPs {
+			if util.IsDefaultIPRoute(allowedIP) {
+				return peer, true
+			}
+		}
+	}
+	return wgPeerConfig{}, false
+}
+
+// resolveExitNodeHosts resolves the host names of the api server and the stun servers, which have to
+// stay reachable over the underlay network, until they resolved once, before the traffic to them could
+// go through the exit node. It runs without deviceCacheLock held, as the lookups can block.
+func (ax *Nexodus) resolveExitNodeHosts() {
+	if ax.useExitNode == "" || ax.userspaceMode {
+		return
+	}
+	ax.deviceCacheLock.RLock()
+	resolved := len(ax.exitNodeHosts) > 0
+	ax.deviceCacheLock.RUnlock()
+	if resolved {
+		return
+	}
+
+	hosts := []string{ax.controllerURL.Hostname()}
+	for _, server := range stun.Servers() {
+		if host, _, err := net.SplitHostPort(server); err == nil {
+			hosts = append(hosts, host)
+		}
+	}
+	var resolvedIPs []net.IP
+	for _, host := range hosts {
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			ax.logger.Debugf("failed to resolve %s: %v", host, err)
+			continue
+		}
+		resolvedIPs = append(resolvedIPs, ips...)
+	}
+
+	ax.deviceCacheLock.Lock()
+	defer ax.deviceCacheLock.Unlock()
+	ax.exitNodeHosts = resolvedIPs
+}
+
+// underlayDestinations returns the addresses that have to stay reachable over the underlay network
+// while the default route points at the exit node: the addresses resolved by resolveExitNodeHosts and
+// the wireguard endpoints of the peers. assumes deviceCacheLock is held.
+func (ax *Nexodus) underlayDestinations() []net.IP {
+	ips := append([]net.IP{}, ax.exitNodeHosts...)
+	for _, peer := range ax.wgConfig.Peers {
+		host, _, err := net.SplitHostPort(peer.Endpoint)
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// reconcileExitNode points the default route at the exit node this device uses, or restores it
+// when the exit node is gone.
+func (ax *Nexodus) reconcileExitNode() {
+	if ax.useExitNode == "" || ax.userspaceMode {
+		return
+	}
+	if err := ax.reconcileExitNodeOS(); err != nil {
+		ax.logger.Errorf("failed to set up the routes through the exit node: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build darwin
+
+package nexodus
+
+import "fmt"
+
+type exitNodeState struct{}
+
+// reconcileExitNodeOS for darwin build purposes, exit nodes are currently only supported on linux
+func (ax *Nexodus) reconcileExitNodeOS() error {
+	return fmt.Errorf("using an exit node is only supported on linux")
+}
+
+func (ax *Nexodus) removeExitNodeRoutes() error {
+	return nil
+}
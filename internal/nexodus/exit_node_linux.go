@@ -0,0 +1,178 @@
+//go:build linux
+
+package nexodus
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/nexodus-io/nexodus/internal/util"
+	"github.com/vishvananda/netlink"
+)
+
+// exitNodeSplitRoutes cover the address space of each family with prefixes that are more specific
+// than a default route, so the traffic goes to the exit node without replacing the default route of
+// the underlay network.
+var exitNodeSplitRoutes = map[int][]string{
+	netlink.FAMILY_V4: {"0.0.0.0/1", "128.0.0.0/1"},
+	netlink.FAMILY_V6: {"::/1", "8000::/1"},
+}
+
+type exitNodeState struct {
+	// the routes installed to use the exit node, keyed by destination
+	routes map[string]netlink.Route
+}
+
+// reconcileExitNodeOS routes the traffic through the exit node peer while keeping the addresses from
+// underlayDestinations on the underlay network. assumes deviceCacheLock is held.
+func (ax *Nexodus) reconcileExitNodeOS() error {
+	exitNode, ok := ax.exitNodePeer()
+	if !ok {
+		return ax.removeExitNodeRoutes()
+	}
+
+	link, err := netlink.LinkByName(ax.tunnelIface)
+	if err != nil {
+		return fmt.Errorf("failed to lookup netlink device %s: %w", ax.tunnelIface, err)
+	}
+	tunnelIndex := link.Attrs().Index
+
+	var want []netlink.Route
+	for _, ip := range ax.underlayDestinations() {
+		route, err := underlayRoute(ip, tunnelIndex)
+		if err != nil {
+			ax.logger.Debugf("no underlay route to %s: %v", ip, err)
+			continue
+		}
+		if route != nil {
+			want = append(want, *route)
+		}
+	}
+	for _, allowedIP := range exitNode.AllowedIPs {
+		if !util.IsDefaultIPRoute(allowedIP) {
+			continue
+		}
+		family := netlink.FAMILY_V4
+		if util.IsIPv6Prefix(allowedIP) {
+			if !ax.ipv6Supported {
+				continue
+			}
+			family = netlink.FAMILY_V6
+		}
+		for _, prefix := range exitNodeSplitRoutes[family] {
+			dst, err := ParseIPNet(prefix)
+			if err != nil {
+				return err
+			}
+			want = append(want, netlink.Route{
+				LinkIndex: tunnelIndex,
+				Scope:     netlink.SCOPE_UNIVERSE,
+				Dst:       dst,
+			})
+		}
+	}
+
+	if ax.exitNodeState.routes == nil {
+		ax.exitNodeState.routes = map[string]netlink.Route{}
+	}
+	wanted := map[string]struct{}{}
+	// the underlay routes come first so the traffic to them never goes through the exit node.
+	for i := range want {
+		route := want[i]
+		key := route.Dst.String()
+		wanted[key] = struct{}{}
+		if existing, ok := ax.exitNodeState.routes[key]; ok && existing.Equal(route) {
+			continue
+		}
+		if err := netlink.RouteReplace(&route); err != nil {
+			return fmt.Errorf("failed to add the route to %s: %w", key, err)
+		}
+		ax.exitNodeState.routes[key] = route
+	}
+	for key, route := range ax.exitNodeState.routes {
+		if _, ok := wanted[key]; ok {
+			continue
+		}
+		route := route
+		if err := netlink.RouteDel(&route); err != nil {
+			ax.logger.Debugf("failed to delete the route to %s: %v", key, err)
+		}
+		delete(ax.exitNodeState.routes, key)
+	}
+	return nil
+}
+
+// removeExitNodeRoutes deletes the routes installed to use the exit node, the routes through the
+// tunnel first.
+func (ax *Nexodus) removeExitNodeRoutes() error {
+	for _, pass := range []bool{true, false} {
+		for key, route := range ax.exitNodeState.routes {
+			if (route.Gw == nil) != pass {
+				continue
+			}
+			route := route
+			if err := netlink.RouteDel(&route); err != nil {
+				ax.logger.Debugf("failed to delete the route to %s: %v", key, err)
+			}
+			delete(ax.exitNodeState.routes, key)
+		}
+	}
+	return nil
+}
+
+// underlayRoute returns a host route to the ip through the gateway it is reached by outside the
+// tunnel, or nil if it is directly connected.
+func underlayRoute(ip net.IP, tunnelIndex int) (*netlink.Route, error) {
+	routes, err := netlink.RouteGet(ip)
+	if err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no route found")
+	}
+	route := routes[0]
+	if route.LinkIndex == tunnelIndex {
+		// already routed to the exit node, fall back to the default route of the underlay.
+		family := netlink.FAMILY_V6
+		if ip.To4() != nil {
+			family = netlink.FAMILY_V4
+		}
+		def, err := underlayDefaultRoute(family, tunnelIndex)
+		if err != nil {
+			return nil, err
+		}
+		route = *def
+	}
+	if route.Gw == nil {
+		return nil, nil
+	}
+	bits := 128
+	if ip.To4() != nil {
+		bits = 32
+	}
+	return &netlink.Route{
+		LinkIndex: route.LinkIndex,
+		Gw:        route.Gw,
+		Dst:       &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)},
+	}, nil
+}
+
+// underlayDefaultRoute returns the default route of the family that does not use the tunnel.
+func underlayDefaultRoute(family int, tunnelIndex int) (*netlink.Route, error) {
+	routes, err := netlink.RouteList(nil, family)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving netlink routes: %w", err)
+	}
+	for i, route := range routes {
+		if route.LinkIndex == tunnelIndex || route.Gw == nil {
+			continue
+		}
+		if route.Dst == nil {
+			return &routes[i], nil
+		}
+		if ones, _ := route.Dst.Mask.Size(); ones == 0 {
+			return &routes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no default route found")
+}
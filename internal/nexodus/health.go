@@ -0,0 +1,144 @@
+package nexodus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ReadinessCheck is the result of one of the conditions that make up nexd readiness.
+type ReadinessCheck struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	// Organization is set on the checks of the organizations joined after the first one
+	Organization string `json:"organization,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// interfaceByName looks up the tunnel interface, it is replaced by the tests.
+var interfaceByName = net.InterfaceByName
+
+// Readiness is served by /readyz, Ready is only true if all the checks are ready.
+type Readiness struct {
+	Ready  bool             `json:"ready"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// Readiness reports whether nexd is authenticated, has registered its device, has the
+// tunnel interface up and can reach at least one healthy peer or relay, in each of the
+// organizations it joined.
+func (ax *Nexodus) Readiness() Readiness {
+	checks := []ReadinessCheck{
+		ax.authenticatedCheck(),
+		ax.deviceRegisteredCheck(),
+		ax.interfaceUpCheck(),
+		ax.peerConnectivityCheck(),
+	}
+	for _, member := range ax.members {
+		for _, check := range []ReadinessCheck{
+			member.deviceRegisteredCheck(),
+			member.interfaceUpCheck(),
+			member.peerConnectivityCheck(),
+		} {
+			check.Organization = member.org.Id
+			checks = append(checks, check)
+		}
+	}
+	ready := true
+	for _, check := range checks {
+		ready = ready && check.Ready
+	}
+	return Readiness{
+		Ready:  ready,
+		Checks: checks,
+	}
+}
+
+func (ax *Nexodus) authenticatedCheck() ReadinessCheck {
+	check := ReadinessCheck{Name: "authenticated"}
+	status, _ := ax.getStatus()
+	switch status {
+	case NexdStatusRunning:
+		check.Ready = true
+	case NexdStatusAuth:
+		check.Reason = "waiting for the user to complete authentication"
+	default:
+		check.Reason = "nexd is starting"
+	}
+	return check
+}
+
+func (ax *Nexodus) deviceRegisteredCheck() ReadinessCheck {
+	check := ReadinessCheck{Name: "device_registered"}
+	if ax.deviceRegistered.Load() {
+		check.Ready = true
+	} else {
+		check.Reason = "the device has not been registered with the api-server"
+	}
+	return check
+}
+
+func (ax *Nexodus) interfaceUpCheck() ReadinessCheck {
+	check := ReadinessCheck{Name: "interface_up"}
+	if ax.userspaceMode {
+		ax.statusLock.RLock()
+		created := ax.userspaceDev != nil
+		ax.statusLock.RUnlock()
+		if created {
+			check.Ready = true
+		} else {
+			check.Reason = "the userspace wireguard device has not been created"
+		}
+		return check
+	}
+	iface, err := interfaceByName(ax.tunnelIface)
+	if err != nil {
+		check.Reason = fmt.Sprintf("interface %s not found", ax.tunnelIface)
+	} else if iface.Flags&net.FlagUp == 0 {
+		check.Reason = fmt.Sprintf("interface %s is down", ax.tunnelIface)
+	} else {
+		check.Ready = true
+	}
+	return check
+}
+
+func (ax *Nexodus) peerConnectivityCheck() ReadinessCheck {
+	check := ReadinessCheck{Name: "peer_connectivity"}
+	if ax.relay {
+		// a relay is the peer other devices fall back to, it does not need one itself.
+		check.Ready = true
+		return check
+	}
+	peers := 0
+	ax.deviceCacheIterRead(func(d deviceCacheEntry) {
+		if d.device.PublicKey == ax.wireguardPubKey {
+			return
+		}
+		peers++
+		if d.peerHealthy {
+			check.Ready = true
+		}
+	})
+	if peers == 0 {
+		// the only device of the organization has no peers to wait for.
+		check.Ready = true
+	} else if !check.Ready {
+		check.Reason = fmt.Sprintf("none of the %d peers are healthy", peers)
+	}
+	return check
+}
+
+func (ax *Nexodus) healthzHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (ax *Nexodus) readyzHandler(w http.ResponseWriter, _ *http.Request) {
+	readiness := ax.Readiness()
+	w.Header().Set("Content-Type", "application/json")
+	if !readiness.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(readiness)
+}
@@ -0,0 +1,207 @@
+package nexodus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testInterfaces stubs the interface lookups of the readiness checks with the given flags.
+func testInterfaces(t *testing.T, flags map[string]net.Flags) {
+	interfaceByName = func(name string) (*net.Interface, error) {
+		f, ok := flags[name]
+		if !ok {
+			return nil, fmt.Errorf("no such network interface")
+		}
+		return &net.Interface{Name: name, Flags: f}, nil
+	}
+	t.Cleanup(func() {
+		interfaceByName = net.InterfaceByName
+	})
+}
+
+// readyNexodus returns an instance that passes all the readiness checks.
+func readyNexodus(tunnelIface string) *Nexodus {
+	ax := &Nexodus{
+		status:          NexdStatusRunning,
+		tunnelIface:     tunnelIface,
+		wireguardPubKey: "self",
+		org:             &public.ModelsOrganization{Id: "org-" + tunnelIface},
+		logger:          zap.NewNop().Sugar(),
+		deviceCache: map[string]deviceCacheEntry{
+			"self": {device: public.ModelsDevice{PublicKey: "self"}},
+			"peer": {device: public.ModelsDevice{PublicKey: "peer"}, peerHealth: peerHealth{peerHealthy: true}},
+		},
+	}
+	ax.deviceRegistered.Store(true)
+	return ax
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(ax *Nexodus)
+		failed []ReadinessCheck
+	}{
+		{
+			name:  "ready",
+			setup: func(ax *Nexodus) {},
+		},
+		{
+			name: "not authenticated",
+			setup: func(ax *Nexodus) {
+				ax.SetStatus(NexdStatusAuth, "")
+			},
+			failed: []ReadinessCheck{{Name: "authenticated", Reason: "waiting for the user to complete authentication"}},
+		},
+		{
+			name: "starting",
+			setup: func(ax *Nexodus) {
+				ax.SetStatus(NexdStatusStarting, "")
+			},
+			failed: []ReadinessCheck{{Name: "authenticated", Reason: "nexd is starting"}},
+		},
+		{
+			name: "not registered",
+			setup: func(ax *Nexodus) {
+				ax.deviceRegistered.Store(false)
+			},
+			failed: []ReadinessCheck{{Name: "device_registered", Reason: "the device has not been registered with the api-server"}},
+		},
+		{
+			name: "interface down",
+			setup: func(ax *Nexodus) {
+				ax.tunnelIface = "wg-down"
+			},
+			failed: []ReadinessCheck{{Name: "interface_up", Reason: "interface wg-down is down"}},
+		},
+		{
+			name: "interface missing",
+			setup: func(ax *Nexodus) {
+				ax.tunnelIface = "wg-missing"
+			},
+			failed: []ReadinessCheck{{Name: "interface_up", Reason: "interface wg-missing not found"}},
+		},
+		{
+			name: "userspace device not created",
+			setup: func(ax *Nexodus) {
+				ax.userspaceMode = true
+			},
+			failed: []ReadinessCheck{{Name: "interface_up", Reason: "the userspace wireguard device has not been created"}},
+		},
+		{
+			name: "no peers",
+			setup: func(ax *Nexodus) {
+				delete(ax.deviceCache, "peer")
+			},
+		},
+		{
+			name: "no healthy peers",
+			setup: func(ax *Nexodus) {
+				ax.deviceCache["peer"] = deviceCacheEntry{device: public.ModelsDevice{PublicKey: "peer"}}
+			},
+			failed: []ReadinessCheck{{Name: "peer_connectivity", Reason: "none of the 1 peers are healthy"}},
+		},
+		{
+			name: "relay without healthy peers",
+			setup: func(ax *Nexodus) {
+				ax.relay = true
+				ax.deviceCache["peer"] = deviceCacheEntry{device: public.ModelsDevice{PublicKey: "peer"}}
+			},
+		},
+		{
+			name: "hub without healthy peers",
+			setup: func(ax *Nexodus) {
+				ax.relay, ax.hub = true, true
+				ax.deviceCache["peer"] = deviceCacheEntry{device: public.ModelsDevice{PublicKey: "peer"}}
+			},
+		},
+		{
+			name: "member not registered",
+			setup: func(ax *Nexodus) {
+				member := readyNexodus("wg1")
+				member.member, member.parent = true, ax
+				member.deviceRegistered.Store(false)
+				ax.members = []*Nexodus{member}
+			},
+			failed: []ReadinessCheck{{Name: "device_registered", Organization: "org-wg1", Reason: "the device has not been registered with the api-server"}},
+		},
+		{
+			name: "member without healthy peers",
+			setup: func(ax *Nexodus) {
+				member := readyNexodus("wg1")
+				member.member, member.parent = true, ax
+				member.deviceCache["peer"] = deviceCacheEntry{device: public.ModelsDevice{PublicKey: "peer"}}
+				ax.members = []*Nexodus{member}
+			},
+			failed: []ReadinessCheck{{Name: "peer_connectivity", Organization: "org-wg1", Reason: "none of the 1 peers are healthy"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			testInterfaces(t, map[string]net.Flags{
+				"wg0":     net.FlagUp,
+				"wg1":     net.FlagUp,
+				"wg-down": 0,
+			})
+
+			ax := readyNexodus("wg0")
+			tt.setup(ax)
+			readiness := ax.Readiness()
+
+			var failed []ReadinessCheck
+			for _, check := range readiness.Checks {
+				if !check.Ready {
+					failed = append(failed, check)
+				}
+			}
+			require.Equal(tt.failed, failed)
+			require.Equal(len(tt.failed) == 0, readiness.Ready)
+		})
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	require := require.New(t)
+	testInterfaces(t, map[string]net.Flags{"wg0": net.FlagUp})
+
+	ax := readyNexodus("wg0")
+	handler := ax.httpHandler(false, true)
+	get := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		return res
+	}
+
+	res := get("/healthz")
+	require.Equal(http.StatusOK, res.Code)
+	require.Equal("application/json", res.Header().Get("Content-Type"))
+
+	res = get("/readyz")
+	require.Equal(http.StatusOK, res.Code)
+	var readiness Readiness
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &readiness))
+	require.True(readiness.Ready)
+	require.Len(readiness.Checks, 4)
+
+	// a live nexd that is not ready yet.
+	ax.SetStatus(NexdStatusAuth, "")
+	require.Equal(http.StatusOK, get("/healthz").Code)
+	res = get("/readyz")
+	require.Equal(http.StatusServiceUnavailable, res.Code)
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &readiness))
+	require.False(readiness.Ready)
+
+	// the endpoints are only served when enabled.
+	res = httptest.NewRecorder()
+	ax.httpHandler(true, false).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(http.StatusNotFound, res.Code)
+}
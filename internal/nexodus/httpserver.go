@@ -0,0 +1,50 @@
+package nexodus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/util"
+)
+
+// HttpServerStart serves the optional http endpoints of nexd on the given address: prometheus
+// metrics on /metrics, and the liveness and readiness checks on /healthz and /readyz.
+func (ax *Nexodus) HttpServerStart(ctx context.Context, wg *sync.WaitGroup, addr string, metrics bool, health bool) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:           ax.httpHandler(metrics, health),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	util.GoWithWaitGroup(wg, func() {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			ax.logger.Errorf("http server failed: %v", err)
+		}
+	})
+	util.GoWithWaitGroup(wg, func() {
+		<-ctx.Done()
+		_ = server.Close()
+	})
+	ax.logger.Infof("Serving nexd http endpoints on %s", l.Addr())
+	return nil
+}
+
+// httpHandler routes the enabled http endpoints of nexd.
+func (ax *Nexodus) httpHandler(metrics bool, health bool) http.Handler {
+	mux := http.NewServeMux()
+	if metrics {
+		mux.Handle("/metrics", ax.metricsHandler())
+	}
+	if health {
+		mux.HandleFunc("/healthz", ax.healthzHandler)
+		mux.HandleFunc("/readyz", ax.readyzHandler)
+	}
+	return mux
+}
@@ -17,10 +17,12 @@ func (ax *Nexodus) createOrUpdateDeviceOperation(userID string, endpoints []publ
 		PublicKey:               ax.wireguardPubKey,
 		TunnelIp:                ax.requestedIP,
 		ChildPrefix:             ax.childPrefix,
+		ChildPrefixPriority:     ax.childPrefixPriority,
 		EndpointLocalAddressIp4: ax.endpointLocalAddress,
 		SymmetricNat:            ax.symmetricNat,
 		Hostname:                ax.hostname,
 		Relay:                   ax.relay,
+		Hub:                     ax.hub,
 		Os:                      ax.os,
 		Endpoints:               endpoints,
 	}).Execute()
@@ -33,6 +35,7 @@ func (ax *Nexodus) createOrUpdateDeviceOperation(userID string, endpoints []publ
 				var resp *http.Response
 				d, resp, err = ax.client.DevicesApi.UpdateDevice(context.Background(), model.Id).Update(public.ModelsUpdateDevice{
 					ChildPrefix:             ax.childPrefix,
+					ChildPrefixPriority:     public.PtrInt32(ax.childPrefixPriority),
 					EndpointLocalAddressIp4: ax.endpointLocalAddress,
 					SymmetricNat:            ax.symmetricNat,
 					Hostname:                ax.hostname,
@@ -3,6 +3,7 @@ package nexodus
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -24,6 +25,16 @@ const (
 	privateKeyPermissions = 0600
 )
 
+// orgKeyFile returns the key file used to join the organization of a member. A key pair identifies a
+// single device, so the members get their own key files next to the default ones.
+func (ax *Nexodus) orgKeyFile(keyFile string) string {
+	if !ax.member {
+		return keyFile
+	}
+	ext := filepath.Ext(keyFile)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(keyFile, ext), ax.org.Id, ext)
+}
+
 // generateKeyPair a key pair and write them to disk
 func (ax *Nexodus) generateKeyPair(publicKeyFile, privateKeyFile string) error {
 
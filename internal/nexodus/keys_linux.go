@@ -16,6 +16,7 @@ func (ax *Nexodus) handleKeys() error {
 		pubKeyFile = linuxPublicKeyFile
 		privKeyFile = linuxPrivateKeyFile
 	}
+	pubKeyFile, privKeyFile = ax.orgKeyFile(pubKeyFile), ax.orgKeyFile(privKeyFile)
 	publicKey := readKeyFile(ax.logger, pubKeyFile)
 	privateKey := readKeyFile(ax.logger, privKeyFile)
 	if publicKey != "" && privateKey != "" {
@@ -0,0 +1,140 @@
+package nexodus
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "nexd"
+
+var (
+	reconcileDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Time taken to reconcile the device cache and wireguard peers with the api-server.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	reconcileErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_errors_total",
+		Help:      "Number of failed reconciliations with the api-server.",
+	})
+	stunRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stun_requests_total",
+		Help:      "Number of STUN requests by result.",
+	}, []string{"result"})
+	stunBindingChanges = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stun_nat_binding_changes_total",
+		Help:      "Number of times STUN detected a change of the NAT binding of this device.",
+	})
+	informerReconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "informer_reconnects_total",
+		Help:      "Number of times the device watch stream to the api-server was re-established.",
+	})
+)
+
+var (
+	peerRxBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "peer", "rx_bytes"),
+		"Bytes received from the peer.",
+		[]string{"organization", "public_key", "hostname"}, nil,
+	)
+	peerTxBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "peer", "tx_bytes"),
+		"Bytes sent to the peer.",
+		[]string{"organization", "public_key", "hostname"}, nil,
+	)
+	peerHandshakeAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "peer", "handshake_age_seconds"),
+		"Seconds since the last wireguard handshake with the peer.",
+		[]string{"organization", "public_key", "hostname"}, nil,
+	)
+	peerHealthyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "peer", "healthy"),
+		"Whether the connection to the peer is healthy (1) or not (0).",
+		[]string{"organization", "public_key", "hostname"}, nil,
+	)
+	proxyConnectionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "proxy", "connections_total"),
+		"Number of connections handled by a userspace proxy.",
+		[]string{"type", "protocol", "listen_port"}, nil,
+	)
+)
+
+// nexdCollector exposes the state nexd already tracks, the device caches of the organizations it
+// joined and the proxies, as metrics at scrape time.
+type nexdCollector struct {
+	ax *Nexodus
+}
+
+func (c nexdCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- peerRxBytesDesc
+	ch <- peerTxBytesDesc
+	ch <- peerHandshakeAgeDesc
+	ch <- peerHealthyDesc
+	ch <- proxyConnectionsDesc
+}
+
+func (c nexdCollector) Collect(ch chan<- prometheus.Metric) {
+	collectPeers(c.ax, ch)
+	for _, member := range c.ax.members {
+		collectPeers(member, ch)
+	}
+
+	c.ax.proxyLock.RLock()
+	for key, proxy := range c.ax.proxies {
+		ch <- prometheus.MustNewConstMetric(proxyConnectionsDesc, prometheus.CounterValue,
+			float64(atomic.LoadUint64(&proxy.connectionCounter)),
+			key.ruleType.String(), string(key.protocol), strconv.Itoa(key.listenPort))
+	}
+	c.ax.proxyLock.RUnlock()
+}
+
+// collectPeers reports the peers in the device cache of the organization ax joined.
+func collectPeers(ax *Nexodus, ch chan<- prometheus.Metric) {
+	organization := ""
+	if ax.org != nil {
+		organization = ax.org.Id
+	}
+	ax.deviceCacheIterRead(func(d deviceCacheEntry) {
+		if d.device.PublicKey == ax.wireguardPubKey {
+			return
+		}
+		labels := []string{organization, d.device.PublicKey, d.device.Hostname}
+		ch <- prometheus.MustNewConstMetric(peerRxBytesDesc, prometheus.CounterValue, float64(d.lastRxBytes), labels...)
+		ch <- prometheus.MustNewConstMetric(peerTxBytesDesc, prometheus.CounterValue, float64(d.lastTxBytes), labels...)
+		if !d.lastHandshakeTime.IsZero() {
+			ch <- prometheus.MustNewConstMetric(peerHandshakeAgeDesc, prometheus.GaugeValue, time.Since(d.lastHandshakeTime).Seconds(), labels...)
+		}
+		healthy := 0.0
+		if d.peerHealthy {
+			healthy = 1
+		}
+		ch <- prometheus.MustNewConstMetric(peerHealthyDesc, prometheus.GaugeValue, healthy, labels...)
+	})
+}
+
+// metricsHandler serves the prometheus metrics of this nexd.
+func (ax *Nexodus) metricsHandler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		reconcileDuration,
+		reconcileErrors,
+		stunRequests,
+		stunBindingChanges,
+		informerReconnects,
+		nexdCollector{ax: ax},
+	)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
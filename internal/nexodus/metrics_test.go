@@ -0,0 +1,60 @@
+package nexodus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNexdCollector(t *testing.T) {
+	require := require.New(t)
+
+	ax := &Nexodus{
+		wireguardPubKey: "self-a",
+		org:             &public.ModelsOrganization{Id: "org-a"},
+		deviceCache: map[string]deviceCacheEntry{
+			"self-a": {device: public.ModelsDevice{PublicKey: "self-a", Hostname: "host"}},
+			"peer-a": {
+				device:     public.ModelsDevice{PublicKey: "peer-a", Hostname: "web"},
+				peerHealth: peerHealth{lastRxBytes: 100, lastTxBytes: 200, peerHealthy: true},
+			},
+		},
+	}
+	ax.members = []*Nexodus{{
+		member:          true,
+		parent:          ax,
+		wireguardPubKey: "self-b",
+		org:             &public.ModelsOrganization{Id: "org-b"},
+		deviceCache: map[string]deviceCacheEntry{
+			"self-b": {device: public.ModelsDevice{PublicKey: "self-b", Hostname: "host"}},
+			"peer-b": {
+				device:     public.ModelsDevice{PublicKey: "peer-b", Hostname: "db"},
+				peerHealth: peerHealth{lastRxBytes: 5},
+			},
+		},
+	}}
+
+	expected := `
+# HELP nexd_peer_healthy Whether the connection to the peer is healthy (1) or not (0).
+# TYPE nexd_peer_healthy gauge
+nexd_peer_healthy{hostname="db",organization="org-b",public_key="peer-b"} 0
+nexd_peer_healthy{hostname="web",organization="org-a",public_key="peer-a"} 1
+# HELP nexd_peer_rx_bytes Bytes received from the peer.
+# TYPE nexd_peer_rx_bytes counter
+nexd_peer_rx_bytes{hostname="db",organization="org-b",public_key="peer-b"} 5
+nexd_peer_rx_bytes{hostname="web",organization="org-a",public_key="peer-a"} 100
+# HELP nexd_peer_tx_bytes Bytes sent to the peer.
+# TYPE nexd_peer_tx_bytes counter
+nexd_peer_tx_bytes{hostname="db",organization="org-b",public_key="peer-b"} 0
+nexd_peer_tx_bytes{hostname="web",organization="org-a",public_key="peer-a"} 200
+`
+	require.NoError(testutil.CollectAndCompare(nexdCollector{ax: ax}, strings.NewReader(expected),
+		"nexd_peer_healthy", "nexd_peer_rx_bytes", "nexd_peer_tx_bytes"))
+
+	// the handshake age is only reported once there was a handshake, and the devices themselves
+	// are not reported.
+	require.Equal(6, testutil.CollectAndCount(nexdCollector{ax: ax}))
+}
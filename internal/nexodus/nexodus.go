@@ -13,8 +13,10 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -32,6 +34,8 @@ import (
 
 const (
 	pollInterval       = 5 * time.Second
+	heartbeatInterval  = 60 * time.Second
+	peerStatusInterval = 60 * time.Second
 	wgGoBinary         = "wireguard-go"
 	nexdWgGoBinary     = "nexd-wireguard-go"
 	wgWinBinary        = "wireguard.exe"
@@ -118,15 +122,23 @@ type Nexodus struct {
 	tunnelIface              string
 	controllerIP             string
 	listenPort               int
-	orgId                    string
+	orgIds                   []string
 	org                      *public.ModelsOrganization
 	requestedIP              string
 	userProvidedLocalIP      string
 	TunnelIP                 string
 	TunnelIpV6               string
 	childPrefix              []string
+	childPrefixPriority      int32
 	stun                     bool
 	relay                    bool
+	hub                      bool
+	exitNode                 bool
+	useExitNode              string
+	exitNodeHosts            []net.IP
+	exitNodeState            exitNodeState
+	meshDnsEnabled           bool
+	meshDns                  meshDns
 	relayWgIP                string
 	wgConfig                 wgConfig
 	client                   *client.APIClient
@@ -142,6 +154,9 @@ type Nexodus struct {
 	os                       string
 	logger                   *zap.SugaredLogger
 	logLevel                 *zap.AtomicLevel
+	// statusLock guards status and statusMsg, and userspaceDev once it is created, which the
+	// ctl and http servers read.
+	statusLock sync.RWMutex
 	// See the NexdStatus* constants
 	status        int
 	statusMsg     string
@@ -153,8 +168,18 @@ type Nexodus struct {
 	userspaceWG
 	informer     *public.ApiListDevicesInOrganizationInformer
 	informerStop context.CancelFunc
-	nexCtx       context.Context
-	nexWg        *sync.WaitGroup
+	// informer reconnects already added to the informerReconnects counter
+	informerReconnectsSeen uint64
+	deviceRegistered       atomic.Bool
+	nexCtx                 context.Context
+	nexWg                  *sync.WaitGroup
+	// member is set on the instances joining the organizations requested after the first one
+	member bool
+	// parent is the instance that joined the first organization, set on members, which report
+	// their status through it
+	parent *Nexodus
+	// members join the other organizations requested with --organization-id
+	members []*Nexodus
 }
 
 type wgConfig struct {
@@ -186,15 +211,20 @@ func NewNexodus(
 	requestedIP string,
 	userProvidedLocalIP string,
 	childPrefix []string,
+	childPrefixPriority int32,
 	stun bool,
 	relay bool,
+	hub bool,
 	relayOnly bool,
+	exitNode bool,
+	useExitNode string,
+	meshDns bool,
 	insecureSkipTlsVerify bool,
 	version string,
 	userspaceMode bool,
 	stateDir string,
 	ctx context.Context,
-	orgId string,
+	orgIds []string,
 ) (*Nexodus, error) {
 
 	if err := binaryChecks(); err != nil {
@@ -234,8 +264,13 @@ func NewNexodus(
 		requestedIP:         requestedIP,
 		userProvidedLocalIP: userProvidedLocalIP,
 		childPrefix:         childPrefix,
+		childPrefixPriority: childPrefixPriority,
 		stun:                stun,
-		relay:               relay,
+		relay:               relay || hub,
+		hub:                 hub,
+		exitNode:            exitNode,
+		useExitNode:         useExitNode,
+		meshDnsEnabled:      meshDns,
 		deviceCache:         make(map[string]deviceCacheEntry),
 		controllerURL:       controllerURL,
 		hostname:            hostname,
@@ -248,7 +283,7 @@ func NewNexodus(
 		password:            password,
 		skipTlsVerify:       insecureSkipTlsVerify,
 		stateDir:            stateDir,
-		orgId:               orgId,
+		orgIds:              orgIds,
 		userspaceWG: userspaceWG{
 			proxies: map[ProxyKey]*UsProxy{},
 		},
@@ -260,6 +295,11 @@ func NewNexodus(
 		ax.listenPort = WgDefaultPort
 	}
 
+	// an exit node advertises the default routes
+	if ax.exitNode {
+		ax.childPrefix = append(ax.childPrefix, exitNodePrefixes...)
+	}
+
 	if err := ax.checkUnsupportedConfigs(); err != nil {
 		return nil, err
 	}
@@ -279,10 +319,26 @@ func NewNexodus(
 }
 
 func (ax *Nexodus) SetStatus(status int, msg string) {
+	if ax.parent != nil {
+		ax.parent.SetStatus(status, msg)
+		return
+	}
+	ax.statusLock.Lock()
+	defer ax.statusLock.Unlock()
 	ax.statusMsg = msg
 	ax.status = status
 }
 
+// getStatus returns the status and status message set by SetStatus.
+func (ax *Nexodus) getStatus() (int, string) {
+	if ax.parent != nil {
+		return ax.parent.getStatus()
+	}
+	ax.statusLock.RLock()
+	defer ax.statusLock.RUnlock()
+	return ax.status, ax.statusMsg
+}
+
 func (ax *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	ax.nexCtx = ctx
 	ax.nexWg = wg
@@ -389,11 +445,37 @@ func (ax *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		return fmt.Errorf("get organizations error: %w", err)
 	}
 
-	ax.org, err = ax.chooseOrganization(organizations, *user)
+	orgs, err := ax.chooseOrganizations(organizations, *user)
 	if err != nil {
 		return fmt.Errorf("failed to choose an organization: %w", err)
 	}
+	ax.org = orgs[0]
+	var members []*Nexodus
+	for i, org := range orgs[1:] {
+		member, err := ax.newMember(i+1, org)
+		if err != nil {
+			return fmt.Errorf("failed to join organization %s: %w", org.Id, err)
+		}
+		members = append(members, member)
+	}
+	ax.members = members
 
+	if err := ax.join(ctx, wg, user, options); err != nil {
+		return err
+	}
+	for _, member := range ax.members {
+		if err := member.join(ctx, wg, user, options); err != nil {
+			return fmt.Errorf("failed to join organization %s: %w", member.org.Id, err)
+		}
+	}
+
+	return nil
+}
+
+// join registers the device in the organization and keeps the tunnel interface in sync with the peers
+// of the organization until ctx is cancelled.
+func (ax *Nexodus) join(ctx context.Context, wg *sync.WaitGroup, user *public.ModelsUser, options []client.Option) error {
+	var err error
 	informerCtx, informerCancel := context.WithCancel(ctx)
 	ax.informerStop = informerCancel
 	ax.informer = ax.client.DevicesApi.ListDevicesInOrganization(informerCtx, ax.org.Id).Informer()
@@ -413,6 +495,9 @@ func (ax *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 			return err
 		}
 
+		if ax.hub && !ax.org.HubZone {
+			return fmt.Errorf("organization %s is not a hub organization, hub devices can only join a hub organization", ax.org.Id)
+		}
 		existingRelay, err := ax.orgRelayCheck(peerMap)
 		if err != nil {
 			return err
@@ -472,6 +557,7 @@ func (ax *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		return fmt.Errorf("join error %w", err)
 	}
 
+	ax.deviceRegistered.Store(true)
 	ax.logger.Debug(fmt.Sprintf("Device: %+v", modelsDevice))
 	ax.logger.Infof("Successfully registered device with UUID: [ %+v ] into organization: [ %s (%s) ]",
 		modelsDevice.Id, ax.org.Name, ax.org.Id)
@@ -483,16 +569,30 @@ func (ax *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		}
 	}
 
+	// an exit node forwards like a relay and masquerades the traffic leaving the mesh
+	if ax.exitNode {
+		if err := ax.exitNodePrep(); err != nil {
+			return err
+		}
+	}
+
 	util.GoWithWaitGroup(wg, func() {
 		// kick it off with an immediate reconcile
 		ax.reconcileDevices(ctx, options)
 		ax.reconcileSecurityGroups(ctx)
+		if err := ax.sendHeartbeat(ctx, modelsDevice.Id); err != nil {
+			ax.logger.Debug(err)
+		}
 		for _, proxy := range ax.proxies {
 			proxy.Start(ctx, wg, ax.userspaceNet)
 		}
 		stunTicker := time.NewTicker(time.Second * 20)
 		secGroupTicker := time.NewTicker(time.Second * 20)
+		heartbeatTicker := time.NewTicker(heartbeatInterval)
+		peerStatusTicker := time.NewTicker(peerStatusInterval)
 		defer stunTicker.Stop()
+		defer heartbeatTicker.Stop()
+		defer peerStatusTicker.Stop()
 		pollTicker := time.NewTicker(pollInterval)
 		defer pollTicker.Stop()
 		for {
@@ -512,6 +612,15 @@ func (ax *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 				ax.reconcileDevices(ctx, options)
 			case <-secGroupTicker.C:
 				ax.reconcileSecurityGroups(ctx)
+				ax.reconcileOrganization(ctx)
+			case <-heartbeatTicker.C:
+				if err := ax.sendHeartbeat(ctx, modelsDevice.Id); err != nil {
+					ax.logger.Debug(err)
+				}
+			case <-peerStatusTicker.C:
+				if err := ax.reportPeerStatus(ctx, modelsDevice.Id); err != nil {
+					ax.logger.Debug(err)
+				}
 			}
 		}
 	})
@@ -519,11 +628,92 @@ func (ax *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	return nil
 }
 
+// newMember creates the instance joining the organization org next to the first one. It gets its own
+// tunnel interface, listen port and keys, and shares the api client and the settings of ax. Relays and
+// exit nodes only serve the first organization.
+func (ax *Nexodus) newMember(index int, org *public.ModelsOrganization) (*Nexodus, error) {
+	listenPort, err := getWgListenPort()
+	if err != nil {
+		return nil, err
+	}
+	member := ax.memberOf(index, org, listenPort)
+	member.removeExistingInterface()
+	if err := member.handleKeys(); err != nil {
+		return nil, fmt.Errorf("handleKeys: %w", err)
+	}
+	return member, nil
+}
+
+// memberOf returns the member of ax with the given index for the organization org, without touching
+// the host.
+func (ax *Nexodus) memberOf(index int, org *public.ModelsOrganization, listenPort int) *Nexodus {
+	return &Nexodus{
+		tunnelIface:         memberTunnelDev(ax.defaultTunnelDev(), index),
+		controllerIP:        ax.controllerIP,
+		listenPort:          listenPort,
+		org:                 org,
+		member:              true,
+		userProvidedLocalIP: ax.userProvidedLocalIP,
+		childPrefix:         ax.childPrefix,
+		childPrefixPriority: ax.childPrefixPriority,
+		stun:                ax.stun,
+		meshDnsEnabled:      ax.meshDnsEnabled,
+		client:              ax.client,
+		controllerURL:       ax.controllerURL,
+		deviceCache:         make(map[string]deviceCacheEntry),
+		hostname:            ax.hostname,
+		symmetricNat:        ax.symmetricNat,
+		logger:              ax.logger.With("organization", org.Name),
+		logLevel:            ax.logLevel,
+		parent:              ax,
+		version:             ax.version,
+		username:            ax.username,
+		password:            ax.password,
+		skipTlsVerify:       ax.skipTlsVerify,
+		stateDir:            ax.stateDir,
+		ipv6Supported:       ax.ipv6Supported,
+		os:                  ax.os,
+		userspaceWG: userspaceWG{
+			userspaceMode: ax.userspaceMode,
+			proxies:       map[ProxyKey]*UsProxy{},
+		},
+		nexCtx: ax.nexCtx,
+		nexWg:  ax.nexWg,
+	}
+}
+
+// memberTunnelDev returns the tunnel interface of the member with the given index, the default
+// interface with its number increased by the index, wg1 or utun9 for the second organization.
+func memberTunnelDev(defaultDev string, index int) string {
+	name := strings.TrimRight(defaultDev, "0123456789")
+	number, err := strconv.Atoi(defaultDev[len(name):])
+	if err != nil {
+		number = 0
+	}
+	return fmt.Sprintf("%s%d", name, number+index)
+}
+
 func (ax *Nexodus) Stop() {
 	ax.logger.Info("Stopping nexd")
 	for _, proxy := range ax.proxies {
 		proxy.Stop()
 	}
+	ax.stop()
+	for _, member := range ax.members {
+		member.stop()
+	}
+}
+
+// stop cleans up the host configuration made for the organization.
+func (ax *Nexodus) stop() {
+	ax.deviceCacheLock.Lock()
+	defer ax.deviceCacheLock.Unlock()
+	ax.stopMeshDns()
+	if ax.useExitNode != "" && !ax.userspaceMode {
+		if err := ax.removeExitNodeRoutes(); err != nil {
+			ax.logger.Error(err)
+		}
+	}
 }
 
 // reconcileSecurityGroups will check the security group and update it if necessary.
@@ -591,9 +781,14 @@ func (ax *Nexodus) reconcileSecurityGroups(ctx context.Context) {
 
 func (ax *Nexodus) reconcileDevices(ctx context.Context, options []client.Option) {
 	var err error
-	if err = ax.reconcileDeviceCache(); err == nil {
+	ax.resolveExitNodeHosts()
+	start := time.Now()
+	err = ax.reconcileDeviceCache()
+	reconcileDuration.Observe(time.Since(start).Seconds())
+	if err == nil {
 		return
 	}
+	reconcileErrors.Inc()
 
 	var dnsErr *net.DNSError
 	if errors.As(err, &dnsErr) && dnsErr.Temporary() {
@@ -632,6 +827,8 @@ func (ax *Nexodus) reconcileDevices(ctx context.Context, options []client.Option
 	}
 
 	ax.client = c
+	informerReconnects.Inc()
+	ax.informerReconnectsSeen = 0
 	informerCtx, informerCancel := context.WithCancel(ctx)
 	ax.informerStop = informerCancel
 	ax.informer = ax.client.DevicesApi.ListDevicesInOrganization(informerCtx, ax.org.Id).Informer()
@@ -640,6 +837,15 @@ func (ax *Nexodus) reconcileDevices(ctx context.Context, options []client.Option
 	ax.logger.Infoln("Nexodus agent has re-established a connection to the api-server")
 }
 
+// sendHeartbeat lets the api-server know this device is still online
+func (ax *Nexodus) sendHeartbeat(ctx context.Context, deviceID string) error {
+	_, _, err := ax.client.DevicesApi.HeartbeatDevice(ctx, deviceID).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to send heartbeat, likely still reconnecting to the api-server, retrying in %s: %w", heartbeatInterval, err)
+	}
+	return nil
+}
+
 func (ax *Nexodus) reconcileStun(deviceID string) error {
 	if ax.symmetricNat {
 		return nil
@@ -649,8 +855,10 @@ func (ax *Nexodus) reconcileStun(deviceID string) error {
 	stunServer1 := stun.NextServer()
 	reflexiveIP, err := stun.Request(ax.logger, stunServer1, ax.listenPort)
 	if err != nil {
+		stunRequests.WithLabelValues("failure").Inc()
 		return fmt.Errorf("stun request error: %w", err)
 	}
+	stunRequests.WithLabelValues("success").Inc()
 
 	if ax.nodeReflexiveAddressIPv4 != reflexiveIP {
 		ax.logger.Infof("detected a NAT binding changed for this device %s from %s to %s, updating peers", deviceID, ax.nodeReflexiveAddressIPv4, reflexiveIP)
@@ -674,6 +882,7 @@ func (ax *Nexodus) reconcileStun(deviceID string) error {
 		} else {
 			ax.logger.Debugf("update device response %+v", res)
 			ax.nodeReflexiveAddressIPv4 = reflexiveIP
+			stunBindingChanges.Inc()
 			// reinitialize peers if the NAT binding has changed for the node
 			if err = ax.reconcileDeviceCache(); err != nil {
 				ax.logger.Debugf("reconcile failed %v", res)
@@ -684,32 +893,59 @@ func (ax *Nexodus) reconcileStun(deviceID string) error {
 	return nil
 }
 
+// chooseOrganizations returns the organizations requested with --organization-id, in the requested order,
+// or the default organization of the user if none were requested.
+func (nx *Nexodus) chooseOrganizations(organizations []public.ModelsOrganization, user public.ModelsUser) ([]*public.ModelsOrganization, error) {
+	if len(nx.orgIds) == 0 {
+		org, err := nx.chooseOrganization(organizations, user)
+		if err != nil {
+			return nil, err
+		}
+		return []*public.ModelsOrganization{org}, nil
+	}
+	var orgs []*public.ModelsOrganization
+	for _, orgId := range nx.orgIds {
+		org, err := nx.findOrganization(organizations, orgId)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range orgs {
+			if o.Id == org.Id {
+				return nil, fmt.Errorf("organization %s is requested more than once", orgId)
+			}
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
 func (nx *Nexodus) chooseOrganization(organizations []public.ModelsOrganization, user public.ModelsUser) (*public.ModelsOrganization, error) {
 	if len(organizations) == 0 {
 		return nil, fmt.Errorf("user does not belong to any organizations")
 	}
-	if nx.orgId == "" {
-		if len(organizations) > 1 {
-			// default to the org that matches the user name, the one created for a new user by default
-			for i, org := range organizations {
-				if org.Name == user.UserName {
-					return &organizations[i], nil
-				}
+	if len(organizations) > 1 {
+		// default to the org that matches the user name, the one created for a new user by default
+		for i, org := range organizations {
+			if org.Name == user.UserName {
+				return &organizations[i], nil
 			}
-			// Log all org names + Ids for convenience before returning the error
-			for _, org := range organizations {
-				nx.logger.Infof("organization name: '%s'  Id: %s", org.Name, org.Id)
-			}
-			return nil, fmt.Errorf("user belongs to multiple organizations, please specify one with --organization-id")
 		}
-		return &organizations[0], nil
+		// Log all org names + Ids for convenience before returning the error
+		for _, org := range organizations {
+			nx.logger.Infof("organization name: '%s'  Id: %s", org.Name, org.Id)
+		}
+		return nil, fmt.Errorf("user belongs to multiple organizations, please specify one with --organization-id")
 	}
+	return &organizations[0], nil
+}
+
+func (nx *Nexodus) findOrganization(organizations []public.ModelsOrganization, orgId string) (*public.ModelsOrganization, error) {
 	for i, org := range organizations {
-		if org.Id == nx.orgId {
+		if org.Id == orgId {
 			return &organizations[i], nil
 		}
 	}
-	return nil, fmt.Errorf("user does not belong to organization %s", nx.orgId)
+	return nil, fmt.Errorf("user does not belong to organization %s", orgId)
 }
 
 func (nx *Nexodus) deviceCacheIterRead(f func(deviceCacheEntry)) {
@@ -817,6 +1053,10 @@ func (nx *Nexodus) addToDeviceCache(p public.ModelsDevice) {
 
 func (ax *Nexodus) reconcileDeviceCache() error {
 	peerMap, resp, err := ax.informer.Execute()
+	if reconnects := ax.informer.Reconnects(); reconnects > ax.informerReconnectsSeen {
+		informerReconnects.Add(float64(reconnects - ax.informerReconnectsSeen))
+		ax.informerReconnectsSeen = reconnects
+	}
 	if err != nil {
 		if resp != nil {
 			return fmt.Errorf("error: %w header: %v", err, resp.Header)
@@ -911,6 +1151,9 @@ func (ax *Nexodus) reconcileDeviceCache() error {
 		ax.logger.Error(err)
 	}
 
+	ax.reconcileExitNode()
+	ax.reconcileMeshDns()
+
 	return nil
 }
 
@@ -924,12 +1167,18 @@ func (ax *Nexodus) isEqualIgnoreSecurityGroup(p1, p2 public.ModelsDevice) bool {
 	// set the Revision to 0, so it will not affect the comparison
 	tmpDev1.Revision = 0
 	tmpDev2.Revision = 0
+	// heartbeats update the liveness fields, they do not affect the peer configuration
+	tmpDev1.LastSeen, tmpDev1.Online = "", false
+	tmpDev2.LastSeen, tmpDev2.Online = "", false
 
 	return reflect.DeepEqual(tmpDev1, tmpDev2)
 }
 
 // checkUnsupportedConfigs general matrix checks of required information or constraints to run the agent and join the mesh
 func (ax *Nexodus) checkUnsupportedConfigs() error {
+	if ax.userspaceMode && len(ax.orgIds) > 1 {
+		return fmt.Errorf("joining multiple organizations is not supported in userspace mode")
+	}
 
 	if ax.ipv6Supported = isIPv6Supported(); !ax.ipv6Supported {
 		ax.logger.Warn("IPv6 does not appear to be enabled on this host, only IPv4 will be provisioned or restart nexd with IPv6 enabled on this host")
@@ -989,6 +1238,10 @@ func (ax *Nexodus) symmetricNatDisco(ctx context.Context) error {
 // orgRelayCheck checks if there is an existing Relay node in the organization that does not match this device's pub key
 func (ax *Nexodus) orgRelayCheck(peerMap map[string]public.ModelsDevice) (string, error) {
 	for _, p := range peerMap {
+		// a hub organization can have several hubs
+		if ax.hub && p.Hub {
+			continue
+		}
 		if p.Relay && ax.wireguardPubKey != p.PublicKey {
 			return p.Id, nil
 		}
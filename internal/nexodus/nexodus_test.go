@@ -0,0 +1,107 @@
+package nexodus
+
+import (
+	"testing"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMemberTunnelDev(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("wg1", memberTunnelDev("wg0", 1))
+	require.Equal("wg2", memberTunnelDev("wg0", 2))
+	require.Equal("utun9", memberTunnelDev("utun8", 1))
+	require.Equal("go1", memberTunnelDev("go", 1))
+
+	// each member gets an interface of its own, other than the default one.
+	for _, defaultDev := range []string{"wg0", "utun8", "go"} {
+		seen := map[string]bool{defaultDev: true}
+		for index := 1; index <= 10; index++ {
+			dev := memberTunnelDev(defaultDev, index)
+			require.False(seen[dev], "%s is used twice", dev)
+			seen[dev] = true
+		}
+	}
+}
+
+func TestOrgKeyFile(t *testing.T) {
+	require := require.New(t)
+
+	first := &Nexodus{org: &public.ModelsOrganization{Id: "org-a"}}
+	require.Equal("/etc/wireguard/private.key", first.orgKeyFile("/etc/wireguard/private.key"))
+
+	seen := map[string]bool{
+		"/etc/wireguard/private.key": true,
+		"/etc/wireguard/public.key":  true,
+	}
+	for _, id := range []string{"org-b", "org-c"} {
+		member := &Nexodus{member: true, org: &public.ModelsOrganization{Id: id}}
+		for _, keyFile := range []string{"/etc/wireguard/private.key", "/etc/wireguard/public.key"} {
+			file := member.orgKeyFile(keyFile)
+			require.False(seen[file], "%s is used twice", file)
+			seen[file] = true
+		}
+	}
+	member := &Nexodus{member: true, org: &public.ModelsOrganization{Id: "org-b"}}
+	require.Equal("C:/nexd/private-org-b.key", member.orgKeyFile("C:/nexd/private.key"))
+}
+
+func TestMemberStatus(t *testing.T) {
+	require := require.New(t)
+
+	ax := &Nexodus{status: NexdStatusRunning}
+	member := &Nexodus{member: true, parent: ax}
+
+	// a member that has to authenticate again is reported by the parent.
+	member.SetStatus(NexdStatusAuth, "waiting")
+	status, msg := ax.getStatus()
+	require.Equal(NexdStatusAuth, status)
+	require.Equal("waiting", msg)
+
+	ax.SetStatus(NexdStatusRunning, "")
+	status, _ = member.getStatus()
+	require.Equal(NexdStatusRunning, status)
+}
+
+func TestMemberOf(t *testing.T) {
+	require := require.New(t)
+
+	ax := &Nexodus{
+		org:           &public.ModelsOrganization{Id: "org-a"},
+		logger:        zap.NewNop().Sugar(),
+		hostname:      "host",
+		childPrefix:   []string{"172.16.0.0/24"},
+		ipv6Supported: true,
+		os:            "linux",
+		userspaceWG:   userspaceWG{userspaceMode: true},
+	}
+	member := ax.memberOf(1, &public.ModelsOrganization{Id: "org-b", Name: "Org B"}, 51821)
+
+	require.True(member.member)
+	require.Same(ax, member.parent)
+	require.Equal("org-b", member.org.Id)
+	require.Equal(51821, member.listenPort)
+	require.Equal(memberTunnelDev(ax.defaultTunnelDev(), 1), member.tunnelIface)
+	require.Equal("host", member.hostname)
+	require.Equal(ax.childPrefix, member.childPrefix)
+	require.True(member.ipv6Supported)
+	require.Equal("linux", member.os)
+	require.True(member.userspaceMode)
+	require.NotNil(member.proxies)
+	require.NotNil(member.deviceCache)
+}
+
+func TestUserspaceModeRejectsMultipleOrganizations(t *testing.T) {
+	ax := &Nexodus{
+		logger:      zap.NewNop().Sugar(),
+		orgIds:      []string{"org-a", "org-b"},
+		userspaceWG: userspaceWG{userspaceMode: true},
+	}
+	require.Error(t, ax.checkUnsupportedConfigs())
+
+	ax.orgIds = ax.orgIds[:1]
+	require.NoError(t, ax.checkUnsupportedConfigs())
+}
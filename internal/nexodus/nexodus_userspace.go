@@ -66,7 +66,9 @@ func (ax *Nexodus) setupInterfaceUS() error {
 		ax.logger.Errorf("Failed to bring up userspace device: %w", err)
 		return err
 	}
+	ax.statusLock.Lock()
 	ax.userspaceDev = dev
+	ax.statusLock.Unlock()
 
 	devName, err := tun.Name()
 	if err != nil {
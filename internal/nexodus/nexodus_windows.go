@@ -12,7 +12,8 @@ import (
 
 const (
 	windowsConfFilePermissions = 0644
-	windowsWgConfigFile        = "C:/nexd/wg0.conf"
+	// the tunnel service is named after the config file, C:/nexd/wg0.conf for wg0
+	windowsWgConfigDir = "C:/nexd"
 )
 
 func (ax *Nexodus) setupInterfaceOS() error {
@@ -20,9 +21,10 @@ func (ax *Nexodus) setupInterfaceOS() error {
 	logger := ax.logger
 	dev := ax.tunnelIface
 	listenPortStr := strconv.Itoa(ax.listenPort)
+	configFile := windowsWgConfigDir + "/" + dev + ".conf"
 
-	if err := buildWindowsWireguardIfaceConf(ax.wireguardPvtKey, ax.TunnelIP, listenPortStr); err != nil {
-		return fmt.Errorf("failed to create the windows wireguard wg0 interface file: %w", err)
+	if err := buildWindowsWireguardIfaceConf(configFile, ax.wireguardPvtKey, ax.TunnelIP, listenPortStr); err != nil {
+		return fmt.Errorf("failed to create the windows wireguard %s interface file: %w", dev, err)
 	}
 
 	var wgOut string
@@ -39,7 +41,7 @@ func (ax *Nexodus) setupInterfaceOS() error {
 	}
 	logger.Debugf("stopped windows tunnel svc:%v\n", wgOut)
 	// sleep for one second to give the wg async exe time to tear down any existing wg0 configuration
-	wgOut, err = RunCommand("wireguard.exe", "/installtunnelservice", windowsWgConfigFile)
+	wgOut, err = RunCommand("wireguard.exe", "/installtunnelservice", configFile)
 	if err != nil {
 		return fmt.Errorf("failed to start the wireguard interface: %w", err)
 	}
@@ -64,8 +66,8 @@ func (ax *Nexodus) findLocalIP() (string, error) {
 	return discoverGenericIPv4(ax.logger, ax.controllerURL.Host, "443")
 }
 
-func buildWindowsWireguardIfaceConf(pvtKey, wgAddress, wgListenPort string) error {
-	f, err := fileHandle(windowsWgConfigFile, windowsConfFilePermissions)
+func buildWindowsWireguardIfaceConf(configFile, pvtKey, wgAddress, wgListenPort string) error {
+	f, err := fileHandle(configFile, windowsConfFilePermissions)
 	if err != nil {
 		return err
 	}
@@ -82,7 +84,7 @@ func buildWindowsWireguardIfaceConf(pvtKey, wgAddress, wgListenPort string) erro
 		WgAddress:    wgAddress,
 		WgListenPort: wgListenPort,
 	}); err != nil {
-		return fmt.Errorf("failed to fill windows template %s: %w", windowsWgConfigFile, err)
+		return fmt.Errorf("failed to fill windows template %s: %w", configFile, err)
 	}
 
 	return nil
@@ -0,0 +1,57 @@
+package nexodus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+)
+
+// buildPeerStatus summarizes the health of our connection to each peer in the device cache.
+// Peers without a wireguard peer entry of their own are reached through the relay node, so
+// they inherit the health of the relay connection.
+func (ax *Nexodus) buildPeerStatus() []public.ModelsPeerStatus {
+	var relay *deviceCacheEntry
+	ax.deviceCacheIterRead(func(d deviceCacheEntry) {
+		if d.device.Relay && d.device.PublicKey != ax.wireguardPubKey {
+			relay = &d
+		}
+	})
+
+	peers := []public.ModelsPeerStatus{}
+	ax.deviceCacheIterRead(func(d deviceCacheEntry) {
+		if d.device.PublicKey == ax.wireguardPubKey {
+			return
+		}
+		status := public.ModelsPeerStatus{
+			PublicKey: d.device.PublicKey,
+		}
+		if _, ok := ax.wgConfig.Peers[d.device.PublicKey]; ok || ax.relay {
+			status.Endpoint = d.endpoint
+			status.Healthy = d.peerHealthy
+			status.LatestHandshake = d.lastHandshake
+			status.TxBytes = d.lastTxBytes
+			status.RxBytes = d.lastRxBytes
+		} else {
+			status.Relayed = true
+			if relay != nil {
+				status.Endpoint = relay.endpoint
+				status.Healthy = relay.peerHealthy
+				status.LatestHandshake = relay.lastHandshake
+			}
+		}
+		peers = append(peers, status)
+	})
+	return peers
+}
+
+// reportPeerStatus uploads the peer status summary to the api-server
+func (ax *Nexodus) reportPeerStatus(ctx context.Context, deviceID string) error {
+	_, _, err := ax.client.DevicesApi.ReportDevicePeerStatus(ctx, deviceID).Status(public.ModelsReportPeerStatus{
+		Peers: ax.buildPeerStatus(),
+	}).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to report peer status, likely still reconnecting to the api-server, retrying in %s: %w", peerStatusInterval, err)
+	}
+	return nil
+}
@@ -34,10 +34,6 @@ const (
 	protoUDP    = "udp"
 )
 
-var (
-	ruleInterface string
-)
-
 // processSecurityGroupRules processes a security group for a Linux node
 func (nx *Nexodus) processSecurityGroupRules() error {
 
@@ -48,8 +44,6 @@ func (nx *Nexodus) processSecurityGroupRules() error {
 		return nil
 	}
 
-	ruleInterface = fmt.Sprintf("iifname %s", wgIface)
-
 	inboundRules := nx.securityGroup.InboundRules
 	outboundRules := nx.securityGroup.OutboundRules
 
@@ -141,7 +135,7 @@ func (nx *Nexodus) processSecurityGroupRules() error {
 	// connections. The state keyword is used to match traffic based on its connection state, in this case as
 	// established. The established state refers to traffic that is part of an existing connection that has
 	// already been established, and where both endpoints have exchanged packets.
-	nft := []string{"insert", "rule", tableFamily, tableName, ingressChain, "ct", "state", "established,related", ruleInterface, "counter", "accept"}
+	nft := []string{"insert", "rule", tableFamily, nx.nfTableName(), ingressChain, "ct", "state", "established,related", nx.nfRuleInterface(), "counter", "accept"}
 	if _, err := runNftCmd(nx.logger, nft); err != nil {
 		return err
 	}
@@ -186,7 +180,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
 				// v4 permits for L3 src or dst
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, ruleInterface, counter, actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, ax.nfRuleInterface(), counter, actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -197,7 +191,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, destPort, "0-65535", ax.nfRuleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -207,7 +201,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		if rule.FromPort != 0 && rule.ToPort != 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, dportOption, ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, dportOption, ax.nfRuleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -218,7 +212,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoUDP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoUDP, destPort, "0-65535", ax.nfRuleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -228,7 +222,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		if rule.FromPort != 0 && rule.ToPort != 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, rule.IpProtocol, dportOption, ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, rule.IpProtocol, dportOption, ax.nfRuleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -238,7 +232,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		// icmpv4 permits to L3 src or dst
 		for _, ipRange := range rule.IpRanges {
 			srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-			nft = []string{"insert", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, srcOrDstOption, ruleInterface, counter, actionAccept}
+			nft = []string{"insert", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, srcOrDstOption, ax.nfRuleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(ax.logger, nft); err != nil {
 				return err
 			}
@@ -275,7 +269,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, ruleInterface, counter, actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, ax.nfRuleInterface(), counter, actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -286,7 +280,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoTCP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoTCP, destPort, "0-65535", ax.nfRuleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -296,7 +290,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort != 0 && rule.ToPort != 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, rule.IpProtocol, dportOption, ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, rule.IpProtocol, dportOption, ax.nfRuleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -307,7 +301,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoUDP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoUDP, destPort, "0-65535", ax.nfRuleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -317,7 +311,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort != 0 && rule.ToPort != 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, protoUDP, dportOption, ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, protoUDP, dportOption, ax.nfRuleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(ax.logger, nft); err != nil {
 					return err
 				}
@@ -327,7 +321,7 @@ func (ax *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		// icmpv4 permits to L3 src or dst
 		for _, ipRange := range rule.IpRanges {
 			srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-			nft = []string{"insert", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", srcOrDstIpAddrOption, ruleInterface, counter, actionAccept}
+			nft = []string{"insert", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", srcOrDstIpAddrOption, ax.nfRuleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(ax.logger, nft); err != nil {
 				return err
 			}
@@ -354,20 +348,20 @@ func (ax *Nexodus) nfPermitProtoPort(chain string, rule public.ModelsSecurityRul
 			return nil
 		}
 		// tcp permits for ports to the specified dport for v4/v6
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, protoTCP, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, protoTCP, dportOption, ax.nfRuleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(ax.logger, nft); err != nil {
 			return err
 		}
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, protoTCP, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, protoTCP, dportOption, ax.nfRuleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(ax.logger, nft); err != nil {
 			return err
 		}
 		// udp permits for ports to the specified dport for v4/v6
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, protoUDP, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, protoUDP, dportOption, ax.nfRuleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(ax.logger, nft); err != nil {
 			return err
 		}
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, protoUDP, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, protoUDP, dportOption, ax.nfRuleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(ax.logger, nft); err != nil {
 			return err
 
@@ -377,11 +371,11 @@ func (ax *Nexodus) nfPermitProtoPort(chain string, rule public.ModelsSecurityRul
 		if dportOption == "" {
 			return nil
 		}
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, dportOption, ax.nfRuleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(ax.logger, nft); err != nil {
 			return err
 		}
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, dportOption, ax.nfRuleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(ax.logger, nft); err != nil {
 			return err
 		}
@@ -404,14 +398,14 @@ func (ax *Nexodus) nfPermitProtoAny(chain string, rule public.ModelsSecurityRule
 	case protoIPv4, protoIPv6:
 		// permit ipv6 any
 		if rule.IpProtocol == protoIPv4 {
-			nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", rule.IpProtocol, ruleInterface, counter, actionAccept}
+			nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", rule.IpProtocol, ax.nfRuleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(ax.logger, nft); err != nil {
 				return err
 			}
 		}
 		// permit ipv4 any
 		if rule.IpProtocol == protoIPv6 {
-			nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", rule.IpProtocol, ruleInterface, counter, actionAccept}
+			nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", rule.IpProtocol, ax.nfRuleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(ax.logger, nft); err != nil {
 				return err
 			}
@@ -420,7 +414,7 @@ func (ax *Nexodus) nfPermitProtoAny(chain string, rule public.ModelsSecurityRule
 	case "icmp", protoICMPv4, protoICMPv6:
 		// permit icmpv4 any
 		if rule.IpProtocol == protoICMPv4 || rule.IpProtocol == "icmp" {
-			nft = []string{"insert", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, ruleInterface, counter, actionAccept}
+			nft = []string{"insert", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, ax.nfRuleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(ax.logger, nft); err != nil {
 				return err
 			}
@@ -428,19 +422,19 @@ func (ax *Nexodus) nfPermitProtoAny(chain string, rule public.ModelsSecurityRule
 		// permit icmpv6 any
 		if rule.IpProtocol == protoICMPv6 {
 			// ip6 nexthdr is used instead of ip6 protocol for IPv6, because the protocol field is not directly in the IPv6 header.
-			nft = []string{"insert", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", ruleInterface, counter, actionAccept}
+			nft = []string{"insert", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", ax.nfRuleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(ax.logger, nft); err != nil {
 				return err
 			}
 		}
 	case protoTCP, protoUDP:
 		// permit ip/ip6 tcp or udp any to all ports
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, destPort, "0-65535", ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, destPort, "0-65535", ax.nfRuleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(ax.logger, nft); err != nil {
 			return err
 		}
 		// permit ipv6 tcp or udp any
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, destPort, "0-65535", ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, ax.nfTableName(), chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, destPort, "0-65535", ax.nfRuleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(ax.logger, nft); err != nil {
 			return err
 		}
@@ -471,7 +465,7 @@ func (ax *Nexodus) nftPortOption(rule public.ModelsSecurityRule) string {
 
 // nfIngressRuleDrop is used to append a drop rule to the ingress chain. Example rule handled by this method:
 func (ax *Nexodus) nfIngressRuleDrop() error {
-	nft := []string{"add", "rule", tableFamily, tableName, ingressChain, ruleInterface, "counter", actionDrop}
+	nft := []string{"add", "rule", tableFamily, ax.nfTableName(), ingressChain, ax.nfRuleInterface(), "counter", actionDrop}
 	if _, err := runNftCmd(ax.logger, nft); err != nil {
 		return err
 	}
@@ -481,7 +475,7 @@ func (ax *Nexodus) nfIngressRuleDrop() error {
 
 // nfEgressRuleDrop is used to append a drop rule to the egress chain
 func (ax *Nexodus) nfEgressRuleDrop() error {
-	nft := []string{"add", "rule", tableFamily, tableName, egressChain, ruleInterface, "counter", actionDrop}
+	nft := []string{"add", "rule", tableFamily, ax.nfTableName(), egressChain, ax.nfRuleInterface(), "counter", actionDrop}
 	if _, err := runNftCmd(ax.logger, nft); err != nil {
 		return err
 	}
@@ -502,7 +496,7 @@ func (ax *Nexodus) nfTableDrop() error {
 	}
 
 	// If the table exists, proceed with deletion
-	nft := []string{"delete", "table", tableFamily, tableName}
+	nft := []string{"delete", "table", tableFamily, ax.nfTableName()}
 	if _, err := runNftCmd(ax.logger, nft); err != nil {
 		return err
 	}
@@ -517,13 +511,33 @@ func (ax *Nexodus) nfTableExists() (bool, error) {
 		return false, err
 	}
 
-	tableFullName := fmt.Sprintf("%s %s", tableFamily, tableName)
-	return strings.Contains(output, tableFullName), nil
+	tableFullName := fmt.Sprintf("table %s %s", tableFamily, ax.nfTableName())
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == tableFullName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// nfTableName returns the name of the table holding the security group rules of the tunnel interface.
+// The default interface keeps the original table name, the interfaces of the other organizations joined
+// by nexd get a table each.
+func (ax *Nexodus) nfTableName() string {
+	if ax.tunnelIface == wgIface {
+		return tableName
+	}
+	return fmt.Sprintf("%s-%s", tableName, ax.tunnelIface)
+}
+
+// nfRuleInterface returns the match restricting a rule to the traffic of the tunnel interface.
+func (ax *Nexodus) nfRuleInterface() string {
+	return fmt.Sprintf("iifname %s", ax.tunnelIface)
 }
 
 // nfCreateTable is used to create the nftables table
 func (ax *Nexodus) nfCreateTable() error {
-	if _, err := runNftCmd(ax.logger, []string{"add", "table", tableFamily, tableName}); err != nil {
+	if _, err := runNftCmd(ax.logger, []string{"add", "table", tableFamily, ax.nfTableName()}); err != nil {
 		return err
 	}
 
@@ -532,7 +546,7 @@ func (ax *Nexodus) nfCreateTable() error {
 
 // nfCreateChain is used to create the nftables chain in the nf table
 func (ax *Nexodus) nfCreateChain(chainName string) error {
-	if _, err := runNftCmd(ax.logger, []string{"add", "chain", tableFamily, tableName, chainName, "{", "type", "filter", "hook", "input", "priority", "0", ";", "policy", "accept", ";", "}"}); err != nil {
+	if _, err := runNftCmd(ax.logger, []string{"add", "chain", tableFamily, ax.nfTableName(), chainName, "{", "type", "filter", "hook", "input", "priority", "0", ";", "policy", "accept", ";", "}"}); err != nil {
 		return err
 	}
 
@@ -0,0 +1,25 @@
+package nexodus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNfTableName(t *testing.T) {
+	require := require.New(t)
+
+	ax := &Nexodus{tunnelIface: wgIface}
+	require.Equal(tableName, ax.nfTableName())
+
+	// each member gets a table of its own, so applying the security group of one organization does
+	// not drop the rules of another.
+	seen := map[string]bool{tableName: true, natTableName: true}
+	for index := 1; index <= 10; index++ {
+		member := &Nexodus{member: true, tunnelIface: memberTunnelDev(wgIface, index)}
+		name := member.nfTableName()
+		require.False(seen[name], "%s is used twice", name)
+		seen[name] = true
+	}
+	require.Equal("nexodus-wg1", (&Nexodus{tunnelIface: "wg1"}).nfTableName())
+}
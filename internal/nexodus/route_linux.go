@@ -14,6 +14,10 @@ func (ax *Nexodus) handlePeerRouteOS(wgPeerConfig wgPeerConfig) {
 		if util.IsIPv6Prefix(allowedIP) && !ax.ipv6Supported {
 			continue
 		}
+		// the default routes of the exit node are handled by reconcileExitNode
+		if util.IsDefaultIPRoute(allowedIP) {
+			continue
+		}
 		routeExists, err := ax.RouteExists(allowedIP)
 		if err != nil {
 			ax.logger.Warnf("%v", err)
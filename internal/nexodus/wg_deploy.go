@@ -24,6 +24,8 @@ func (ax *Nexodus) DeployWireguardConfig(updatedPeers map[string]public.ModelsDe
 		if err := ax.setupInterface(); err != nil {
 			return err
 		}
+		// the mesh dns listens on the tunnel address and its resolver configuration is bound to the interface
+		ax.stopMeshDns()
 	}
 
 	// add routes and tunnels for the new peers only according to the cache diff
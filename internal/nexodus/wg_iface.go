@@ -161,6 +161,38 @@ func setupNftables(dev string) error {
 	return nil
 }
 
+// natTableName is the nftables table of the exit node masquerade rules.
+const natTableName = "nexodus_nat"
+
+// exitNodePrep prepare a node to be an exit node, in addition to the relay forwarding the traffic
+// leaving the mesh is masqueraded behind the addresses of the node
+func (ax *Nexodus) exitNodePrep() error {
+	if err := ax.relayPrep(); err != nil {
+		return err
+	}
+	return setupNatNftables(wgIface)
+}
+
+// setupNatNftables adds v4/v6 nftables rules to masquerade the traffic forwarded out of the tunnel.
+// The rules live in a table of their own that is flushed first, so a restart does not add them again
+// and the nat rules of other software are left alone.
+func setupNatNftables(dev string) error {
+	for _, family := range []string{"ip", "ip6"} {
+		nft := []string{
+			fmt.Sprintf("add table %s %s", family, natTableName),
+			fmt.Sprintf("add chain %s %s POSTROUTING { type nat hook postrouting priority 100; }", family, natTableName),
+			fmt.Sprintf("flush chain %s %s POSTROUTING", family, natTableName),
+			fmt.Sprintf(`add rule %s %s POSTROUTING iifname "%s" oifname != "%s" counter masquerade`, family, natTableName, dev, dev),
+		}
+		for _, cmd := range nft {
+			if err := runNftCommand(cmd); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func runNftCommand(cmd string) error {
 	nft := exec.Command("nft", cmd)
 	output, err := nft.CombinedOutput()
@@ -2,11 +2,14 @@ package nexodus
 
 import (
 	"net"
+	"net/netip"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/util"
 )
 
 func (nx *Nexodus) peerUpdated(device public.ModelsDevice, peer wgPeerConfig) bool {
@@ -34,15 +37,30 @@ func (ax *Nexodus) buildPeersConfig() map[string]public.ModelsDevice {
 
 	updatedPeers := map[string]public.ModelsDevice{}
 
-	_, ax.wireguardPubKeyInConfig = ax.deviceCache[ax.wireguardPubKey]
+	var self deviceCacheEntry
+	self, ax.wireguardPubKeyInConfig = ax.deviceCache[ax.wireguardPubKey]
 
 	relayAllowedIP := []string{
 		ax.org.Cidr,
 		ax.org.CidrV6,
 	}
+	// the organization prefixes change when it is grown or renumbered, the device carries the current ones.
+	if self.device.OrganizationPrefix != "" {
+		relayAllowedIP[0] = self.device.OrganizationPrefix
+	}
+	if self.device.OrganizationPrefixV6 != "" {
+		relayAllowedIP[1] = self.device.OrganizationPrefixV6
+	}
 
 	ax.buildLocalConfig()
 
+	hub, hubMode := ax.activeHub()
+	var hubAllowedIPs []string
+	if hubMode {
+		hubAllowedIPs = ax.hubAllowedIPs(hub, relayAllowedIP)
+		ax.removeSpokePeers(hub)
+	}
+
 	for _, d := range ax.deviceCache {
 		// skip ourselves
 		if d.device.PublicKey == ax.wireguardPubKey {
@@ -63,6 +81,25 @@ func (ax *Nexodus) buildPeersConfig() map[string]public.ModelsDevice {
 			continue
 		}
 
+		// In a hub organization the devices only peer with the hubs, the active hub routes the
+		// organization prefixes and the child prefixes of the other devices.
+		if hubMode && d.device.OrganizationId == ax.org.Id {
+			if !d.device.Hub {
+				continue
+			}
+			allowedIPs := append(d.device.AllowedIps, ax.peerChildPrefixes(d.device)...)
+			if d.device.PublicKey == hub.PublicKey {
+				allowedIPs = hubAllowedIPs
+			}
+			peerHub := ax.buildHubPeer(d.device, allowedIPs, localIP, reflexiveIP4)
+			if ax.peerUpdated(d.device, peerHub) {
+				updatedPeers[d.device.PublicKey] = d.device
+				ax.wgConfig.Peers[d.device.PublicKey] = peerHub
+				ax.logPeerInfo(d.device, peerHub.Endpoint)
+			}
+			continue
+		}
+
 		// The peer is a relay node
 		if d.device.Relay {
 			peerRelay := ax.buildRelayPeer(d.device, relayAllowedIP, localIP, reflexiveIP4)
@@ -130,7 +167,7 @@ func (ax *Nexodus) extractPeerPort(localIP string) string {
 // buildRelayPeer Build the relay peer entry that will be a CIDR block as opposed to a /32 host route. All nodes get this peer.
 // This is the only peer a symmetric NAT node will get unless it also has a direct peering
 func (ax *Nexodus) buildRelayPeer(device public.ModelsDevice, relayAllowedIP []string, localIP, reflexiveIP4 string) wgPeerConfig {
-	device.AllowedIps = append(device.AllowedIps, device.ChildPrefix...)
+	device.AllowedIps = append(device.AllowedIps, ax.peerChildPrefixes(device)...)
 	config := wgPeerConfig{
 		PublicKey:           device.PublicKey,
 		Endpoint:            reflexiveIP4,
@@ -143,10 +180,103 @@ func (ax *Nexodus) buildRelayPeer(device public.ModelsDevice, relayAllowedIP []s
 	return config
 }
 
+// activeHub returns the hub the device routes the traffic of a hub organization through, the online
+// hub with the lowest tunnel address, every device of the organization elects the same one. Hubs and
+// the devices of organizations without hubs peer with the other devices directly.
+// assumes deviceCacheLock is held.
+func (ax *Nexodus) activeHub() (public.ModelsDevice, bool) {
+	if ax.relay || ax.org == nil || !ax.org.HubZone {
+		return public.ModelsDevice{}, false
+	}
+	var hub public.ModelsDevice
+	found := false
+	for _, d := range ax.deviceCache {
+		if !d.device.Hub || d.device.OrganizationId != ax.org.Id || d.device.PublicKey == ax.wireguardPubKey {
+			continue
+		}
+		if !found || hubPreferred(d.device, hub) {
+			hub = d.device
+			found = true
+		}
+	}
+	return hub, found
+}
+
+// hubPreferred returns true if the hub a is elected over the hub b.
+func hubPreferred(a, b public.ModelsDevice) bool {
+	if a.Online != b.Online {
+		return a.Online
+	}
+	addrA, errA := netip.ParseAddr(a.TunnelIp)
+	addrB, errB := netip.ParseAddr(b.TunnelIp)
+	if errA == nil && errB == nil && addrA != addrB {
+		return addrA.Less(addrB)
+	}
+	return a.Id < b.Id
+}
+
+// hubAllowedIPs returns the prefixes routed through the active hub, the organization prefixes and the
+// child prefixes of the devices of the organization. The hubs don't forward the default routes of
+// the exit nodes, they are left out. assumes deviceCacheLock is held.
+func (ax *Nexodus) hubAllowedIPs(hub public.ModelsDevice, relayAllowedIP []string) []string {
+	allowedIPs := append([]string{}, relayAllowedIP...)
+	allowedIPs = append(allowedIPs, ax.peerChildPrefixes(hub)...)
+	var prefixes []string
+	for _, d := range ax.deviceCache {
+		if d.device.PublicKey == ax.wireguardPubKey || d.device.PublicKey == hub.PublicKey || d.device.OrganizationId != ax.org.Id {
+			continue
+		}
+		for _, prefix := range d.device.ActiveChildPrefix {
+			if !util.IsDefaultIPRoute(prefix) {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	// the device cache is a map, keep the order stable so the peer is only updated on changes
+	sort.Strings(prefixes)
+	return append(allowedIPs, prefixes...)
+}
+
+// buildHubPeer builds the peer entry of a hub, reached on its local address if it is behind the same
+// reflexive address as this device.
+func (ax *Nexodus) buildHubPeer(device public.ModelsDevice, allowedIPs []string, localIP, reflexiveIP4 string) wgPeerConfig {
+	config := wgPeerConfig{
+		PublicKey:           device.PublicKey,
+		Endpoint:            reflexiveIP4,
+		AllowedIPs:          allowedIPs,
+		PersistentKeepAlive: persistentKeepalive,
+	}
+	if ax.nodeReflexiveAddressIPv4.Addr().String() == parseIPfromAddrPort(reflexiveIP4) {
+		config.Endpoint = localIP
+	}
+	return config
+}
+
+// removeSpokePeers removes the direct peerings with the devices of a hub organization, configured before
+// the organization had a hub. The peer of the hub is rebuilt, to restore the routes the removals delete.
+// assumes deviceCacheLock is held.
+func (ax *Nexodus) removeSpokePeers(hub public.ModelsDevice) {
+	for _, d := range ax.deviceCache {
+		if d.device.Hub || d.device.OrganizationId != ax.org.Id {
+			continue
+		}
+		if _, ok := ax.wgConfig.Peers[d.device.PublicKey]; !ok {
+			continue
+		}
+		ax.logger.Debugf("Removing the direct peering with %s, the traffic is routed through the hub %s", d.device.PublicKey, hub.PublicKey)
+		if err := ax.deletePeer(d.device.PublicKey, ax.tunnelIface); err != nil {
+			ax.logger.Debugf("failed to delete peer %s: %v", d.device.PublicKey, err)
+		}
+		ax.handlePeerRouteDelete(ax.tunnelIface, d.device)
+		delete(ax.wgConfig.Peers, d.device.PublicKey)
+		delete(ax.wgConfig.Peers, hub.PublicKey)
+	}
+}
+
 // buildPeerForRelayNode build a config for all peers if this node is the organization's relay node. Also check for direct peering.
 // The peer for a relay node is currently left blank and assumed to be exposed to all peers, we still build its peer config for flexibility.
 func (ax *Nexodus) buildPeerForRelayNode(device public.ModelsDevice, localIP, reflexiveIP4 string) wgPeerConfig {
-	device.AllowedIps = append(device.AllowedIps, device.ChildPrefix...)
+	device.AllowedIps = append(device.AllowedIps, ax.peerChildPrefixes(device)...)
 	config := wgPeerConfig{
 		PublicKey:           device.PublicKey,
 		Endpoint:            reflexiveIP4,
@@ -163,7 +293,7 @@ func (ax *Nexodus) buildPeerForRelayNode(device public.ModelsDevice, localIP, re
 // The exception is if the peer is a relay node since that will get a peering with the org prefix supernet
 func (ax *Nexodus) buildDirectLocalPeer(device public.ModelsDevice, localIP, peerPort string) wgPeerConfig {
 	directLocalPeerEndpointSocket := net.JoinHostPort(device.EndpointLocalAddressIp4, peerPort)
-	device.AllowedIps = append(device.AllowedIps, device.ChildPrefix...)
+	device.AllowedIps = append(device.AllowedIps, ax.peerChildPrefixes(device)...)
 	return wgPeerConfig{
 		PublicKey:           device.PublicKey,
 		Endpoint:            directLocalPeerEndpointSocket,
@@ -175,7 +305,7 @@ func (ax *Nexodus) buildDirectLocalPeer(device public.ModelsDevice, localIP, pee
 // buildDefaultPeer the bulk of the peers will be added here except for local address peers or
 // symmetric NAT peers or if this device is itself a symmetric nat node, that require relaying.
 func (ax *Nexodus) buildDefaultPeer(device public.ModelsDevice, reflexiveIP4 string) wgPeerConfig {
-	device.AllowedIps = append(device.AllowedIps, device.ChildPrefix...)
+	device.AllowedIps = append(device.AllowedIps, ax.peerChildPrefixes(device)...)
 	return wgPeerConfig{
 		PublicKey:           device.PublicKey,
 		Endpoint:            reflexiveIP4,
@@ -3,6 +3,7 @@ package routers
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"github.com/go-session/redis/v3"
 	"github.com/go-session/session/v3"
 	"github.com/nexodus-io/nexodus/pkg/ginsession"
@@ -12,9 +13,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/handlers"
+	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/nexodus-io/nexodus/internal/util"
 	"github.com/nexodus-io/nexodus/internal/util/cache"
-	"github.com/open-policy-agent/opa/rego"
 	"golang.org/x/oauth2"
 )
 
@@ -22,26 +24,19 @@ import (
 const AuthUserName string = "_nexodus.UserName"
 
 //go:embed token.rego
-var policy string
+var embeddedPolicy string
 
 var jwksCache = cache.NewMemoizeCache[string, string](time.Second*30, time.Second*5)
 
 // Naive JWS Key validation
 func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(*gin.Context), error) {
-	query, err := rego.New(
-		rego.Query(`result = {
-			"authorized": data.token.valid_token,
-			"allow": data.token.allow,
-			"user_id": data.token.user_id,
-			"user_name": data.token.user_name,
-			"full_name": data.token.full_name,
-		}`),
-		rego.Store(o.Store),
-		rego.Module("policy.rego", policy),
-	).PrepareForEval(context.Background())
+	policy, err := newPolicy(o.Store, o.Logger, o.PolicyBundle)
 	if err != nil {
 		return nil, err
 	}
+	if policy.hasBundle() && o.PolicyBundleReloadInterval > 0 {
+		policy.startReloader(ctx, o.WaitGroup, o.PolicyBundleReloadInterval)
+	}
 
 	return func(c *gin.Context) {
 		logger := util.WithTrace(c.Request.Context(), o.Logger)
@@ -85,15 +80,35 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			"path":         path,
 		}
 
-		results, err := query.Eval(c.Request.Context(), rego.EvalInput(input))
-		if err != nil {
-			logger.Error(err)
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
+		subject := ""
+		if strings.HasPrefix(parts[1], models.PersonalAccessTokenPrefix) {
+			pat, userName, err := o.Api.LookupPersonalAccessToken(c.Request.Context(), parts[1])
+			if errors.Is(err, handlers.ErrInvalidPersonalAccessToken) {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				logger.Error(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			// the policy evaluates the personal access token like the payload of a JWT.
+			input["personal_access_token"] = map[string]interface{}{
+				"sub":                pat.UserID,
+				"scope":              strings.Join(pat.Scopes, " "),
+				"preferred_username": userName,
+			}
+			subject = pat.UserID
+		} else if policy.hasBundle() {
+			subject = unverifiedSubject(parts[1])
+		}
+		if policy.hasBundle() {
+			policy.addRequestInput(c, o, input, subject)
 		}
+
+		results, err := policy.eval(c.Request.Context(), input)
 		if err != nil {
 			logger.Error(err)
-			c.AbortWithStatus(http.StatusInternalServerError)
+			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		} else if len(results) == 0 {
 			logger.Error("undefined result from authz policy")
@@ -119,18 +134,6 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			return
 		}
 
-		allowed, ok := result["allow"].(bool)
-		if !ok {
-			logger.Error("allow is not a bool")
-			c.AbortWithStatus(http.StatusInternalServerError)
-			return
-		}
-		if !allowed {
-			logger.Debug("forbidden by authz policy")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
-
 		userID, ok := result["user_id"].(string)
 		if !ok {
 			logger.Error("user_id is not a string")
@@ -152,6 +155,8 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			return
 		}
 
+		// the user is known before the request is authorized, so that the audit trail records who was
+		// forbidden.
 		c.Set(gin.AuthUserKey, userID)
 		if len(username) > 0 {
 			c.Set(AuthUserName, username)
@@ -161,6 +166,45 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			logger.Debugf("Not able to determine a name for this user -- %s", userID)
 		}
 
+		allowed, ok := result["allow"].(bool)
+		if !ok {
+			logger.Error("allow is not a bool")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			logger.Debug("forbidden by authz policy")
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		denied, ok := result["denied"].(bool)
+		if !ok {
+			logger.Error("denied is not a bool")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if denied {
+			logger.Debug("denied by the authz policy bundle")
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		scopes, ok := result["scopes"].([]interface{})
+		if !ok {
+			logger.Error("scopes is not an array")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		scopeNames := make([]string, 0, len(scopes))
+		for _, scope := range scopes {
+			if name, ok := scope.(string); ok && name != "" {
+				scopeNames = append(scopeNames, name)
+			}
+		}
+
+		c.Set(handlers.AuthUserScopes, scopeNames)
+
 		logger.Debugf("user-id is %s", userID)
 		c.Next()
 	}, nil
@@ -0,0 +1,292 @@
+package routers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"go.uber.org/zap"
+)
+
+// maxPolicyInputBodySize is the largest request body passed to the policy bundles.
+const maxPolicyInputBodySize = 1 << 20
+
+// policyQuery evaluates the embedded policy together with the modules of the policy bundle. The
+// bundle modules are confined to the bundle package, the embedded policy only reads its deny
+// rules, any of them rejects the request.
+// bundlePackage is the package of the policy bundle modules, its subpackages can hold helpers.
+const bundlePackage = "data.bundle"
+
+// emptyBundle is compiled with the embedded policy when no policy bundle defines deny rules, the
+// embedded policy denies the requests when the deny rules are missing or are not a set.
+const emptyBundle = `package bundle
+
+deny := set()
+`
+
+// reservedDataKeys are the documents a policy bundle can't write to the store, they are managed by the
+// apiserver or hold the rules of the policies.
+var reservedDataKeys = []string{"nexodus", "token", "bundle"}
+
+const policyQuery = `result = {
+	"authorized": data.token.valid_token,
+	"allow": data.token.allow,
+	"denied": data.token.denied,
+	"user_id": data.token.user_id,
+	"user_name": data.token.user_name,
+	"full_name": data.token.full_name,
+	"scopes": data.token.scopes,
+}`
+
+// policy holds the prepared authorization query, it is replaced when the policy bundle is reloaded.
+type policy struct {
+	store  storage.Store
+	logger *zap.SugaredLogger
+	query  atomic.Pointer[rego.PreparedEvalQuery]
+
+	// the policy bundle, when there is one.
+	bundlePath     string
+	bundleHash     []byte
+	bundleDataKeys []string
+}
+
+func newPolicy(store storage.Store, logger *zap.SugaredLogger, bundlePath string) (*policy, error) {
+	p := &policy{
+		store:      store,
+		logger:     logger,
+		bundlePath: bundlePath,
+	}
+	if bundlePath == "" {
+		query, err := prepareQuery(store, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.query.Store(&query)
+		return p, nil
+	}
+	if _, err := p.reload(); err != nil {
+		return nil, fmt.Errorf("failed to load the policy bundle %s: %w", bundlePath, err)
+	}
+	return p, nil
+}
+
+func prepareQuery(store storage.Store, modules map[string]string) (rego.PreparedEvalQuery, error) {
+	options := []func(*rego.Rego){
+		rego.Query(policyQuery),
+		rego.Store(store),
+		rego.Module("policy.rego", embeddedPolicy),
+	}
+	if len(modules) == 0 {
+		modules = map[string]string{"bundle/empty.rego": emptyBundle}
+	}
+	for name, module := range modules {
+		options = append(options, rego.Module(name, module))
+	}
+	return rego.New(options...).PrepareForEval(context.Background())
+}
+
+func (p *policy) eval(ctx context.Context, input map[string]interface{}) (rego.ResultSet, error) {
+	return p.query.Load().Eval(ctx, rego.EvalInput(input))
+}
+
+// hasBundle returns true if a policy bundle is loaded, the richer input is only built for them.
+func (p *policy) hasBundle() bool {
+	return p.bundlePath != ""
+}
+
+// startReloader checks the policy bundle for changes every interval.
+func (p *policy) startReloader(ctx context.Context, wg *sync.WaitGroup, interval time.Duration) {
+	util.GoWithWaitGroup(wg, func() {
+		util.RunPeriodically(ctx, interval, func() {
+			if reloaded, err := p.reload(); err != nil {
+				p.logger.Warnf("failed to reload the policy bundle %s, the previous policy is kept: %v", p.bundlePath, err)
+			} else if reloaded {
+				p.logger.Infof("Reloaded the policy bundle %s", p.bundlePath)
+			}
+		})
+	})
+}
+
+// reload loads the policy bundle if it has changed, its modules are compiled together with the
+// embedded policy before they replace the current ones, and its data is written to the store.
+func (p *policy) reload() (bool, error) {
+	hash, err := hashPath(p.bundlePath)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(hash, p.bundleHash) {
+		return false, nil
+	}
+
+	b, err := loader.NewFileLoader().AsBundle(p.bundlePath)
+	if err != nil {
+		return false, err
+	}
+	modules := map[string]string{}
+	hasDeny := false
+	for _, module := range b.Modules {
+		// the modules can only deny requests, a module in another package could add rules to the
+		// embedded policy or override the policy data.
+		pkg := module.Parsed.Package.Path.String()
+		if pkg != bundlePackage && !strings.HasPrefix(pkg, bundlePackage+".") {
+			return false, fmt.Errorf("the bundle module %s must be in the bundle package, not %s", module.Path, pkg)
+		}
+		if pkg == bundlePackage {
+			for _, rule := range module.Parsed.Rules {
+				if !rule.Head.Ref()[0].Equal(ast.VarTerm("deny")) {
+					continue
+				}
+				// a deny rule that is not a set would leave the requests undecided.
+				if len(rule.Head.Ref()) > 1 || rule.Head.RuleKind() != ast.MultiValue {
+					return false, fmt.Errorf("the deny rules of the bundle module %s must be a set, like deny contains msg if { ... }", module.Path)
+				}
+				hasDeny = true
+			}
+		}
+		modules["bundle/"+strings.TrimPrefix(module.Path, "/")] = string(module.Raw)
+	}
+	if !hasDeny {
+		modules["bundle/empty.rego"] = emptyBundle
+	}
+	for _, key := range reservedDataKeys {
+		if _, ok := b.Data[key]; ok {
+			return false, fmt.Errorf("the bundle data can't contain the %s document", key)
+		}
+	}
+	query, err := prepareQuery(p.store, modules)
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.Background()
+	keys := make([]string, 0, len(b.Data))
+	for key, value := range b.Data {
+		if err := storage.WriteOne(ctx, p.store, storage.AddOp, storage.Path{key}, value); err != nil {
+			return false, err
+		}
+		keys = append(keys, key)
+	}
+	for _, key := range p.bundleDataKeys {
+		if _, ok := b.Data[key]; !ok {
+			if err := storage.WriteOne(ctx, p.store, storage.RemoveOp, storage.Path{key}, nil); err != nil && !storage.IsNotFound(err) {
+				return false, err
+			}
+		}
+	}
+	p.query.Store(&query)
+	p.bundleDataKeys = keys
+	p.bundleHash = hash
+	return true, nil
+}
+
+// hashPath hashes a file, or the names and contents of the files in a directory.
+func hashPath(path string) ([]byte, error) {
+	hash := sha256.New()
+	var files []string
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = fmt.Fprintf(hash, "%s\n%d\n", file, len(content))
+		_, _ = hash.Write(content)
+	}
+	return hash.Sum(nil), nil
+}
+
+// addRequestInput adds the route, its parameters, the json request body and the organization role
+// of the user to the policy input, so that the policy bundles can look at the resources of a request.
+func (p *policy) addRequestInput(c *gin.Context, o APIRouterOptions, input map[string]interface{}, userID string) {
+	input["route"] = c.FullPath()
+	params := map[string]interface{}{}
+	for _, param := range c.Params {
+		params[param.Key] = param.Value
+	}
+	input["params"] = params
+
+	body := map[string]interface{}{}
+	if c.Request.Body != nil && c.Request.ContentLength <= maxPolicyInputBodySize && strings.HasPrefix(c.ContentType(), "application/json") {
+		// the content length of a chunked request is unknown, so read one byte past the limit to
+		// tell whether the body is too large.
+		content, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPolicyInputBodySize+1))
+		// the handlers read the whole body again, including what was not read here.
+		c.Request.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(content), c.Request.Body),
+			Closer: c.Request.Body,
+		}
+		if err == nil && len(content) <= maxPolicyInputBodySize {
+			_ = json.Unmarshal(content, &body)
+		}
+	}
+	input["body"] = body
+
+	role := ""
+	orgID := c.Param("organization")
+	if orgID == "" {
+		orgID, _ = body["organization_id"].(string)
+	}
+	if id, err := uuid.Parse(orgID); err == nil && userID != "" {
+		role, err = o.Api.OrganizationRole(c.Request.Context(), userID, id)
+		if err != nil {
+			p.logger.Warnf("failed to lookup the organization role of the user: %v", err)
+		}
+	}
+	input["organization_role"] = role
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// unverifiedSubject returns the sub claim of a JWT without verifying it, the policy verifies the
+// token and computes the user_id from the same claim.
+func unverifiedSubject(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.Sub
+}
@@ -0,0 +1,167 @@
+package routers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPolicyBundle(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	writeFile := func(name string, content string) {
+		require.NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	writeFile("relays.rego", `package bundle
+
+import future.keywords
+
+deny contains "relays must be named relay-*" if {
+	input.route == "/api/devices"
+	input.body.relay
+	not startswith(input.body.hostname, data.relays.prefix)
+}
+`)
+	writeFile("data.json", `{"relays": {"prefix": "relay-"}}`)
+
+	p, err := newPolicy(inmem.New(), zap.NewNop().Sugar(), dir)
+	require.NoError(err)
+	require.True(p.hasBundle())
+
+	evalWithScope := func(scope string, body map[string]interface{}) map[string]interface{} {
+		results, err := p.eval(context.Background(), map[string]interface{}{
+			"method": "POST",
+			"path":   []string{"api", "devices"},
+			"route":  "/api/devices",
+			"body":   body,
+			"personal_access_token": map[string]interface{}{
+				"sub":   "e3a8b4c2-07b7-4ea4-a9b2-a0e6d6e1a2d1",
+				"scope": scope,
+			},
+		})
+		require.NoError(err)
+		require.Len(results, 1)
+		return results[0].Bindings["result"].(map[string]interface{})
+	}
+	eval := func(body map[string]interface{}) map[string]interface{} {
+		return evalWithScope("write:devices", body)
+	}
+
+	result := eval(map[string]interface{}{"relay": true, "hostname": "relay-1"})
+	require.Equal(true, result["allow"])
+	require.Equal(false, result["denied"])
+	result = eval(map[string]interface{}{"relay": true, "hostname": "laptop"})
+	require.Equal(true, result["allow"])
+	require.Equal(true, result["denied"])
+
+	// an unchanged bundle is not reloaded.
+	reloaded, err := p.reload()
+	require.NoError(err)
+	require.False(reloaded)
+
+	// the data changes are picked up on reload.
+	writeFile("data.json", `{"relays": {"prefix": "lap"}}`)
+	reloaded, err = p.reload()
+	require.NoError(err)
+	require.True(reloaded)
+	require.Equal(false, eval(map[string]interface{}{"relay": true, "hostname": "laptop"})["denied"])
+
+	// an invalid bundle keeps the previous policy.
+	writeFile("relays.rego", "package bundle\n\ndeny contains")
+	_, err = p.reload()
+	require.Error(err)
+	require.Equal(true, eval(map[string]interface{}{"relay": true, "hostname": "relay-1"})["denied"])
+
+	// the bundles can't add rules to the embedded policy.
+	writeFile("relays.rego", "package token\n\nallow := true\n")
+	_, err = p.reload()
+	require.Error(err)
+	writeFile("relays.rego", "package nexodus\n\nallowed_email_domains := []\n")
+	_, err = p.reload()
+	require.Error(err)
+	writeFile("relays.rego", "package bundle\n\nallow := true\n\nvalid_token := true\n")
+	reloaded, err = p.reload()
+	require.NoError(err)
+	require.True(reloaded)
+	require.Equal(false, evalWithScope("read:devices", map[string]interface{}{})["allow"])
+
+	// the deny rules must be a set.
+	writeFile("relays.rego", "package bundle\n\ndeny := true\n")
+	_, err = p.reload()
+	require.Error(err)
+	writeFile("relays.rego", "package bundle\n\ndeny[\"relays\"] := true\n")
+	_, err = p.reload()
+	require.Error(err)
+
+	// the nexodus document is reserved for the policy data of the apiserver.
+	require.NoError(os.Remove(filepath.Join(dir, "relays.rego")))
+	writeFile("data.json", `{"nexodus": {"audience": "other"}}`)
+	_, err = p.reload()
+	require.Error(err)
+
+	// removed data and modules are dropped.
+	require.NoError(os.Remove(filepath.Join(dir, "data.json")))
+	reloaded, err = p.reload()
+	require.NoError(err)
+	require.True(reloaded)
+	require.Equal(false, eval(map[string]interface{}{"relay": true, "hostname": "laptop"})["denied"])
+	_, err = newPolicy(inmem.New(), zap.NewNop().Sugar(), filepath.Join(dir, "missing"))
+	require.Error(err)
+}
+
+func TestPolicyRequestInput(t *testing.T) {
+	require := require.New(t)
+
+	p, err := newPolicy(inmem.New(), zap.NewNop().Sugar(), "")
+	require.NoError(err)
+	require.False(p.hasBundle())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var input map[string]interface{}
+	r.PATCH("/api/devices/:id", func(c *gin.Context) {
+		input = map[string]interface{}{}
+		p.addRequestInput(c, APIRouterOptions{}, input, "")
+		c.Next()
+	}, func(c *gin.Context) {
+		// the handlers can still read the body.
+		body, err := io.ReadAll(c.Request.Body)
+		require.NoError(err)
+		c.String(http.StatusOK, string(body))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/api/devices/42", strings.NewReader(`{"hostname":"relay-1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	require.Equal(`{"hostname":"relay-1"}`, w.Body.String())
+	require.Equal(map[string]interface{}{
+		"route":             "/api/devices/:id",
+		"params":            map[string]interface{}{"id": "42"},
+		"body":              map[string]interface{}{"hostname": "relay-1"},
+		"organization_role": "",
+	}, input)
+
+	// a chunked body larger than the limit is not passed to the policy, and reaches the handler whole.
+	large := `{"hostname":"` + strings.Repeat("a", maxPolicyInputBodySize) + `"}`
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPatch, "/api/devices/42", io.MultiReader(strings.NewReader(large)))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	require.Equal(large, w.Body.String())
+	require.Equal(map[string]interface{}{}, input["body"])
+}
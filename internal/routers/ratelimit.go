@@ -0,0 +1,193 @@
+package routers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+var rateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "apiserver",
+	Name:      "rate_limited_requests_total",
+	Help:      "Number of API requests rejected by the rate limits, by route group and scope.",
+}, []string{"group", "scope"})
+
+// RateLimit is a token bucket that allows Rate requests per second on average, with bursts of up to Burst requests.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimits configures the rate limits of an API route group, they apply to each user and to each
+// organization the requests are made in. A zero RateLimit does not limit the requests.
+type RateLimits struct {
+	User         RateLimit
+	Organization RateLimit
+}
+
+// RateLimitGroups are the API route groups the rate limits can be configured for, the "*" group
+// configures the groups that are not configured explicitly.
+var RateLimitGroups = []string{"*", "organizations", "devices", "invitations", "users", "security_groups", "fflags", "admin"}
+
+// ParseRateLimits parses rate limits formatted as <group>.<user|organization>=<rate>[:<burst>], like
+// "devices.user=5:10", the burst defaults to the rate rounded up.
+func ParseRateLimits(specs []string) (map[string]RateLimits, error) {
+	result := map[string]RateLimits{}
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		group, scope, ok2 := strings.Cut(key, ".")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid rate limit '%s', expected <group>.<user|organization>=<rate>[:<burst>]", spec)
+		}
+		if !validRateLimitGroup(group) {
+			return nil, fmt.Errorf("invalid rate limit '%s', the group must be one of %s", spec, strings.Join(RateLimitGroups, ", "))
+		}
+		rateValue, burstValue, hasBurst := strings.Cut(value, ":")
+		var limit RateLimit
+		var err error
+		if limit.Rate, err = strconv.ParseFloat(rateValue, 64); err != nil || limit.Rate < 0 {
+			return nil, fmt.Errorf("invalid rate limit '%s', the rate must be a positive number", spec)
+		}
+		limit.Burst = int(math.Ceil(limit.Rate))
+		if hasBurst {
+			if limit.Burst, err = strconv.Atoi(burstValue); err != nil || limit.Burst < 1 {
+				return nil, fmt.Errorf("invalid rate limit '%s', the burst must be a positive integer", spec)
+			}
+		}
+
+		limits := result[group]
+		switch scope {
+		case "user":
+			limits.User = limit
+		case "organization":
+			limits.Organization = limit
+		default:
+			return nil, fmt.Errorf("invalid rate limit '%s', the scope must be user or organization", spec)
+		}
+		result[group] = limits
+	}
+	return result, nil
+}
+
+func validRateLimitGroup(group string) bool {
+	for _, g := range RateLimitGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// keyedLimiter holds a token bucket per key.
+type keyedLimiter struct {
+	limit     RateLimit
+	mu        sync.Mutex
+	limiters  map[string]*limiterEntry
+	lastSweep time.Time
+}
+
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+func newKeyedLimiter(limit RateLimit) *keyedLimiter {
+	return &keyedLimiter{
+		limit:    limit,
+		limiters: map[string]*limiterEntry{},
+	}
+}
+
+// allow takes a token from the bucket of the key, when the bucket is empty it returns
+// how long to wait before retrying.
+func (kl *keyedLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	kl.sweep(now)
+
+	entry, ok := kl.limiters[key]
+	if !ok {
+		entry = &limiterEntry{limiter: rate.NewLimiter(rate.Limit(kl.limit.Rate), kl.limit.Burst)}
+		kl.limiters[key] = entry
+	}
+	entry.lastUsed = now
+
+	reservation := entry.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Second
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep drops the buckets that have been idle long enough to be full again, a new bucket behaves the same.
+func (kl *keyedLimiter) sweep(now time.Time) {
+	if now.Sub(kl.lastSweep) < time.Minute {
+		return
+	}
+	kl.lastSweep = now
+	idle := time.Duration(float64(kl.limit.Burst) / kl.limit.Rate * float64(time.Second))
+	if idle < time.Minute {
+		idle = time.Minute
+	}
+	for key, entry := range kl.limiters {
+		if now.Sub(entry.lastUsed) > idle {
+			delete(kl.limiters, key)
+		}
+	}
+}
+
+// organizationParam returns the organization path parameter of a request.
+func organizationParam(c *gin.Context) string {
+	return c.Param("organization")
+}
+
+// rateLimiter returns a middleware that limits the requests of a route group per user, and per
+// organization for the requests the organization function maps to one. The function is only
+// called when an organization rate limit is configured.
+func rateLimiter(group string, limits RateLimits, organization func(c *gin.Context) string) gin.HandlerFunc {
+	var users, organizations *keyedLimiter
+	if limits.User.Rate > 0 {
+		users = newKeyedLimiter(limits.User)
+	}
+	if limits.Organization.Rate > 0 {
+		organizations = newKeyedLimiter(limits.Organization)
+	}
+	return func(c *gin.Context) {
+		now := time.Now()
+		if users != nil {
+			if ok, retryAfter := users.allow(c.GetString(gin.AuthUserKey), now); !ok {
+				rejectRateLimited(c, group, "user", retryAfter)
+				return
+			}
+		}
+		if organizations != nil {
+			if id := organization(c); id != "" {
+				if ok, retryAfter := organizations.allow(id, now); !ok {
+					rejectRateLimited(c, group, "organization", retryAfter)
+					return
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+func rejectRateLimited(c *gin.Context, group string, scope string, retryAfter time.Duration) {
+	rateLimitedRequests.WithLabelValues(group, scope).Inc()
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, models.BaseError{Error: "rate limit exceeded"})
+}
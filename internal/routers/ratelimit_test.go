@@ -0,0 +1,97 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	require := require.New(t)
+
+	limits, err := ParseRateLimits([]string{"*.user=10", "devices.user=0.5:5", "devices.organization=100:200"})
+	require.NoError(err)
+	require.Equal(map[string]RateLimits{
+		"*":       {User: RateLimit{Rate: 10, Burst: 10}},
+		"devices": {User: RateLimit{Rate: 0.5, Burst: 5}, Organization: RateLimit{Rate: 100, Burst: 200}},
+	}, limits)
+
+	for _, spec := range []string{"devices=10", "unknown.user=10", "devices.team=10", "devices.user=fast", "devices.user=10:0"} {
+		_, err := ParseRateLimits([]string{spec})
+		require.Error(err, spec)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	require := require.New(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.GET("/organizations/:organization", rateLimiter("organizations", RateLimits{
+		User:         RateLimit{Rate: 0.001, Burst: 2},
+		Organization: RateLimit{Rate: 0.001, Burst: 3},
+	}, organizationParam), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	get := func(user string, org string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/organizations/"+org, nil)
+		req.Header.Set("X-User", user)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	// each user has its own bucket.
+	require.Equal(http.StatusOK, get("a", "org1").Code)
+	require.Equal(http.StatusOK, get("a", "org2").Code)
+	res := get("a", "org3")
+	require.Equal(http.StatusTooManyRequests, res.Code)
+	require.NotEmpty(res.Header().Get("Retry-After"))
+	require.JSONEq(`{"error":"rate limit exceeded"}`, res.Body.String())
+
+	// and each organization too.
+	require.Equal(http.StatusOK, get("b", "org1").Code)
+	require.Equal(http.StatusOK, get("c", "org1").Code)
+	require.Equal(http.StatusTooManyRequests, get("d", "org1").Code)
+
+	// the routes without an organization parameter are limited by the organization of the resource.
+	r.GET("/devices/:id", rateLimiter("devices", RateLimits{
+		Organization: RateLimit{Rate: 0.001, Burst: 1},
+	}, func(c *gin.Context) string {
+		return "org-of-" + c.Param("id")
+	}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	getDevice := func(id string) int {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/devices/"+id, nil))
+		return res.Code
+	}
+	require.Equal(http.StatusOK, getDevice("device1"))
+	require.Equal(http.StatusTooManyRequests, getDevice("device1"))
+	require.Equal(http.StatusOK, getDevice("device2"))
+}
+
+func TestKeyedLimiterSweep(t *testing.T) {
+	require := require.New(t)
+
+	limiter := newKeyedLimiter(RateLimit{Rate: 1, Burst: 1})
+	now := time.Now()
+	ok, _ := limiter.allow("a", now)
+	require.True(ok)
+	ok, retryAfter := limiter.allow("a", now)
+	require.False(ok)
+	require.Equal(time.Second, retryAfter)
+
+	// the idle buckets are dropped once they are full again.
+	ok, _ = limiter.allow("b", now.Add(2*time.Minute))
+	require.True(ok)
+	require.Len(limiter.limiters, 1)
+}
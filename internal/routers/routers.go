@@ -6,6 +6,7 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -37,6 +38,13 @@ type APIRouterOptions struct {
 	Store           storage.Store
 	RedisServer     string
 	RedisDB         int
+	// RateLimits holds the rate limits by route group, see RateLimitGroups.
+	RateLimits map[string]RateLimits
+	// PolicyBundle is an optional directory or OPA bundle file with authorization policies that
+	// are evaluated with the embedded one, it is reloaded every PolicyBundleReloadInterval.
+	PolicyBundle               string
+	PolicyBundleReloadInterval time.Duration
+	WaitGroup                  *sync.WaitGroup
 }
 
 func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error) {
@@ -80,41 +88,96 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 
 		private.Use(validateJWT)
 		private.Use(api.CreateUserIfNotExists())
+		limit := func(group string, organization func(c *gin.Context) string) gin.HandlerFunc {
+			limits, ok := o.RateLimits[group]
+			if !ok {
+				limits = o.RateLimits["*"]
+			}
+			return rateLimiter(group, limits, organization)
+		}
+		// the routes of a device or an invitation are limited by the organization it belongs to.
+		deviceOrganization := func(c *gin.Context) string {
+			if id := c.Param("organization"); id != "" {
+				return id
+			}
+			if id := c.Param("id"); id != "" {
+				return api.DeviceOrganization(c, id)
+			}
+			return ""
+		}
+		invitationOrganization := func(c *gin.Context) string {
+			if id := c.Param("invitation"); id != "" {
+				return api.InvitationOrganization(c, id)
+			}
+			return ""
+		}
 		// Organizations
-		private.GET("/organizations", api.ListOrganizations)
-		private.POST("/organizations", api.CreateOrganization)
-		private.GET("/organizations/:organization", api.GetOrganizations)
-		private.DELETE("/organizations/:organization", api.DeleteOrganization)
-		private.GET("/organizations/:organization/devices", api.ListDevicesInOrganization)
-		private.GET("/organizations/:organization/devices/:id", api.GetDeviceInOrganization)
-		private.GET("/organizations/:organization/users", api.ListUsersInOrganization)
+		organizations := private.Group("", limit("organizations", organizationParam))
+		organizations.GET("/organizations", api.ListOrganizations)
+		organizations.POST("/organizations", api.CreateOrganization)
+		organizations.GET("/organizations/:organization", api.GetOrganizations)
+		organizations.DELETE("/organizations/:organization", api.DeleteOrganization)
+		organizations.PATCH("/organizations/:organization", api.UpdateOrganization)
+		organizations.POST("/organizations/:organization/renumber", api.RenumberOrganization)
+		organizations.GET("/organizations/:organization/users", api.ListUsersInOrganization)
+		organizations.GET("/organizations/:organization/connectivity", api.GetOrganizationConnectivity)
+		organizations.GET("/organizations/:organization/routes", api.GetOrganizationRoutes)
+		organizations.POST("/organizations/:organization/peerings", api.CreateOrganizationPeering)
+		organizations.GET("/organizations/:organization/peerings", api.ListOrganizationPeerings)
+		organizations.PATCH("/organizations/:organization/peerings/:id", api.UpdateOrganizationPeering)
+		organizations.DELETE("/organizations/:organization/peerings/:id", api.DeleteOrganizationPeering)
 		// Invitations
-		private.POST("/invitations", api.CreateInvitation)
-		private.GET("/invitations", api.ListInvitations)
-		private.GET("/invitations/:invitation", api.GetInvitation)
-		private.POST("/invitations/:invitation/accept", api.AcceptInvitation)
-		private.DELETE("/invitations/:invitation", api.DeleteInvitation)
+		invitations := private.Group("", limit("invitations", invitationOrganization))
+		invitations.POST("/invitations", api.CreateInvitation)
+		invitations.GET("/invitations", api.ListInvitations)
+		invitations.GET("/invitations/:invitation", api.GetInvitation)
+		invitations.POST("/invitations/:invitation/accept", api.AcceptInvitation)
+		invitations.DELETE("/invitations/:invitation", api.DeleteInvitation)
 		// Devices
-		private.GET("/devices", api.ListDevices)
-		private.GET("/devices/:id", api.GetDevice)
-		private.PATCH("/devices/:id", api.UpdateDevice)
-		private.POST("/devices", api.CreateDevice)
-		private.DELETE("/devices/:id", api.DeleteDevice)
+		devices := private.Group("", limit("devices", deviceOrganization))
+		devices.GET("/organizations/:organization/devices", api.ListDevicesInOrganization)
+		devices.GET("/organizations/:organization/devices/:id", api.GetDeviceInOrganization)
+		devices.GET("/devices", api.ListDevices)
+		devices.GET("/devices/:id", api.GetDevice)
+		devices.PATCH("/devices/:id", api.UpdateDevice)
+		devices.POST("/devices", api.CreateDevice)
+		devices.DELETE("/devices/:id", api.DeleteDevice)
+		devices.POST("/devices/:id/heartbeat", api.HeartbeatDevice)
+		devices.PUT("/devices/:id/peer_status", api.ReportDevicePeerStatus)
 		// Users
-		private.GET("/users/:id", api.GetUser)
-		private.GET("/users", api.ListUsers)
-		// private.PATCH("/users/:id", api.PatchUser)
-		private.DELETE("/users/:id", api.DeleteUser)
-		private.DELETE("/users/:id/organizations/:organization", api.DeleteUserFromOrganization)
+		users := private.Group("", limit("users", organizationParam))
+		users.GET("/users/:id", api.GetUser)
+		users.GET("/users", api.ListUsers)
+		// users.PATCH("/users/:id", api.PatchUser)
+		users.DELETE("/users/:id", api.DeleteUser)
+		users.DELETE("/users/:id/organizations/:organization", api.DeleteUserFromOrganization)
+		users.POST("/personal_access_tokens", api.CreatePersonalAccessToken)
+		users.GET("/personal_access_tokens", api.ListPersonalAccessTokens)
+		users.GET("/personal_access_tokens/:id", api.GetPersonalAccessToken)
+		users.DELETE("/personal_access_tokens/:id", api.DeletePersonalAccessToken)
 		// Security Groups
-		private.POST("/organizations/:organization/security_groups", api.CreateSecurityGroup)
-		private.GET("/organizations/:organization/security_groups", api.ListSecurityGroups)
-		private.DELETE("/organizations/:organization/security_groups/:id", api.DeleteSecurityGroup)
-		private.GET("/organizations/:organization/security_group/:id", api.GetSecurityGroup)
-		private.PATCH("/organizations/:organization/security_groups/:id", api.UpdateSecurityGroup)
+		securityGroups := private.Group("", limit("security_groups", organizationParam))
+		securityGroups.POST("/organizations/:organization/security_groups", api.CreateSecurityGroup)
+		securityGroups.GET("/organizations/:organization/security_groups", api.ListSecurityGroups)
+		securityGroups.DELETE("/organizations/:organization/security_groups/:id", api.DeleteSecurityGroup)
+		securityGroups.GET("/organizations/:organization/security_group/:id", api.GetSecurityGroup)
+		securityGroups.PATCH("/organizations/:organization/security_groups/:id", api.UpdateSecurityGroup)
 		// Feature Flags
-		private.GET("fflags", api.ListFeatureFlags)
-		private.GET("fflags/:name", api.GetFeatureFlag)
+		fflags := private.Group("", limit("fflags", organizationParam))
+		fflags.GET("fflags", api.ListFeatureFlags)
+		fflags.GET("fflags/:name", api.GetFeatureFlag)
+		// Admin, the token.rego policy only allows the users with the admin role. The requests are audited
+		// before they are authorized, so that the forbidden ones are recorded too.
+		admin := r.Group("/api/admin", loggerMiddleware, api.AuditAdminRequests(), validateJWT, api.CreateUserIfNotExists(), limit("admin", organizationParam))
+		admin.GET("/organizations", api.AdminListOrganizations)
+		admin.GET("/devices", api.AdminListDevices)
+		admin.DELETE("/devices/:id", api.AdminDeleteDevice)
+		admin.GET("/users", api.AdminListUsers)
+		admin.GET("/users/:id", api.AdminGetUser)
+		admin.GET("/audit_events", api.AdminListAuditEvents)
+		admin.GET("/fflags", api.AdminListFeatureFlags)
+		admin.PUT("/fflags/:name", api.AdminUpdateFeatureFlag)
+		admin.DELETE("/fflags/:name", api.AdminResetFeatureFlag)
 	}
 
 	r.GET("/api/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler), loggerMiddleware)
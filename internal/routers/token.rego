@@ -4,23 +4,55 @@ import future.keywords
 
 default valid_token := false
 
+# the apiserver stores its policy configuration in data.nexodus.
+default audience := "account"
+
+audience := data.nexodus.audience
+
+default allowed_email_domains := []
+
+allowed_email_domains := data.nexodus.allowed_email_domains
+
+default allowed_issuers := []
+
+allowed_issuers := data.nexodus.allowed_issuers
+
+# the users that log in through Google are denied when no email domain is allowed.
 default allowed_email := false
 
 allowed_email if {
 	token_payload.from_google
-	endswith(token_payload.email, "redhat.com")
+	some domain in allowed_email_domains
+	email_in_domain(lower(token_payload.email), lower(domain))
 }
 
 allowed_email if {
 	not token_payload.from_google
 }
 
+email_in_domain(email, domain) if endswith(email, concat("", ["@", domain]))
+
+# subdomains of an allowed domain are allowed too.
+email_in_domain(email, domain) if endswith(email, concat("", [".", domain]))
+
+default allowed_issuer := false
+
+allowed_issuer if count(allowed_issuers) == 0
+
+allowed_issuer if token_payload.iss in allowed_issuers
+
 valid_token if {
-	[valid, _, _] := io.jwt.decode_verify(input.access_token, {"cert": input.jwks, "aud": "account"})
+	[valid, _, _] := io.jwt.decode_verify(input.access_token, {"cert": input.jwks, "aud": audience})
 	valid == true
+	allowed_issuer
 	allowed_email
 }
 
+# personal access tokens are validated by the apiserver before the policy is evaluated.
+valid_token if {
+	input.personal_access_token
+}
+
 default allow := false
 
 allow if {
@@ -93,16 +125,56 @@ allow if {
 	contains(token_payload.scope, "write:organizations")
 }
 
+allow if {
+	"personal_access_tokens" = input.path[1]
+	action_is_read
+	valid_token
+	contains(token_payload.scope, "read:users")
+}
+
+# a personal access token can't be used to create or delete personal access tokens.
+allow if {
+	"personal_access_tokens" = input.path[1]
+	action_is_write
+	valid_token
+	not input.personal_access_token
+	contains(token_payload.scope, "write:users")
+}
+
 allow if {
 	"fflags" = input.path[1]
 	valid_token
 }
 
+# the admin api can't be used with a personal access token.
+allow if {
+	"admin" = input.path[1]
+	valid_token
+	not input.personal_access_token
+	is_admin
+}
+
+# The policy bundles loaded with --policy-bundle add deny rules to the bundle package, any of them
+# rejects a request that is otherwise allowed. The bundles can't add rules to this package. The
+# requests are denied unless the deny rules are an empty set, so that a broken bundle fails closed.
+default denied := true
+
+denied := false if {
+	is_set(data.bundle.deny)
+	count(data.bundle.deny) == 0
+}
+
+default is_admin := false
+
+is_admin if "admin" in token_payload.realm_access.roles
+
 action_is_read if input.method in ["GET"]
 
 action_is_write := input.method in ["POST", "PATCH", "DELETE", "PUT"]
 
-token_payload := payload if {
+token_payload := input.personal_access_token if {
+	input.personal_access_token
+} else := payload if {
 	[_, payload, _] = io.jwt.decode(input.access_token)
 }
 
@@ -117,3 +189,7 @@ user_name = token_payload.preferred_username
 default full_name = ""
 
 full_name = token_payload.name
+
+default scopes := []
+
+scopes := split(token_payload.scope, " ")
@@ -200,3 +200,224 @@ test_get_fflags_bad_jwt_denied if {
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
 }
+
+admin_user := object.union(valid_user("openid profile email"), {"realm_access": {"roles": ["admin"]}})
+
+mock_decode_verify("admin-jwt", _) := [true, {}, {}]
+
+mock_decode("admin-jwt") := [{}, admin_user, {}]
+
+test_admin_get_allowed if {
+	token.allow with input.path as ["api", "admin", "organizations"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "admin-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_admin_delete_allowed if {
+	token.allow with input.path as ["api", "admin", "devices", "foo"]
+		with input.method as "DELETE"
+		with input.jwks as "my-cert"
+		with input.access_token as "admin-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_admin_without_role_denied if {
+	not token.allow with input.path as ["api", "admin", "organizations"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "org-read-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_admin_bad_jwt_denied if {
+	not token.allow with input.path as ["api", "admin", "organizations"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "bad-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+pat_user(scopes) := {
+	"sub": "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9",
+	"scope": scopes,
+	"preferred_username": "valid-user",
+}
+
+test_pat_device_get_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "nexpat_secret"
+		with input.personal_access_token as pat_user("read:devices")
+}
+
+test_pat_device_post_with_read_scope_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with input.jwks as "my-cert"
+		with input.access_token as "nexpat_secret"
+		with input.personal_access_token as pat_user("read:devices")
+}
+
+test_pat_user_id if {
+	token.user_id == "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9" with input.access_token as "nexpat_secret"
+		with input.personal_access_token as pat_user("read:devices")
+}
+
+test_pat_create_pat_denied if {
+	not token.allow with input.path as ["api", "personal_access_tokens"]
+		with input.method as "POST"
+		with input.jwks as "my-cert"
+		with input.access_token as "nexpat_secret"
+		with input.personal_access_token as pat_user("write:users")
+}
+
+test_pat_create_pat_allowed if {
+	token.allow with input.path as ["api", "personal_access_tokens"]
+		with input.method as "POST"
+		with input.jwks as "my-cert"
+		with input.access_token as "user-write-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_pat_admin_denied if {
+	not token.allow with input.path as ["api", "admin", "organizations"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "nexpat_secret"
+		with input.personal_access_token as object.union(pat_user("read:users"), {"realm_access": {"roles": ["admin"]}})
+}
+
+google_user(email) := object.union(valid_user("openid profile email read:devices"), {
+	"from_google": true,
+	"email": email,
+	"iss": "https://auth.example.com/realms/nexodus",
+})
+
+mock_decode_verify("google-jwt", _) := [true, {}, {}]
+
+mock_decode("google-jwt") := [{}, google_user("user@example.com"), {}]
+
+mock_decode_verify("google-subdomain-jwt", _) := [true, {}, {}]
+
+mock_decode("google-subdomain-jwt") := [{}, google_user("user@eng.example.com"), {}]
+
+mock_decode_verify("google-lookalike-jwt", _) := [true, {}, {}]
+
+mock_decode("google-lookalike-jwt") := [{}, google_user("user@notexample.com"), {}]
+
+test_google_denied_without_allowed_domains if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "google-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_google_allowed_domain if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "google-jwt"
+		with data.nexodus.allowed_email_domains as ["Example.com"]
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_google_allowed_subdomain if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "google-subdomain-jwt"
+		with data.nexodus.allowed_email_domains as ["example.com"]
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_google_other_domain_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "google-lookalike-jwt"
+		with data.nexodus.allowed_email_domains as ["example.com"]
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_allowed_issuer if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "google-jwt"
+		with data.nexodus.allowed_issuers as ["https://auth.example.com/realms/nexodus"]
+		with data.nexodus.allowed_email_domains as ["example.com"]
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_other_issuer_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "google-jwt"
+		with data.nexodus.allowed_issuers as ["https://auth.other.com/realms/nexodus"]
+		with data.nexodus.allowed_email_domains as ["example.com"]
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+mock_decode_verify_aud(_, constraints) := [constraints.aud == "nexodus-api", {}, {}]
+
+test_audience if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "device-read-jwt"
+		with data.nexodus.audience as "nexodus-api"
+		with io.jwt.decode_verify as mock_decode_verify_aud
+		with io.jwt.decode as mock_decode
+}
+
+test_other_audience_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "device-read-jwt"
+		with io.jwt.decode_verify as mock_decode_verify_aud
+		with io.jwt.decode as mock_decode
+}
+
+test_denied_by_bundle if {
+	token.denied with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with data.bundle.deny as {"relays must be named relay-*"}
+}
+
+test_not_denied_without_bundle if {
+	not token.denied with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with data.bundle.deny as set()
+}
+
+test_denied_without_deny_rules if {
+	token.denied with input.path as ["api", "devices"]
+		with input.method as "POST"
+}
+
+test_denied_by_bundle_deny_not_a_set if {
+	token.denied with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with data.bundle.deny as false
+}
+
+test_scopes if {
+	token.scopes == ["openid", "read:devices"] with input.personal_access_token as pat_user("openid read:devices")
+}
@@ -34,18 +34,28 @@ func NewPgSignalBus(signalBus SignalBus, db *gorm.DB, connectDSN string, logger
 
 // Notify will notify all the subscriptions created across the cluster of the given named signal.
 func (pgsb *PgSignalBus) Notify(name string) {
+	pgsb.NotifyWithPayload(name, "")
+}
+
+// NotifyWithPayload will notify all the subscriptions created across the cluster of the given
+// named signal and pass them the payload.
+func (pgsb *PgSignalBus) NotifyWithPayload(name string, payload string) {
 	// instead of send the Notify to the in memory bus, first send it to the DB
 	// with the pg_notify function.  The DB will send it back to us and all other processes
 	// that are listening for those events.
-	if err := pgsb.db.Exec("SELECT pg_notify('signalbus', ?)", name).Error; err != nil {
+	if err := pgsb.db.Exec("SELECT pg_notify('signalbus', ?)", encodeSignal(name, payload)).Error; err != nil {
 		pgsb.logger.Info("notify failed:", err.Error())
+		return
 	}
+	notificationsSent.Inc()
 }
 
 func (pgsb *PgSignalBus) NotifyAll() {
 	if err := pgsb.db.Exec("SELECT pg_notify('signalbus', ?)", "*").Error; err != nil {
 		pgsb.logger.Info("notify failed:", err.Error())
+		return
 	}
+	notificationsSent.Inc()
 }
 
 // Subscribe creates a subscription the named signal.
@@ -108,13 +118,14 @@ func (pgsb *PgSignalBus) waitForNotification(ctx context.Context, l *pq.Listener
 				return false, fmt.Errorf("postgres listner channel closed")
 			}
 			pgsb.logger.Infof("Received data from channel: %s, data: %s", n.Channel, n.Extra)
+			notificationsReceived.Inc()
 
 			// we got the signal name from the DB... lets use the in memory signalBus
 			// to notify all the subscribers that registered for events.
 			if n.Extra == "*" {
 				pgsb.signalBus.NotifyAll()
 			} else {
-				pgsb.signalBus.Notify(n.Extra)
+				pgsb.signalBus.NotifyWithPayload(decodeSignal(n.Extra))
 			}
 			return
 		case <-time.After(90 * time.Second):
@@ -0,0 +1,123 @@
+package signalbus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/util"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var _ SignalBus = &RedisSignalBus{} // type check the interface is implemented.
+
+// redisChannel is the redis pub/sub channel the signals are published to.
+const redisChannel = "signalbus"
+
+// RedisSignalBus implements a signalbus.SignalBus that is clustered using redis pub/sub.
+type RedisSignalBus struct {
+	client    *redis.Client
+	signalBus SignalBus // typically an in memory signal bus.
+	logger    *zap.SugaredLogger
+}
+
+// NewRedisSignalBus creates a new RedisSignalBus
+func NewRedisSignalBus(signalBus SignalBus, client *redis.Client, logger *zap.SugaredLogger) *RedisSignalBus {
+	return &RedisSignalBus{
+		client:    client,
+		signalBus: signalBus,
+		logger:    logger,
+	}
+}
+
+// Notify will notify all the subscriptions created across the cluster of the given named signal.
+func (rsb *RedisSignalBus) Notify(name string) {
+	// like the PgSignalBus, the signal is published to redis, which sends it back to us
+	// and to all the other processes subscribed to the channel.
+	rsb.publish(name)
+}
+
+// NotifyWithPayload will notify all the subscriptions created across the cluster of the given
+// named signal and pass them the payload.
+func (rsb *RedisSignalBus) NotifyWithPayload(name string, payload string) {
+	rsb.publish(encodeSignal(name, payload))
+}
+
+func (rsb *RedisSignalBus) NotifyAll() {
+	rsb.publish("*")
+}
+
+func (rsb *RedisSignalBus) publish(payload string) {
+	if err := rsb.client.Publish(context.Background(), redisChannel, payload).Err(); err != nil {
+		rsb.logger.Info("notify failed:", err.Error())
+		return
+	}
+	notificationsSent.Inc()
+}
+
+// Subscribe creates a subscription the named signal.
+// They are performed on the in memory bus.
+func (rsb *RedisSignalBus) Subscribe(name string) *Subscription {
+	return rsb.signalBus.Subscribe(name)
+}
+
+// Start starts the background worker that receives the signals published
+// by this process and all the other processes to the signalbus channel.
+func (rsb *RedisSignalBus) Start(ctx context.Context, wg *sync.WaitGroup) {
+	pubsub := rsb.client.Subscribe(ctx, redisChannel)
+	util.GoWithWaitGroup(wg, func() {
+		// unblocks the receive below.
+		<-ctx.Done()
+		_ = pubsub.Close()
+	})
+	util.GoWithWaitGroup(wg, func() {
+		subscribed := false
+		for {
+			msg, err := pubsub.ReceiveTimeout(ctx, 90*time.Second)
+			if ctx.Err() != nil {
+				return
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// in case we have not received an event in a while... lets check to make sure the
+				// connection is still good, the pubsub reconnects on the next receive if not.
+				rsb.logger.Info("Received no events for 90 seconds, checking connection")
+				if err := pubsub.Ping(ctx); err != nil {
+					rsb.logger.Info("redis ping failed:", err.Error())
+				}
+				continue
+			}
+			if err != nil {
+				rsb.logger.Errorln("error waiting for event:", err.Error())
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+				continue
+			}
+
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				// the subscription is restored after a reconnect, the signals published while
+				// we were disconnected are lost so notify all the subscribers.
+				if subscribed {
+					rsb.signalBus.NotifyAll()
+				}
+				subscribed = true
+			case *redis.Message:
+				rsb.logger.Infof("Received data from channel: %s, data: %s", msg.Channel, msg.Payload)
+				notificationsReceived.Inc()
+
+				if msg.Payload == "*" {
+					rsb.signalBus.NotifyAll()
+				} else {
+					rsb.signalBus.NotifyWithPayload(decodeSignal(msg.Payload))
+				}
+			}
+		}
+	})
+}
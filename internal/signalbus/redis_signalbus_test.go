@@ -0,0 +1,69 @@
+package signalbus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRedisSignalBus(t *testing.T) {
+	require := require.New(t)
+
+	server := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	// two buses sharing the redis server, like two apiserver replicas.
+	newBus := func() *RedisSignalBus {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		bus := NewRedisSignalBus(NewSignalBus(), client, zap.NewNop().Sugar())
+		bus.Start(ctx, wg)
+		return bus
+	}
+	bus1 := newBus()
+	bus2 := newBus()
+	require.Eventually(func() bool {
+		return len(server.PubSubChannels("")) == 1 && server.PubSubNumSub(redisChannel)[redisChannel] == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	aSub := bus2.Subscribe("a")
+	defer aSub.Close()
+	bSub := bus2.Subscribe("b")
+	defer bSub.Close()
+
+	// signals published by one replica reach the subscriptions of the others.
+	bus1.Notify("a")
+	require.Eventually(aSub.IsSignaled, 2*time.Second, time.Millisecond)
+	require.False(bSub.IsSignaled())
+
+	bus1.NotifyAll()
+	require.Eventually(aSub.IsSignaled, 2*time.Second, time.Millisecond)
+	require.Eventually(bSub.IsSignaled, 2*time.Second, time.Millisecond)
+
+	// the signals published while disconnected are lost, all the subscriptions are
+	// notified once the bus has reconnected.
+	server.Close()
+	require.NoError(server.Restart())
+	require.Eventually(aSub.IsSignaled, 5*time.Second, time.Millisecond)
+	require.Eventually(bSub.IsSignaled, 5*time.Second, time.Millisecond)
+
+	bus1.Notify("b")
+	require.Eventually(bSub.IsSignaled, 2*time.Second, time.Millisecond)
+
+	// the payloads are passed to the subscriptions of the other replicas.
+	_, _ = aSub.Payloads()
+	bus1.NotifyWithPayload("a", "42")
+	require.Eventually(aSub.IsSignaled, 2*time.Second, time.Millisecond)
+	payloads, complete := aSub.Payloads()
+	require.True(complete)
+	require.Equal([]string{"42"}, payloads)
+}
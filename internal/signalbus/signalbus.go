@@ -2,15 +2,39 @@
 package signalbus
 
 import (
+	"strings"
 	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	notificationsSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "apiserver",
+		Name:      "signalbus_notifications_sent_total",
+		Help:      "Number of signals published to the signalbus channel shared by the apiserver replicas.",
+	})
+	notificationsReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "apiserver",
+		Name:      "signalbus_notifications_received_total",
+		Help:      "Number of signals received from the signalbus channel shared by the apiserver replicas.",
+	})
 )
 
+// maxPendingPayloads bounds the payloads a subscription holds until they are read.
+const maxPendingPayloads = 64
+
 type SignalBus interface {
 	// Notify will notify all the subscriptions created for the given named signal.
 	Notify(name string)
+	// NotifyWithPayload will notify all the subscriptions created for the given named signal
+	// and pass them the payload, it should be kept small, like a revision or an ID.
+	NotifyWithPayload(name string, payload string)
 	// NotifyAll will notify all the subscriptions
 	NotifyAll()
-	// Subscribe creates a subscription the named signal
+	// Subscribe creates a subscription the named signal, a name that ends with "*" subscribes
+	// to all the signals with that prefix, like "/devices/*".
 	Subscribe(name string) *Subscription
 }
 
@@ -19,42 +43,54 @@ var _ SignalBus = &signalBus{} // type check the interface is implemented.
 type signalBus struct {
 	sync.RWMutex
 	signals map[string][]*Subscription
+	// prefixes holds the wildcard subscriptions by the prefix they match.
+	prefixes map[string][]*Subscription
 }
 
 // NewSignalBus creates a new signalBus
 func NewSignalBus() SignalBus {
 	return &signalBus{
-		signals: make(map[string][]*Subscription),
+		signals:  make(map[string][]*Subscription),
+		prefixes: make(map[string][]*Subscription),
 	}
 }
 
 // Notify will notify all the subscriptions created for the given named signal.
 func (sb *signalBus) Notify(name string) {
+	sb.NotifyWithPayload(name, "")
+}
+
+// NotifyWithPayload will notify all the subscriptions created for the given named signal
+// and pass them the payload.
+func (sb *signalBus) NotifyWithPayload(name string, payload string) {
 	var result []*Subscription
 	sb.RLock()
-	result = sb.signals[name]
+	result = append(result, sb.signals[name]...)
+	for prefix, subs := range sb.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			result = append(result, subs...)
+		}
+	}
 	sb.RUnlock()
 
 	for _, sub := range result {
-		select {
-		case sub.c <- true:
-		default:
-		}
+		sub.notify(payload)
 	}
 }
+
 func (sb *signalBus) NotifyAll() {
 	var result []*Subscription
 	sb.RLock()
 	for _, s := range sb.signals {
 		result = append(result, s...)
 	}
+	for _, s := range sb.prefixes {
+		result = append(result, s...)
+	}
 	sb.RUnlock()
 
 	for _, sub := range result {
-		select {
-		case sub.c <- true:
-		default:
-		}
+		sub.notify("")
 	}
 }
 
@@ -67,15 +103,25 @@ func (sb *signalBus) Subscribe(name string) *Subscription {
 	}
 
 	sb.Lock()
-	subs := sb.signals[name]
-	sb.signals[name] = append(subs, sub)
+	signals := sb.signals
+	if prefix, ok := strings.CutSuffix(name, "*"); ok {
+		sub.name = prefix
+		sub.prefix = true
+		signals = sb.prefixes
+	}
+	subs := signals[sub.name]
+	signals[sub.name] = append(subs, sub)
 	sb.Unlock()
 	return sub
 }
 
 func (sb *signalBus) close(sub *Subscription) {
 	sb.Lock()
-	subs := sb.signals[sub.name]
+	signals := sb.signals
+	if sub.prefix {
+		signals = sb.prefixes
+	}
+	subs := signals[sub.name]
 	for i, s := range subs {
 		if s == sub {
 			// replace it with the last item..
@@ -84,9 +130,9 @@ func (sb *signalBus) close(sub *Subscription) {
 				subs[i] = subs[lastIdx]
 				// then shrink the slice...
 				subs = subs[:lastIdx]
-				sb.signals[sub.name] = subs
+				signals[sub.name] = subs
 			} else {
-				delete(sb.signals, sub.name)
+				delete(signals, sub.name)
 			}
 		}
 	}
@@ -96,8 +142,31 @@ func (sb *signalBus) close(sub *Subscription) {
 type Subscription struct {
 	sb        *signalBus
 	name      string
+	prefix    bool
 	closeOnce sync.Once
 	c         chan bool
+
+	mu       sync.Mutex
+	payloads []string
+	// incomplete is set when a notification without a payload was received, or when
+	// too many payloads were received, since the payloads were last read.
+	incomplete bool
+}
+
+func (sub *Subscription) notify(payload string) {
+	sub.mu.Lock()
+	if payload == "" || len(sub.payloads) >= maxPendingPayloads {
+		sub.incomplete = true
+		sub.payloads = nil
+	} else if !sub.incomplete {
+		sub.payloads = append(sub.payloads, payload)
+	}
+	sub.mu.Unlock()
+
+	select {
+	case sub.c <- true:
+	default:
+	}
 }
 
 // Signal returns a channel that receives a true message when the subscription is notified.
@@ -129,9 +198,35 @@ func (sub *Subscription) IsSignaled() bool {
 	}
 }
 
+// Payloads returns the payloads of the notifications received since the payloads were last read.
+// complete is false when some of those notifications did not carry a payload, or when there
+// were too many to hold, in which case the subscriber can't rely on the payloads alone.
+func (sub *Subscription) Payloads() (payloads []string, complete bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	payloads, complete = sub.payloads, !sub.incomplete
+	sub.payloads, sub.incomplete = nil, false
+	return payloads, complete
+}
+
 // Close is used to close out the subscription.
 func (sub *Subscription) Close() {
 	sub.closeOnce.Do(func() {
 		sub.sb.close(sub)
 	})
 }
+
+// encodeSignal encodes a signal name and its payload into the message the clustered
+// signal buses publish, a signal without a payload is published as its name.
+func encodeSignal(name string, payload string) string {
+	if payload == "" {
+		return name
+	}
+	return name + "\n" + payload
+}
+
+// decodeSignal decodes a message published with encodeSignal.
+func decodeSignal(message string) (name string, payload string) {
+	name, payload, _ = strings.Cut(message, "\n")
+	return name, payload
+}
@@ -46,3 +46,81 @@ func TestNewSignalBus(t *testing.T) {
 	aSub2.Close()
 	require.Equal(0, len(bus.signals))
 }
+
+func TestSignalBusPayloads(t *testing.T) {
+	require := require.New(t)
+
+	bus := NewSignalBus().(*signalBus)
+	sub := bus.Subscribe("a")
+	defer sub.Close()
+
+	bus.NotifyWithPayload("a", "1")
+	bus.NotifyWithPayload("a", "2")
+	require.True(sub.IsSignaled())
+	payloads, complete := sub.Payloads()
+	require.True(complete)
+	require.Equal([]string{"1", "2"}, payloads)
+
+	// reading the payloads resets them.
+	payloads, complete = sub.Payloads()
+	require.True(complete)
+	require.Empty(payloads)
+
+	// a notification without a payload makes the payloads incomplete.
+	bus.NotifyWithPayload("a", "3")
+	bus.Notify("a")
+	payloads, complete = sub.Payloads()
+	require.False(complete)
+	require.Empty(payloads)
+
+	bus.NotifyWithPayload("a", "4")
+	bus.NotifyAll()
+	_, complete = sub.Payloads()
+	require.False(complete)
+
+	// too many payloads makes them incomplete.
+	for i := 0; i <= maxPendingPayloads; i++ {
+		bus.NotifyWithPayload("a", "5")
+	}
+	payloads, complete = sub.Payloads()
+	require.False(complete)
+	require.Empty(payloads)
+}
+
+func TestSignalBusWildcards(t *testing.T) {
+	require := require.New(t)
+
+	bus := NewSignalBus().(*signalBus)
+	devicesSub := bus.Subscribe("/devices/*")
+	allSub := bus.Subscribe("*")
+	orgSub := bus.Subscribe("/devices/org=a")
+	require.Equal(1, len(bus.signals))
+	require.Equal(2, len(bus.prefixes))
+
+	bus.NotifyWithPayload("/devices/org=a", "1")
+	require.True(devicesSub.IsSignaled())
+	require.True(allSub.IsSignaled())
+	require.True(orgSub.IsSignaled())
+	payloads, _ := devicesSub.Payloads()
+	require.Equal([]string{"1"}, payloads)
+
+	bus.Notify("/devices/org=b")
+	require.True(devicesSub.IsSignaled())
+	require.True(allSub.IsSignaled())
+	require.False(orgSub.IsSignaled())
+
+	bus.Notify("/organizations/a")
+	require.False(devicesSub.IsSignaled())
+	require.True(allSub.IsSignaled())
+
+	bus.NotifyAll()
+	require.True(devicesSub.IsSignaled())
+	require.True(allSub.IsSignaled())
+	require.True(orgSub.IsSignaled())
+
+	devicesSub.Close()
+	allSub.Close()
+	orgSub.Close()
+	require.Equal(0, len(bus.signals))
+	require.Equal(0, len(bus.prefixes))
+}
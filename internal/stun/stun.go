@@ -47,3 +47,10 @@ func NextServer() string {
 	}
 	return stunServers[currentStunServer]
 }
+
+// Servers returns the stun servers in use.
+func Servers() []string {
+	stunServerMu.Lock()
+	defer stunServerMu.Unlock()
+	return append([]string(nil), stunServers...)
+}
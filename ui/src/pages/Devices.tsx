@@ -14,6 +14,8 @@ import {
   BulkExportButton,
   BulkDeleteButton,
   ArrayField,
+  BooleanField,
+  DateField,
 } from "react-admin";
 
 const ZoneNameFromPeer = () => {
@@ -45,6 +47,7 @@ export const DeviceList = () => (
     <Datagrid rowClick="show" bulkActionButtons={<DeviceListBulkActions />}>
       <TextField label="Hostname" source="hostname" />
       <TextField label="Tunnel IP" source="tunnel_ip" />
+      <BooleanField label="Online" source="online" />
 
       <ArrayField label="Endpoints" source="endpoints">
         <Datagrid rowClick="show" bulkActionButtons={false}>
@@ -85,6 +88,8 @@ export const DeviceShow = () => (
         </Datagrid>
       </ArrayField>
       <TextField label="Relay Node" source="relay" />
+      <BooleanField label="Online" source="online" />
+      <DateField label="Last Seen" source="last_seen" showTime />
       <ReferenceField
         label="Organization"
         source="organization_id"